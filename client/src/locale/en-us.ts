@@ -51,6 +51,10 @@ export const controls = {
   unlock: 'Unlock Controls',
   has: 'You have control',
   hasnot: 'You do not have control',
+  queue_waiting: '{count} waiting for controls',
+  queue_position: 'You are {position}. in queue',
+  remaining: 'Controls are passed on in {time}',
+  idle_timeout: 'Controls are released after {time} without input',
 }
 
 export const locks = {
@@ -89,6 +93,7 @@ export const connection = {
   disconnected: 'Disconnected',
   kicked: 'You have been removed from this room.',
   button_confirm: 'OK',
+  admin_error: 'Error while performing {action}',
 }
 
 export const notifications = {
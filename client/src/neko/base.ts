@@ -1,5 +1,5 @@
 import EventEmitter from 'eventemitter3'
-import { OPCODE } from './data'
+import { OPCODE, DATA_EVENT, LED, DataPayload } from './data'
 import { EVENT, WebSocketEvents } from './events'
 
 import {
@@ -27,6 +27,12 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
   protected _state: RTCIceConnectionState = 'disconnected'
   protected _id = ''
   protected _candidates: RTCIceCandidate[] = []
+  protected _url = ''
+  protected _query = ''
+  // token to resume session after websocket was closed
+  protected _resume = ''
+  protected _resuming = false
+  protected _retry?: number
 
   get id() {
     return this._id
@@ -63,36 +69,65 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
     this[EVENT.CONNECTING]()
 
     try {
-      this._ws = new WebSocket(`${url}?password=${encodeURIComponent(password)}`)
-      this.emit('debug', `connecting to ${this._ws.url}`)
-      this._ws.onmessage = this.onMessage.bind(this)
-      this._ws.onerror = (event) => this.onError.bind(this)
-      this._ws.onclose = (event) => this.onDisconnected.bind(this, new Error('websocket closed'))
+      // display name is used as username, if server uses user accounts
+      let query = `password=${encodeURIComponent(password)}&username=${encodeURIComponent(displayname)}`
+
+      this._url = url
+      this._query = query
+
+      // invite link is used instead of credentials, it is not sent again on
+      // reconnect, so that it is not used up by resumed session
+      const token = new URL(location.href).searchParams.get('token')
+      if (token) {
+        query += `&token=${encodeURIComponent(token)}`
+      }
+
+      this.openSocket(query)
       this._timeout = window.setTimeout(this.onTimeout.bind(this), 15000)
     } catch (err: any) {
       this.onDisconnected(err)
     }
   }
 
-  protected disconnect() {
-    if (this._timeout) {
-      clearTimeout(this._timeout)
-      this._timeout = undefined
+  private openSocket(query: string) {
+    this._ws = new WebSocket(`${this._url}?${query}`)
+    this.emit('debug', `connecting to ${this._ws.url}`)
+    this._ws.onmessage = this.onMessage.bind(this)
+    this._ws.onerror = (event) => this.onError.bind(this)
+    this._ws.onclose = (event) => this.onSocketClosed()
+  }
+
+  private onSocketClosed() {
+    // server keeps session for a while, peer is kept until it is resumed
+    if (!this._resume || !this._peer) {
+      this.onDisconnected(new Error('websocket closed'))
+      return
     }
 
+    this.reconnect()
+  }
+
+  private reconnect() {
     if (this._ws) {
-      // reset all events
       this._ws.onmessage = () => {}
       this._ws.onerror = () => {}
       this._ws.onclose = () => {}
-
-      try {
-        this._ws.close()
-      } catch (err) {}
-
       this._ws = undefined
     }
 
+    if (!this._timeout) {
+      this[EVENT.RECONNECTING]()
+      this._timeout = window.setTimeout(this.onTimeout.bind(this), 15000)
+    }
+
+    this._resuming = true
+    this._retry = window.setTimeout(() => {
+      this._retry = undefined
+      this.openSocket(`${this._query}&id=${encodeURIComponent(this._id)}&resume=${encodeURIComponent(this._resume)}`)
+    }, 1000)
+  }
+
+  private closePeer() {
     if (this._channel) {
       // reset all events
       this._channel.onmessage = () => {}
@@ -121,8 +156,38 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
     }
 
     this._state = 'disconnected'
+  }
+
+  protected disconnect() {
+    if (this._timeout) {
+      clearTimeout(this._timeout)
+      this._timeout = undefined
+    }
+
+    if (this._ws) {
+      // reset all events
+      this._ws.onmessage = () => {}
+      this._ws.onerror = () => {}
+      this._ws.onclose = () => {}
+
+      try {
+        this._ws.close()
+      } catch (err) {}
+
+      this._ws = undefined
+    }
+
+    if (this._retry) {
+      clearTimeout(this._retry)
+      this._retry = undefined
+    }
+
+    this.closePeer()
+
     this._displayname = undefined
     this._id = ''
+    this._resume = ''
+    this._resuming = false
   }
 
   public sendData(event: 'wheel' | 'mousemove', data: { x: number; y: number }): void
@@ -241,7 +306,13 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
         // go back to a connected state after some time. Watching it would close the video call on any temporary
         // network issue.
         case 'failed':
-          this.onDisconnected(new Error('peer failed'))
+          // server restarts ICE, while it keeps the session for reconnect
+          if (!this._resume) {
+            this.onDisconnected(new Error('peer failed'))
+          } else if (!this._timeout) {
+            this[EVENT.RECONNECTING]()
+            this._timeout = window.setTimeout(this.onTimeout.bind(this), 15000)
+          }
           break
         case 'closed':
           this.onDisconnected(new Error('peer closed'))
@@ -265,7 +336,9 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
       )
     }
 
-    this._channel = this._peer.createDataChannel('data')
+    // channel is negotiated with the same id by server
+    this._channel = this._peer.createDataChannel('data', { negotiated: true, id: 0 })
+    this._channel.binaryType = 'arraybuffer'
     this._channel.onerror = this.onError.bind(this)
     this._channel.onmessage = this.onData.bind(this)
     this._channel.onclose = this.onDisconnected.bind(this, new Error('peer data channel closed'))
@@ -314,9 +387,29 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
 
     this.emit('debug', `received websocket event ${event} ${payload ? `with payload: ` : ''}`, payload)
 
+    // session was resumed, unless server created a new one
+    if (this._resuming) {
+      this._resuming = false
+
+      if (event !== EVENT.SIGNAL.PROVIDE) {
+        this[EVENT.RECONNECTED](true)
+        if (this.peerConnected) {
+          this.onConnected()
+        }
+      }
+    }
+
     if (event === EVENT.SIGNAL.PROVIDE) {
-      const { sdp, lite, ice, id } = payload as SignalProvidePayload
+      const { sdp, lite, ice, id, resume } = payload as SignalProvidePayload
+
+      // peer of the lost session is replaced
+      if (this._peer) {
+        this.closePeer()
+        this[EVENT.RECONNECTED](false)
+      }
+
       this._id = id
+      this._resume = resume || ''
       await this.createPeer(lite, ice)
       await this.setRemoteOffer(sdp)
       return
@@ -354,8 +447,46 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
     }
   }
 
-  private onData(e: MessageEvent) {
-    this[EVENT.DATA](e.data)
+  private onData(e: MessageEvent<ArrayBuffer>) {
+    const payload = new DataView(e.data)
+    const event = payload.getUint8(0)
+    const length = payload.getUint16(1, true)
+
+    switch (event) {
+      case DATA_EVENT.CURSOR_POSITION:
+        this[EVENT.DATA]({
+          event: DATA_EVENT.CURSOR_POSITION,
+          x: payload.getUint16(3, true),
+          y: payload.getUint16(5, true),
+        })
+        break
+      case DATA_EVENT.CURSOR_IMAGE:
+        this[EVENT.DATA]({
+          event: DATA_EVENT.CURSOR_IMAGE,
+          width: payload.getUint16(3, true),
+          height: payload.getUint16(5, true),
+          x: payload.getUint16(7, true),
+          y: payload.getUint16(9, true),
+          image: new Blob([e.data.slice(11, 3 + length)], { type: 'image/png' }),
+        })
+        break
+      case DATA_EVENT.KEYBOARD_LEDS:
+        this[EVENT.DATA]({
+          event: DATA_EVENT.KEYBOARD_LEDS,
+          capsLock: (payload.getUint8(3) & LED.CAPS_LOCK) !== 0,
+          numLock: (payload.getUint8(3) & LED.NUM_LOCK) !== 0,
+        })
+        break
+      case DATA_EVENT.CONTROL_ACK:
+        this[EVENT.DATA]({
+          event: DATA_EVENT.CONTROL_ACK,
+          op: payload.getUint8(3),
+          status: payload.getUint8(4),
+        })
+        break
+      default:
+        this.emit('warn', `unknown data event: ${event}`)
+    }
   }
 
   private onTrack(event: RTCTrackEvent) {
@@ -407,9 +538,10 @@ export abstract class BaseClient extends EventEmitter<BaseEvents> {
   }
 
   protected abstract [EVENT.RECONNECTING](): void
+  protected abstract [EVENT.RECONNECTED](resumed: boolean): void
   protected abstract [EVENT.CONNECTING](): void
   protected abstract [EVENT.CONNECTED](): void
   protected abstract [EVENT.DISCONNECTED](reason?: Error): void
   protected abstract [EVENT.TRACK](event: RTCTrackEvent): void
-  protected abstract [EVENT.DATA](data: any): void
+  protected abstract [EVENT.DATA](data: DataPayload): void
 }
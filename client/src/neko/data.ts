@@ -4,3 +4,62 @@ export const OPCODE = {
   KEY_DOWN: 0x03,
   KEY_UP: 0x04,
 } as const
+
+// server to client, high bit is set so that they never collide with OPCODE
+export const DATA_EVENT = {
+  CURSOR_POSITION: 0x81,
+  CURSOR_IMAGE: 0x82,
+  KEYBOARD_LEDS: 0x83,
+  CONTROL_ACK: 0x84,
+} as const
+
+export const LED = {
+  CAPS_LOCK: 0x01,
+  NUM_LOCK: 0x02,
+} as const
+
+export const ACK = {
+  OK: 0x00,
+  NO_CONTROL: 0x01,
+  INPUT_BUSY: 0x02,
+  FAILED: 0x03,
+} as const
+
+export type DataPayload = CursorPositionData | CursorImageData | KeyboardLedsData | ControlAckData
+
+export interface CursorPositionData {
+  event: typeof DATA_EVENT.CURSOR_POSITION
+  x: number
+  y: number
+}
+
+// x and y are hotspot of the image
+export interface CursorImageData {
+  event: typeof DATA_EVENT.CURSOR_IMAGE
+  width: number
+  height: number
+  x: number
+  y: number
+  image: Blob
+}
+
+export interface KeyboardLedsData {
+  event: typeof DATA_EVENT.KEYBOARD_LEDS
+  capsLock: boolean
+  numLock: boolean
+}
+
+export interface ControlAckData {
+  event: typeof DATA_EVENT.CONTROL_ACK
+  op: number
+  status: number
+}
+
+// cursor image, that is ready to be rendered
+export interface CursorImage {
+  uri: string
+  width: number
+  height: number
+  x: number
+  y: number
+}
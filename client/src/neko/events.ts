@@ -1,6 +1,7 @@
 export const EVENT = {
   // Internal Events
   RECONNECTING: 'RECONNECTING',
+  RECONNECTED: 'RECONNECTED',
   CONNECTING: 'CONNECTING',
   CONNECTED: 'CONNECTED',
   DISCONNECTED: 'DISCONNECTED',
@@ -33,6 +34,13 @@ export const EVENT = {
     CLIPBOARD: 'control/clipboard',
     GIVE: 'control/give',
     KEYBOARD: 'control/keyboard',
+    QUEUE: 'control/queue',
+    DEQUEUE: 'control/dequeue',
+    INPUT: 'control/input',
+  },
+  CURSOR: {
+    POSITION: 'cursor/position',
+    IMAGE: 'cursor/image',
   },
   CHAT: {
     MESSAGE: 'chat/message',
@@ -58,6 +66,8 @@ export const EVENT = {
     CONTROL: 'admin/control',
     RELEASE: 'admin/release',
     GIVE: 'admin/give',
+    PERMISSIONS: 'admin/permissions',
+    ERROR: 'admin/error',
   },
 } as const
 
@@ -80,6 +90,7 @@ export type ControlEvents =
   | typeof EVENT.CONTROL.GIVE
   | typeof EVENT.CONTROL.CLIPBOARD
   | typeof EVENT.CONTROL.KEYBOARD
+  | typeof EVENT.CONTROL.DEQUEUE
 
 export type SystemEvents = typeof EVENT.SYSTEM.DISCONNECT
 export type MemberEvents = typeof EVENT.MEMBER.LIST | typeof EVENT.MEMBER.CONNECTED | typeof EVENT.MEMBER.DISCONNECTED
@@ -108,3 +119,5 @@ export type AdminEvents =
   | typeof EVENT.ADMIN.CONTROL
   | typeof EVENT.ADMIN.RELEASE
   | typeof EVENT.ADMIN.GIVE
+  | typeof EVENT.ADMIN.PERMISSIONS
+  | typeof EVENT.ADMIN.ERROR
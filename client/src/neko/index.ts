@@ -3,6 +3,7 @@ import EventEmitter from 'eventemitter3'
 import { BaseClient, BaseEvents } from './base'
 import { Member } from './types'
 import { EVENT } from './events'
+import { DATA_EVENT, ACK, DataPayload } from './data'
 import { accessor } from '~/store'
 
 import {
@@ -16,12 +17,17 @@ import {
   ChatPayload,
   EmotePayload,
   ControlClipboardPayload,
+  ControlQueuePayload,
+  CursorPositionPayload,
+  CursorImagePayload,
   ScreenConfigurationsPayload,
   ScreenResolutionPayload,
   BroadcastStatusPayload,
   AdminPayload,
   AdminTargetPayload,
   AdminLockMessage,
+  AdminPermissionsPayload,
+  AdminErrorPayload,
   SystemInitPayload,
   AdminLockResource,
 } from './messages'
@@ -83,6 +89,14 @@ export class NekoClient extends BaseClient implements EventEmitter<NekoEvents> {
     })
   }
 
+  protected [EVENT.RECONNECTED](resumed: boolean) {
+    // state is sent again by server
+    this.$accessor.remote.reset()
+    if (!resumed) {
+      this.$accessor.video.reset()
+    }
+  }
+
   protected [EVENT.CONNECTING]() {
     this.$accessor.setConnnecting()
   }
@@ -128,13 +142,37 @@ export class NekoClient extends BaseClient implements EventEmitter<NekoEvents> {
     this.$accessor.video.setStream(0)
   }
 
-  protected [EVENT.DATA](data: any) {}
+  protected [EVENT.DATA](data: DataPayload) {
+    switch (data.event) {
+      case DATA_EVENT.CURSOR_POSITION:
+        this.$accessor.remote.setCursorPosition({ x: data.x, y: data.y })
+        break
+      case DATA_EVENT.CURSOR_IMAGE:
+        this.$accessor.remote.setCursorImage({
+          uri: URL.createObjectURL(data.image),
+          width: data.width,
+          height: data.height,
+          x: data.x,
+          y: data.y,
+        })
+        break
+      case DATA_EVENT.KEYBOARD_LEDS:
+        this.$accessor.remote.setKeyboardLeds({ capsLock: data.capsLock, numLock: data.numLock })
+        break
+      case DATA_EVENT.CONTROL_ACK:
+        if (data.status !== ACK.OK) {
+          this.emit('debug', `input ${data.op} was not applied, status ${data.status}`)
+        }
+        break
+    }
+  }
 
   /////////////////////////////
   // System Events
   /////////////////////////////
-  protected [EVENT.SYSTEM.INIT]({ implicit_hosting, locks }: SystemInitPayload) {
+  protected [EVENT.SYSTEM.INIT]({ implicit_hosting, multi_control, locks }: SystemInitPayload) {
     this.$accessor.remote.setImplicitHosting(implicit_hosting)
+    this.$accessor.remote.setMultiControl(multi_control)
 
     for (const resource in locks) {
       this[EVENT.ADMIN.LOCK]({
@@ -325,6 +363,26 @@ export class NekoClient extends BaseClient implements EventEmitter<NekoEvents> {
     this.$accessor.remote.setClipboard(text)
   }
 
+  protected [EVENT.CONTROL.INPUT]({ id }: ControlPayload) {
+    this.$accessor.remote.setInput(id)
+  }
+
+  protected [EVENT.CONTROL.QUEUE]({ queue, remaining, idle_timeout }: ControlQueuePayload) {
+    this.$accessor.remote.setQueue({ queue, remaining: remaining || 0, idleTimeout: idle_timeout || 0 })
+  }
+
+  /////////////////////////////
+  // Cursor Events
+  /////////////////////////////
+  // sent over websocket only until data channel is open
+  protected [EVENT.CURSOR.POSITION]({ x, y }: CursorPositionPayload) {
+    this.$accessor.remote.setCursorPosition({ x, y })
+  }
+
+  protected [EVENT.CURSOR.IMAGE](image: CursorImagePayload) {
+    this.$accessor.remote.setCursorImage(image)
+  }
+
   /////////////////////////////
   // Chat Events
   /////////////////////////////
@@ -577,6 +635,23 @@ export class NekoClient extends BaseClient implements EventEmitter<NekoEvents> {
     })
   }
 
+  protected [EVENT.ADMIN.PERMISSIONS]({ target, permissions }: AdminPermissionsPayload) {
+    if (!target) {
+      return
+    }
+
+    this.$accessor.user.setPermissions({ id: target, permissions })
+  }
+
+  protected [EVENT.ADMIN.ERROR]({ action, message }: AdminErrorPayload) {
+    this.$vue.$swal({
+      title: this.$vue.$t('connection.admin_error', { action }) as string,
+      text: message,
+      icon: 'error',
+      confirmButtonText: this.$vue.$t('connection.button_confirm') as string,
+    })
+  }
+
   // Utilities
   protected member(id: string): Member | undefined {
     return this.$accessor.user.members[id]
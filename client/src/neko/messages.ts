@@ -9,7 +9,7 @@ import {
   ScreenEvents,
   AdminEvents,
 } from './events'
-import { Member, ScreenConfigurations, ScreenResolution } from './types'
+import { Member, Permissions, ScreenConfigurations, ScreenResolution } from './types'
 
 export type WebSocketMessages =
   | WebSocketMessage
@@ -35,6 +35,9 @@ export type WebSocketPayloads =
   | ControlPayload
   | ControlClipboardPayload
   | ControlKeyboardPayload
+  | ControlQueuePayload
+  | CursorPositionPayload
+  | CursorImagePayload
   | ChatPayload
   | ChatSendPayload
   | EmojiSendPayload
@@ -42,6 +45,7 @@ export type WebSocketPayloads =
   | ScreenConfigurationsPayload
   | AdminPayload
   | AdminLockPayload
+  | AdminPermissionsPayload
   | BroadcastStatusPayload
   | BroadcastCreatePayload
 
@@ -58,6 +62,7 @@ export interface SystemInit extends WebSocketMessage, SystemInitPayload {
 }
 export interface SystemInitPayload {
   implicit_hosting: boolean
+  multi_control: boolean
   locks: Record<string, string>
 }
 
@@ -83,6 +88,7 @@ export interface SignalProvidePayload {
   lite: boolean
   ice: RTCIceServer[]
   sdp: string
+  resume?: string
 }
 
 // signal/offer
@@ -162,6 +168,32 @@ export interface ControlKeyboardPayload {
   scrollLock?: boolean
 }
 
+// control/queue
+export interface ControlQueuePayload {
+  host?: string
+  queue: string[]
+  remaining?: number
+  idle_timeout?: number
+}
+
+/*
+  CURSOR PAYLOADS
+*/
+// cursor/position
+export interface CursorPositionPayload {
+  x: number
+  y: number
+}
+
+// cursor/image
+export interface CursorImagePayload {
+  uri: string
+  width: number
+  height: number
+  x: number
+  y: number
+}
+
 /*
   CHAT PAYLOADS
 */
@@ -243,6 +275,26 @@ export interface AdminTargetPayload {
   target?: string
 }
 
+export interface AdminPermissionsMessage extends WebSocketMessage, AdminPermissionsPayload {
+  event: AdminEvents
+}
+
+export interface AdminPermissionsPayload {
+  id: string
+  target?: string
+  permissions: Permissions
+}
+
+// admin/error
+export interface AdminErrorMessage extends WebSocketMessage, AdminErrorPayload {
+  event: typeof EVENT.ADMIN.ERROR
+}
+
+export interface AdminErrorPayload {
+  action: string
+  message: string
+}
+
 export interface AdminLockMessage extends WebSocketMessage, AdminLockPayload {
   event: AdminEvents
   id: string
@@ -1,12 +1,24 @@
 export interface Member {
   id: string
   displayname: string
+  username?: string
   admin: boolean
   muted: boolean
+  permissions?: Permissions
   connected?: boolean
   ignored?: boolean
 }
 
+export interface Permissions {
+  can_watch: boolean
+  can_control: boolean
+  can_chat: boolean
+  can_use_clipboard: boolean
+  can_change_screen: boolean
+  can_broadcast: boolean
+  can_moderate: boolean
+}
+
 export interface ScreenConfigurations {
   [index: string]: ScreenConfiguration
 }
@@ -6,5 +6,6 @@ Vue.use(VueI18n)
 
 export const i18n = new VueI18n({
   locale: 'en',
+  fallbackLocale: 'en',
   messages,
 })
@@ -1,5 +1,6 @@
 import { getterTree, mutationTree, actionTree } from 'typed-vuex'
 import { Member } from '~/neko/types'
+import { CursorImage } from '~/neko/data'
 import { EVENT } from '~/neko/events'
 import { accessor } from '~/store'
 
@@ -13,7 +14,19 @@ export const state = () => ({
   clipboard: '',
   locked: false,
   implicitHosting: true,
+  multiControl: false,
+  // member that sent input last in multi control mode
+  inputId: '',
+  // cursor sent by server, when it is not captured in video
+  cursorImage: null as CursorImage | null,
+  cursorX: 0,
+  cursorY: 0,
   keyboardModifierState: -1,
+  queue: [] as string[],
+  // seconds left for the host, when queue was received
+  remaining: 0,
+  queueReceived: 0,
+  idleTimeout: 0,
 })
 
 export const getters = getterTree(state, {
@@ -26,6 +39,10 @@ export const getters = getterTree(state, {
   host: (state, getters, root) => {
     return root.user.members[state.id] || (state.implicitHosting && root.user.id) || null
   },
+  // 1-based position in control queue, 0 if not queued
+  queued: (state, getters, root) => {
+    return state.queue.indexOf(root.user.id) + 1
+  },
 })
 
 export const mutations = mutationTree(state, {
@@ -45,18 +62,55 @@ export const mutations = mutationTree(state, {
     state.keyboardModifierState = keyboardModifierState(capsLock, numLock, scrollLock)
   },
 
+  // lock keys changed on the desktop, scroll lock is not reported
+  setKeyboardLeds(state, { capsLock, numLock }: { capsLock: boolean; numLock: boolean }) {
+    const scrollLock = state.keyboardModifierState > 0 && (state.keyboardModifierState & 4) !== 0
+    state.keyboardModifierState = keyboardModifierState(capsLock, numLock, scrollLock)
+  },
+
   setLocked(state, locked: boolean) {
     state.locked = locked
   },
 
+  setQueue(state, { queue, remaining, idleTimeout }: { queue: string[]; remaining: number; idleTimeout: number }) {
+    state.queue = queue
+    state.remaining = remaining
+    state.queueReceived = Date.now()
+    state.idleTimeout = idleTimeout
+  },
+
   setImplicitHosting(state, val: boolean) {
     state.implicitHosting = val
   },
 
+  setMultiControl(state, val: boolean) {
+    state.multiControl = val
+  },
+
+  setInput(state, id: string) {
+    state.inputId = id
+  },
+
+  setCursorPosition(state, { x, y }: { x: number; y: number }) {
+    state.cursorX = x
+    state.cursorY = y
+  },
+
+  setCursorImage(state, image: CursorImage) {
+    if (state.cursorImage) {
+      URL.revokeObjectURL(state.cursorImage.uri)
+    }
+
+    state.cursorImage = image
+  },
+
   reset(state) {
     state.id = ''
     state.clipboard = ''
     state.locked = false
+    state.queue = []
+    state.remaining = 0
+    state.idleTimeout = 0
   },
 })
 
@@ -76,7 +130,9 @@ export const actions = actionTree(
         return
       }
 
-      if (!getters.hosting) {
+      if (getters.queued) {
+        $client.sendMessage(EVENT.CONTROL.DEQUEUE)
+      } else if (!getters.hosting) {
         $client.sendMessage(EVENT.CONTROL.REQUEST)
       } else {
         $client.sendMessage(EVENT.CONTROL.RELEASE)
@@ -91,6 +147,14 @@ export const actions = actionTree(
       $client.sendMessage(EVENT.CONTROL.REQUEST)
     },
 
+    dequeue({ getters }) {
+      if (!accessor.connected || !getters.queued) {
+        return
+      }
+
+      $client.sendMessage(EVENT.CONTROL.DEQUEUE)
+    },
+
     release({ getters }) {
       if (!accessor.connected || !getters.hosting) {
         return
@@ -1,5 +1,5 @@
 import { getterTree, mutationTree, actionTree } from 'typed-vuex'
-import { Member } from '~/neko/types'
+import { Member, Permissions } from '~/neko/types'
 import { EVENT } from '~/neko/events'
 
 import md from 'simple-markdown'
@@ -35,6 +35,12 @@ export const mutations = mutationTree(state, {
       muted,
     }
   },
+  setPermissions(state, { id, permissions }: { id: string; permissions: Permissions }) {
+    state.members[id] = {
+      ...state.members[id],
+      permissions,
+    }
+  },
   setMembers(state, members: Member[]) {
     const data: Members = {}
     for (const member of members) {
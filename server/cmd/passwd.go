@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"m1k1o/neko/internal/auth"
+)
+
+func init() {
+	command := &cobra.Command{
+		Use:   "passwd",
+		Short: "hash password for auth file",
+		Long:  `reads password from stdin and prints its argon2id hash, that can be used in auth file`,
+		Run: func(cmd *cobra.Command, args []string) {
+			password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && password == "" {
+				log.Panic().Err(err).Msg("unable to read password")
+			}
+
+			hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+			if err != nil {
+				log.Panic().Err(err).Msg("unable to hash password")
+			}
+
+			fmt.Println(hash)
+		},
+	}
+
+	root.AddCommand(command)
+}
@@ -19,9 +19,13 @@ func init() {
 	configs := []config.Config{
 		neko.Service.Server,
 		neko.Service.WebRTC,
+		neko.Service.TURN,
 		neko.Service.Capture,
 		neko.Service.Desktop,
 		neko.Service.WebSocket,
+		neko.Service.Session,
+		neko.Service.Zoom,
+		neko.Service.Auth,
 	}
 
 	cobra.OnInitialize(func() {
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/types"
+)
+
+type fileUser struct {
+	Password string     `json:"password"`
+	Role     types.Role `json:"role"`
+}
+
+// users file is checked for modifications at most this often
+const fileReloadInterval = 5 * time.Second
+
+// successful verifications are cached, so that clients sending credentials
+// with every request do not need password hashing each time
+const verifiedTimeout = time.Minute
+
+type verifiedUser struct {
+	user      types.User
+	expiresAt time.Time
+}
+
+// FileProvider authenticates users stored in a JSON file, that maps
+// usernames to password hashes and roles:
+//
+//	{ "alice": { "password": "$argon2id$...", "role": "admin" } }
+//
+// File is loaded again when it was modified.
+type FileProvider struct {
+	logger    zerolog.Logger
+	path      string
+	mu        sync.Mutex
+	modTime   time.Time
+	checkedAt time.Time
+	users     map[string]fileUser
+	verified  map[[sha256.Size]byte]verifiedUser
+
+	// unknown usernames are verified against dummy hash, so that
+	// they cannot be told apart by response time
+	dummyHash string
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	dummyHash, err := HashPassword("")
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &FileProvider{
+		logger:    log.With().Str("module", "auth").Str("submodule", "file").Logger(),
+		path:      path,
+		verified:  map[[sha256.Size]byte]verifiedUser{},
+		dummyHash: dummyHash,
+	}
+
+	if err := provider.load(); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (p *FileProvider) load() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+
+	if !info.ModTime().After(p.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	users := map[string]fileUser{}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+
+	for username, user := range users {
+		if username == "" {
+			return fmt.Errorf("empty username")
+		}
+
+		if !user.Role.Valid() {
+			return fmt.Errorf("user %s has unknown role %q", username, user.Role)
+		}
+	}
+
+	p.users = users
+	p.modTime = info.ModTime()
+	p.verified = map[[sha256.Size]byte]verifiedUser{}
+	p.logger.Info().Int("users", len(users)).Msg("users loaded")
+	return nil
+}
+
+func (p *FileProvider) Authenticate(username string, password string) (*types.User, error) {
+	now := time.Now()
+
+	p.mu.Lock()
+	if now.Sub(p.checkedAt) >= fileReloadInterval {
+		p.checkedAt = now
+
+		// keep previously loaded users, if file is being rewritten
+		if err := p.load(); err != nil {
+			p.logger.Warn().Err(err).Msg("unable to reload users")
+		}
+	}
+
+	user, ok := p.users[username]
+	hash := p.dummyHash
+	if ok {
+		hash = user.Password
+	}
+
+	// key contains hash of the user, so that cache is not valid after password change
+	key := sha256.Sum256([]byte(hash + "\x00" + username + "\x00" + password))
+	verified, cached := p.verified[key]
+	p.mu.Unlock()
+
+	if cached && now.Before(verified.expiresAt) {
+		result := verified.user
+		return &result, nil
+	}
+
+	match, err := VerifyPassword(hash, password)
+	if err != nil {
+		p.logger.Warn().Err(err).Str("username", username).Msg("unable to verify password")
+		return nil, types.ErrInvalidCredentials
+	}
+
+	if !ok || !match {
+		return nil, types.ErrInvalidCredentials
+	}
+
+	result := types.User{
+		Username: username,
+		Role:     user.Role,
+	}
+
+	p.mu.Lock()
+	for key, val := range p.verified {
+		if now.After(val.expiresAt) {
+			delete(p.verified, key)
+		}
+	}
+	p.verified[key] = verifiedUser{
+		user:      result,
+		expiresAt: now.Add(verifiedTimeout),
+	}
+	p.mu.Unlock()
+
+	return &result, nil
+}
+
+func (p *FileProvider) Role(username string) (types.Role, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	user, ok := p.users[username]
+	return user.Role, ok
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/utils"
+)
+
+const (
+	inviteDefaultExpiry = 24 * time.Hour
+	inviteMaxExpiry     = 30 * 24 * time.Hour
+)
+
+// inviteClaims are signed in the invite token, limits of uses and
+// revocation are checked against outstanding invites.
+type inviteClaims struct {
+	Role types.Role `json:"role"`
+	Name string     `json:"name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// CreateInvite mints signed invite token, that can be used instead of
+// credentials when connecting to the room.
+func (manager *AuthManager) CreateInvite(createdBy string, createdByAdmin bool, options types.InviteOptions) (string, *types.Invite, error) {
+	if options.Role == "" {
+		options.Role = types.RoleParticipant
+	}
+
+	if !options.Role.Valid() || options.ExpiresIn < 0 || options.MaxUses < 0 {
+		return "", nil, types.ErrInviteInvalid
+	}
+
+	expiry := inviteDefaultExpiry
+	if options.ExpiresIn > 0 {
+		expiry = time.Duration(options.ExpiresIn) * time.Second
+	}
+
+	if expiry > inviteMaxExpiry {
+		return "", nil, types.ErrInviteInvalid
+	}
+
+	id, err := utils.NewUID(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	invite := &types.Invite{
+		ID:             id,
+		Role:           options.Role,
+		Name:           options.Name,
+		MaxUses:        options.MaxUses,
+		CreatedBy:      createdBy,
+		CreatedByAdmin: createdByAdmin,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(expiry),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, inviteClaims{
+		Role: invite.Role,
+		Name: invite.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invite.ID,
+			IssuedAt:  jwt.NewNumericDate(invite.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(invite.ExpiresAt),
+		},
+	})
+
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	signed, err := token.SignedString(manager.inviteKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for key, val := range manager.invites {
+		if now.After(val.ExpiresAt) {
+			manager.removeInvite(key)
+		}
+	}
+
+	manager.invites[invite.ID] = invite
+	manager.storeInvite(invite)
+
+	result := *invite
+	return signed, &result, nil
+}
+
+// VerifyInvite checks signature of a token and whether its invite can
+// still be used. It does not count as use of the invite.
+func (manager *AuthManager) VerifyInvite(token string) (*types.Invite, error) {
+	manager.invitesMu.Lock()
+	key := manager.inviteKey
+	manager.invitesMu.Unlock()
+
+	claims := &inviteClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		if validation, ok := err.(*jwt.ValidationError); ok && validation.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, types.ErrInviteExpired
+		}
+
+		return nil, types.ErrInviteNotFound
+	}
+
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	invite, err := manager.invite(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *invite
+	return &result, nil
+}
+
+// UseInvite counts use of an invite, it fails if the invite was used up.
+func (manager *AuthManager) UseInvite(id string) error {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	invite, err := manager.invite(id)
+	if err != nil {
+		return err
+	}
+
+	invite.Uses++
+	manager.storeInvite(invite)
+	return nil
+}
+
+// RevokeInvite removes outstanding invite, invites created by admins
+// can be revoked only by admins.
+func (manager *AuthManager) RevokeInvite(id string, admin bool) error {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	invite, ok := manager.invites[id]
+	if !ok {
+		return types.ErrInviteNotFound
+	}
+
+	if invite.CreatedByAdmin && !admin {
+		return types.ErrMemberNoPermission
+	}
+
+	manager.removeInvite(id)
+	return nil
+}
+
+// Invites returns outstanding invites, oldest first.
+func (manager *AuthManager) Invites() []types.Invite {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	now := time.Now()
+	invites := []types.Invite{}
+	for _, invite := range manager.invites {
+		if now.After(invite.ExpiresAt) {
+			continue
+		}
+
+		invites = append(invites, *invite)
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+
+	return invites
+}
+
+// invite returns outstanding invite, that can be used. Invites mutex
+// must be held by the caller.
+func (manager *AuthManager) invite(id string) (*types.Invite, error) {
+	invite, ok := manager.invites[id]
+	if !ok {
+		return nil, types.ErrInviteNotFound
+	}
+
+	if time.Now().After(invite.ExpiresAt) {
+		manager.removeInvite(id)
+		return nil, types.ErrInviteExpired
+	}
+
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return nil, types.ErrInviteUsedUp
+	}
+
+	return invite, nil
+}
+
+// SetStore loads signing key and outstanding invites from the store, so
+// that invites survive restarts. Changes are persisted to the store.
+func (manager *AuthManager) SetStore(store types.InviteStore) {
+	manager.invitesMu.Lock()
+	defer manager.invitesMu.Unlock()
+
+	manager.store = store
+
+	if key := store.InviteKey(); len(key) > 0 {
+		manager.inviteKey = key
+	} else {
+		store.SetInviteKey(manager.inviteKey)
+	}
+
+	now := time.Now()
+	for _, invite := range store.StoredInvites() {
+		if now.After(invite.ExpiresAt) {
+			store.RemoveInvite(invite.ID)
+			continue
+		}
+
+		invite := invite
+		manager.invites[invite.ID] = &invite
+	}
+}
+
+// storeInvite persists invite, invites mutex must be held by the caller.
+func (manager *AuthManager) storeInvite(invite *types.Invite) {
+	if manager.store != nil {
+		manager.store.StoreInvite(*invite)
+	}
+}
+
+// removeInvite deletes invite, invites mutex must be held by the caller.
+func (manager *AuthManager) removeInvite(id string) {
+	delete(manager.invites, id)
+
+	if manager.store != nil {
+		manager.store.RemoveInvite(id)
+	}
+}
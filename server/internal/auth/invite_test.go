@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+func newTestManager() *AuthManager {
+	return New(&config.Auth{}, &config.WebSocket{})
+}
+
+// memoryStore keeps invites in memory, as they would be persisted.
+type memoryStore struct {
+	key     []byte
+	invites map[string]types.Invite
+}
+
+func (s *memoryStore) InviteKey() []byte       { return s.key }
+func (s *memoryStore) SetInviteKey(key []byte) { s.key = key }
+func (s *memoryStore) StoreInvite(invite types.Invite) {
+	s.invites[invite.ID] = invite
+}
+func (s *memoryStore) RemoveInvite(id string) { delete(s.invites, id) }
+func (s *memoryStore) StoredInvites() []types.Invite {
+	invites := []types.Invite{}
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+	return invites
+}
+
+func TestCreateInvite(t *testing.T) {
+	tests := []struct {
+		name    string
+		options types.InviteOptions
+		err     error
+	}{
+		{"default", types.InviteOptions{}, nil},
+		{"viewer", types.InviteOptions{Role: types.RoleViewer, MaxUses: 1, ExpiresIn: 60}, nil},
+		{"max expiry", types.InviteOptions{ExpiresIn: int(inviteMaxExpiry / time.Second)}, nil},
+		{"too long", types.InviteOptions{ExpiresIn: int(inviteMaxExpiry/time.Second) + 1}, types.ErrInviteInvalid},
+		{"negative expiry", types.InviteOptions{ExpiresIn: -1}, types.ErrInviteInvalid},
+		{"negative uses", types.InviteOptions{MaxUses: -1}, types.ErrInviteInvalid},
+		{"invalid role", types.InviteOptions{Role: "owner"}, types.ErrInviteInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := newTestManager()
+
+			token, invite, err := manager.CreateInvite("admin", true, test.options)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error = %v, want %v", err, test.err)
+			}
+
+			if err != nil {
+				if len(manager.Invites()) != 0 {
+					t.Fatal("invalid invite is stored")
+				}
+				return
+			}
+
+			verified, err := manager.VerifyInvite(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if verified.ID != invite.ID || verified.Role != invite.Role || !verified.ExpiresAt.Equal(invite.ExpiresAt) {
+				t.Fatalf("verified invite = %+v, want %+v", verified, invite)
+			}
+
+			if test.options.Role == "" && invite.Role != types.RoleParticipant {
+				t.Fatalf("default role = %s, want %s", invite.Role, types.RoleParticipant)
+			}
+		})
+	}
+}
+
+func TestVerifyInviteSignature(t *testing.T) {
+	manager := newTestManager()
+
+	token, invite, err := manager.CreateInvite("admin", true, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same claims, signed by a different key
+	forged, _, err := newTestManager().CreateInvite("admin", true, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := inviteClaims{
+		Role: types.RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invite.ID,
+			ExpiresAt: jwt.NewNumericDate(invite.ExpiresAt),
+		},
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []byte(token)
+	if tampered[len(tampered)-2] == 'A' {
+		tampered[len(tampered)-2] = 'B'
+	} else {
+		tampered[len(tampered)-2] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"tampered", string(tampered)},
+		{"other key", forged},
+		{"unsigned", unsigned},
+		{"empty", ""},
+		{"garbage", "invite"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := manager.VerifyInvite(test.token); !errors.Is(err, types.ErrInviteNotFound) {
+				t.Fatalf("error = %v, want %v", err, types.ErrInviteNotFound)
+			}
+		})
+	}
+}
+
+func TestVerifyInviteExpired(t *testing.T) {
+	manager := newTestManager()
+
+	token, invite, err := manager.CreateInvite("admin", true, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// token signed with expiry in the past
+	claims := inviteClaims{
+		Role: invite.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invite.ID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}
+
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(manager.inviteKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.VerifyInvite(expired); !errors.Is(err, types.ErrInviteExpired) {
+		t.Fatalf("expired token error = %v, want %v", err, types.ErrInviteExpired)
+	}
+
+	// outstanding invite expired, even though token was not
+	manager.invites[invite.ID].ExpiresAt = time.Now().Add(-time.Second)
+
+	if _, err := manager.VerifyInvite(token); !errors.Is(err, types.ErrInviteExpired) {
+		t.Fatalf("expired invite error = %v, want %v", err, types.ErrInviteExpired)
+	}
+
+	if _, ok := manager.invites[invite.ID]; ok {
+		t.Fatal("expired invite is not removed")
+	}
+}
+
+func TestUseInvite(t *testing.T) {
+	manager := newTestManager()
+
+	token, invite, err := manager.CreateInvite("admin", true, types.InviteOptions{MaxUses: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.VerifyInvite(token); err != nil {
+			t.Fatalf("use %d: verify error = %v", i, err)
+		}
+
+		if err := manager.UseInvite(invite.ID); err != nil {
+			t.Fatalf("use %d: error = %v", i, err)
+		}
+	}
+
+	if _, err := manager.VerifyInvite(token); !errors.Is(err, types.ErrInviteUsedUp) {
+		t.Fatalf("verify error = %v, want %v", err, types.ErrInviteUsedUp)
+	}
+
+	if err := manager.UseInvite(invite.ID); !errors.Is(err, types.ErrInviteUsedUp) {
+		t.Fatalf("use error = %v, want %v", err, types.ErrInviteUsedUp)
+	}
+
+	// invites without limit can be used any number of times
+	token, invite, err = manager.CreateInvite("admin", true, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := manager.UseInvite(invite.ID); err != nil {
+			t.Fatalf("use %d: error = %v", i, err)
+		}
+	}
+
+	if _, err := manager.VerifyInvite(token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokeInvite(t *testing.T) {
+	manager := newTestManager()
+
+	adminToken, adminInvite, err := manager.CreateInvite("admin", true, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, invite, err := manager.CreateInvite("moderator", false, types.InviteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// moderators cannot revoke invites of admins
+	if err := manager.RevokeInvite(adminInvite.ID, false); !errors.Is(err, types.ErrMemberNoPermission) {
+		t.Fatalf("error = %v, want %v", err, types.ErrMemberNoPermission)
+	}
+
+	if _, err := manager.VerifyInvite(adminToken); err != nil {
+		t.Fatalf("invite revoked without permission: %v", err)
+	}
+
+	if err := manager.RevokeInvite(invite.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.RevokeInvite(adminInvite.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{token, adminToken} {
+		if _, err := manager.VerifyInvite(token); !errors.Is(err, types.ErrInviteNotFound) {
+			t.Fatalf("revoked invite error = %v, want %v", err, types.ErrInviteNotFound)
+		}
+	}
+
+	if err := manager.RevokeInvite(invite.ID, true); !errors.Is(err, types.ErrInviteNotFound) {
+		t.Fatalf("error = %v, want %v", err, types.ErrInviteNotFound)
+	}
+
+	if invites := manager.Invites(); len(invites) != 0 {
+		t.Fatalf("invites = %+v, want none", invites)
+	}
+}
+
+func TestInviteStore(t *testing.T) {
+	store := &memoryStore{invites: map[string]types.Invite{}}
+
+	manager := newTestManager()
+	manager.SetStore(store)
+
+	token, invite, err := manager.CreateInvite("admin", true, types.InviteOptions{MaxUses: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.UseInvite(invite.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens survive restart, together with counted uses
+	restarted := newTestManager()
+	restarted.SetStore(store)
+
+	verified, err := restarted.VerifyInvite(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if verified.Uses != 1 {
+		t.Fatalf("uses = %d, want 1", verified.Uses)
+	}
+
+	if err := restarted.RevokeInvite(invite.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.invites) != 0 {
+		t.Fatalf("revoked invite is still stored: %+v", store.invites)
+	}
+}
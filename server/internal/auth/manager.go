@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/rand"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+type AuthManager struct {
+	logger    zerolog.Logger
+	config    *config.Auth
+	websocket *config.WebSocket
+	provider  types.AuthProvider
+
+	invites   map[string]*types.Invite
+	invitesMu sync.Mutex
+	inviteKey []byte
+	store     types.InviteStore
+}
+
+func New(config *config.Auth, websocket *config.WebSocket) *AuthManager {
+	logger := log.With().Str("module", "auth").Logger()
+
+	// replaced by persisted key, once store is set
+	inviteKey := make([]byte, 32)
+	if _, err := rand.Read(inviteKey); err != nil {
+		logger.Panic().Err(err).Msg("unable to generate invite key")
+	}
+
+	return &AuthManager{
+		logger:    logger,
+		config:    config,
+		websocket: websocket,
+		invites:   make(map[string]*types.Invite),
+		inviteKey: inviteKey,
+	}
+}
+
+func (manager *AuthManager) Start() {
+	switch manager.config.Provider {
+	case "file":
+		provider, err := NewFileProvider(manager.config.File)
+		if err != nil {
+			manager.logger.Panic().Err(err).Str("path", manager.config.File).Msg("unable to load users")
+		}
+
+		manager.provider = provider
+		manager.logger.Info().Str("path", manager.config.File).Msg("using file auth provider")
+	default:
+		manager.provider = NewPasswordProvider(manager.websocket.Password, manager.websocket.AdminPassword)
+		manager.logger.Info().Msg("using password auth provider")
+	}
+}
+
+func (manager *AuthManager) Shutdown() error {
+	return nil
+}
+
+func (manager *AuthManager) Authenticate(username string, password string) (*types.User, error) {
+	return manager.provider.Authenticate(username, password)
+}
+
+func (manager *AuthManager) Role(username string) (types.Role, bool) {
+	return manager.provider.Role(username)
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/subtle"
+
+	"m1k1o/neko/internal/types"
+)
+
+// PasswordProvider authenticates using two shared passwords, username is ignored.
+// Empty password disables its role.
+type PasswordProvider struct {
+	password      []byte
+	adminPassword []byte
+}
+
+func NewPasswordProvider(password string, adminPassword string) *PasswordProvider {
+	return &PasswordProvider{
+		password:      []byte(password),
+		adminPassword: []byte(adminPassword),
+	}
+}
+
+func (p *PasswordProvider) Authenticate(username string, password string) (*types.User, error) {
+	if len(p.adminPassword) > 0 && subtle.ConstantTimeCompare([]byte(password), p.adminPassword) == 1 {
+		return &types.User{Role: types.RoleAdmin}, nil
+	}
+
+	if len(p.password) > 0 && subtle.ConstantTimeCompare([]byte(password), p.password) == 1 {
+		return &types.User{Role: types.RoleParticipant}, nil
+	}
+
+	return nil, types.ErrInvalidCredentials
+}
+
+// Role does not know any users, they are not named.
+func (p *PasswordProvider) Role(username string) (types.Role, bool) {
+	return "", false
+}
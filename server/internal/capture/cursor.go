@@ -0,0 +1,163 @@
+package capture
+
+import (
+	"bytes"
+	"image/png"
+	"sync"
+	"time"
+
+	"github.com/kataras/go-events"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/types"
+)
+
+// cursor is not reported by X when it moves, its position is polled
+const cursorPositionInterval = time.Second / 60
+
+// CursorManagerCtx reports cursor position and image, when cursor is not
+// captured in video. Images are fetched only when cursor serial changes.
+type CursorManagerCtx struct {
+	logger  zerolog.Logger
+	desktop types.DesktopManager
+	enabled bool
+	emmiter events.EventEmmiter
+
+	mu    sync.Mutex
+	x, y  int
+	image *types.CursorImage
+	png   []byte
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+func cursorNew(desktop types.DesktopManager, enabled bool) *CursorManagerCtx {
+	return &CursorManagerCtx{
+		logger:  log.With().Str("module", "capture").Str("submodule", "cursor").Logger(),
+		desktop: desktop,
+		enabled: enabled,
+		emmiter: events.New(),
+		done:    make(chan struct{}),
+	}
+}
+
+func (manager *CursorManagerCtx) start() {
+	if !manager.enabled {
+		return
+	}
+
+	manager.desktop.OnCursorChanged(func(serial uint64) {
+		manager.update(serial)
+	})
+
+	// fetch initial image
+	manager.update(0)
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+
+		ticker := time.NewTicker(cursorPositionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-manager.done:
+				return
+			case <-ticker.C:
+				manager.poll()
+			}
+		}
+	}()
+}
+
+func (manager *CursorManagerCtx) shutdown() {
+	if !manager.enabled {
+		return
+	}
+
+	manager.logger.Info().Msgf("shutdown")
+
+	close(manager.done)
+	manager.wg.Wait()
+}
+
+func (manager *CursorManagerCtx) poll() {
+	x, y := manager.desktop.GetCursorPosition()
+
+	manager.mu.Lock()
+	if manager.x == x && manager.y == y {
+		manager.mu.Unlock()
+		return
+	}
+
+	manager.x, manager.y = x, y
+	manager.mu.Unlock()
+
+	manager.emmiter.Emit("position", x, y)
+}
+
+func (manager *CursorManagerCtx) update(serial uint64) {
+	manager.mu.Lock()
+	if manager.image != nil && manager.image.Serial == serial {
+		manager.mu.Unlock()
+		return
+	}
+	manager.mu.Unlock()
+
+	image := manager.desktop.GetCursorImage()
+	if image == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.Image); err != nil {
+		manager.logger.Warn().Err(err).Msg("unable to encode cursor image")
+		return
+	}
+
+	manager.mu.Lock()
+	// serial of fetched image can be the same, if cursor changed back
+	if manager.image != nil && manager.image.Serial == image.Serial {
+		manager.mu.Unlock()
+		return
+	}
+
+	manager.image = image
+	manager.png = buf.Bytes()
+	manager.mu.Unlock()
+
+	manager.emmiter.Emit("image", image, buf.Bytes())
+}
+
+func (manager *CursorManagerCtx) Enabled() bool {
+	return manager.enabled
+}
+
+func (manager *CursorManagerCtx) Position() (int, int) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.x, manager.y
+}
+
+func (manager *CursorManagerCtx) Image() (*types.CursorImage, []byte) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.image, manager.png
+}
+
+func (manager *CursorManagerCtx) OnPosition(listener func(x int, y int)) {
+	manager.emmiter.On("position", func(payload ...interface{}) {
+		listener(payload[0].(int), payload[1].(int))
+	})
+}
+
+func (manager *CursorManagerCtx) OnImage(listener func(image *types.CursorImage, png []byte)) {
+	manager.emmiter.On("image", func(payload ...interface{}) {
+		listener(payload[0].(*types.CursorImage), payload[1].([]byte))
+	})
+}
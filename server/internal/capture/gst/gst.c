@@ -166,6 +166,18 @@ gboolean gstreamer_pipeline_set_prop_int(GstPipelineCtx *ctx, char *binName, cha
   return TRUE;
 }
 
+gboolean gstreamer_pipeline_force_key_unit(GstPipelineCtx *ctx) {
+  if (ctx->appsink == NULL) return FALSE;
+
+  // upstream event is sent from sink to the encoder
+  GstStructure *s = gst_structure_new("GstForceKeyUnit",
+    "all-headers", G_TYPE_BOOLEAN, TRUE,
+    NULL);
+
+  GstEvent *event = gst_event_new_custom(GST_EVENT_CUSTOM_UPSTREAM, s);
+  return gst_element_send_event(ctx->appsink, event);
+}
+
 gboolean gstreamer_pipeline_set_caps_framerate(GstPipelineCtx *ctx, const gchar* binName, gint numerator, gint denominator) {
   GstElement *el = gst_bin_get_by_name(GST_BIN(ctx->pipeline), binName);
   if (el == NULL) return FALSE;
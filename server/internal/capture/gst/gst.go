@@ -125,6 +125,14 @@ func (p *Pipeline) SetPropInt(binName string, prop string, value int) bool {
 	return ok == C.TRUE
 }
 
+// ForceKeyUnit asks encoder upstream of appsink to produce a keyframe.
+func (p *Pipeline) ForceKeyUnit() bool {
+	p.logger.Debug().Msg("forcing key unit")
+
+	ok := C.gstreamer_pipeline_force_key_unit(p.Ctx)
+	return ok == C.TRUE
+}
+
 func (p *Pipeline) SetCapsFramerate(binName string, numerator, denominator int) bool {
 	cBinName := C.CString(binName)
 	cNumerator := C.int(numerator)
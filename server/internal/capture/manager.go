@@ -17,12 +17,30 @@ type CaptureManagerCtx struct {
 	// sinks
 	broadcast *BroacastManagerCtx
 	audio     *StreamSinkManagerCtx
-	video     *StreamSinkManagerCtx
+	videos    []*StreamSinkManagerCtx
+	cursor    *CursorManagerCtx
 }
 
 func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCtx {
 	logger := log.With().Str("module", "capture").Logger()
 
+	// encoder of custom pipeline is not known
+	var videoBitrateFn func(bitrate uint) (string, int)
+	if config.VideoPipeline == "" {
+		videoBitrateFn = func(bitrate uint) (string, int) {
+			return VideoBitrateProp(config.VideoCodec, config.VideoHWEnc, bitrate)
+		}
+	}
+
+	// each ladder entry has its own pipeline, started by first listener
+	videos := make([]*StreamSinkManagerCtx, len(config.VideoLadder))
+	for i, entry := range config.VideoLadder {
+		entry := entry
+		videos[i] = streamSinkNew(config.VideoCodec, func() (string, error) {
+			return NewVideoPipeline(config.VideoCodec, config.Display, config.VideoPipeline, config.VideoMaxFPS, entry.Bitrate, config.VideoHWEnc, config.ShowPointer, entry.Height)
+		}, entry.ID, entry.Bitrate, videoBitrateFn)
+	}
+
 	return &CaptureManagerCtx{
 		logger:  logger,
 		desktop: desktop,
@@ -33,14 +51,15 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 		}, config.BroadcastUrl),
 		audio: streamSinkNew(config.AudioCodec, func() (string, error) {
 			return NewAudioPipeline(config.AudioCodec, config.AudioDevice, config.AudioPipeline, config.AudioBitrate)
-		}, "audio"),
-		video: streamSinkNew(config.VideoCodec, func() (string, error) {
-			return NewVideoPipeline(config.VideoCodec, config.Display, config.VideoPipeline, config.VideoMaxFPS, config.VideoBitrate, config.VideoHWEnc)
-		}, "video"),
+		}, "audio", config.AudioBitrate, nil),
+		videos: videos,
+		cursor: cursorNew(desktop, !config.ShowPointer),
 	}
 }
 
 func (manager *CaptureManagerCtx) Start() {
+	manager.cursor.start()
+
 	if manager.broadcast.Started() {
 		if err := manager.broadcast.createPipeline(); err != nil {
 			manager.logger.Panic().Err(err).Msg("unable to create broadcast pipeline")
@@ -48,8 +67,10 @@ func (manager *CaptureManagerCtx) Start() {
 	}
 
 	manager.desktop.OnBeforeScreenSizeChange(func() {
-		if manager.video.Started() {
-			manager.video.destroyPipeline()
+		for _, video := range manager.videos {
+			if video.Started() {
+				video.destroyPipeline()
+			}
 		}
 
 		if manager.broadcast.Started() {
@@ -58,10 +79,12 @@ func (manager *CaptureManagerCtx) Start() {
 	})
 
 	manager.desktop.OnAfterScreenSizeChange(func() {
-		if manager.video.Started() {
-			err := manager.video.createPipeline()
-			if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
-				manager.logger.Panic().Err(err).Msg("unable to recreate video pipeline")
+		for _, video := range manager.videos {
+			if video.Started() {
+				err := video.createPipeline()
+				if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
+					manager.logger.Panic().Err(err).Msg("unable to recreate video pipeline")
+				}
 			}
 		}
 
@@ -80,7 +103,10 @@ func (manager *CaptureManagerCtx) Shutdown() error {
 	manager.broadcast.shutdown()
 
 	manager.audio.shutdown()
-	manager.video.shutdown()
+	for _, video := range manager.videos {
+		video.shutdown()
+	}
+	manager.cursor.shutdown()
 
 	return nil
 }
@@ -94,5 +120,18 @@ func (manager *CaptureManagerCtx) Audio() types.StreamSinkManager {
 }
 
 func (manager *CaptureManagerCtx) Video() types.StreamSinkManager {
-	return manager.video
+	return manager.videos[0]
+}
+
+func (manager *CaptureManagerCtx) VideoLadder() []types.StreamSinkManager {
+	videos := make([]types.StreamSinkManager, len(manager.videos))
+	for i, video := range manager.videos {
+		videos[i] = video
+	}
+
+	return videos
+}
+
+func (manager *CaptureManagerCtx) Cursor() types.CursorManager {
+	return manager.cursor
 }
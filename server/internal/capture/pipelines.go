@@ -29,12 +29,13 @@ import (
 */
 
 const (
-	videoSrc = "ximagesrc display-name=%s show-pointer=true use-damage=false ! video/x-raw,framerate=%d/1 ! videoconvert ! queue ! "
-	audioSrc = "pulsesrc device=%s ! audio/x-raw,channels=2 ! audioconvert ! "
+	videoSrc   = "ximagesrc display-name=%s show-pointer=%t use-damage=false ! video/x-raw,framerate=%d/1 ! videoconvert ! %squeue ! "
+	videoScale = "videoscale ! video/x-raw,height=%d ! "
+	audioSrc   = "pulsesrc device=%s ! audio/x-raw,channels=2 ! audioconvert ! "
 )
 
 func NewBroadcastPipeline(device string, display string, pipelineSrc string, url string) (string, error) {
-	video := fmt.Sprintf(videoSrc, display, 25)
+	video := fmt.Sprintf(videoSrc, display, true, 25, "")
 	audio := fmt.Sprintf(audioSrc, device)
 
 	var pipelineStr string
@@ -52,7 +53,7 @@ func NewBroadcastPipeline(device string, display string, pipelineSrc string, url
 	return pipelineStr, nil
 }
 
-func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc string, fps int16, bitrate uint, hwenc string) (string, error) {
+func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc string, fps int16, bitrate uint, hwenc string, showPointer bool, height int) (string, error) {
 	pipelineStr := " ! appsink name=appsink"
 
 	// if using custom pipeline
@@ -61,6 +62,11 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 		return pipelineStr, nil
 	}
 
+	scale := ""
+	if height > 0 {
+		scale = fmt.Sprintf(videoScale, height)
+	}
+
 	switch rtpCodec.Name {
 	case codec.VP8().Name:
 		if hwenc == "VAAPI" {
@@ -70,7 +76,7 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 			// vp8 encode is missing from gstreamer.freedesktop.org/documentation
 			// note that it was removed from some recent intel CPUs: https://trac.ffmpeg.org/wiki/Hardware/QuickSync
 			// https://gstreamer.freedesktop.org/data/doc/gstreamer/head/gstreamer-vaapi-plugins/html/gstreamer-vaapi-plugins-vaapivp8enc.html
-			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! vaapivp8enc rate-control=vbr bitrate=%d keyframe-period=180"+pipelineStr, display, fps, bitrate)
+			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! vaapivp8enc name=encoder rate-control=vbr bitrate=%d keyframe-period=180"+pipelineStr, display, showPointer, fps, scale, bitrate)
 		} else {
 			// https://gstreamer.freedesktop.org/documentation/vpx/vp8enc.html?gi-language=c
 			// gstreamer1.0-plugins-good
@@ -80,8 +86,8 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 			}
 
 			pipelineStr = strings.Join([]string{
-				fmt.Sprintf(videoSrc, display, fps),
-				"vp8enc",
+				fmt.Sprintf(videoSrc, display, showPointer, fps, scale),
+				"vp8enc name=encoder",
 				fmt.Sprintf("target-bitrate=%d", bitrate*650),
 				"cpu-used=4",
 				"end-usage=cbr",
@@ -105,7 +111,7 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 			return "", err
 		}
 
-		pipelineStr = fmt.Sprintf(videoSrc+"vp9enc target-bitrate=%d cpu-used=-5 threads=4 deadline=1 keyframe-max-dist=30 auto-alt-ref=true"+pipelineStr, display, fps, bitrate*1000)
+		pipelineStr = fmt.Sprintf(videoSrc+"vp9enc name=encoder target-bitrate=%d cpu-used=-5 threads=4 deadline=1 keyframe-max-dist=30 auto-alt-ref=true"+pipelineStr, display, showPointer, fps, scale, bitrate*1000)
 	case codec.H264().Name:
 		if err := gst.CheckPlugins([]string{"ximagesrc"}); err != nil {
 			return "", err
@@ -116,14 +122,14 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 				return "", err
 			}
 
-			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! vaapih264enc rate-control=vbr bitrate=%d keyframe-period=180 quality-level=7 ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, fps, bitrate)
+			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! vaapih264enc name=encoder rate-control=vbr bitrate=%d keyframe-period=180 quality-level=7 ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, showPointer, fps, scale, bitrate)
 
 		} else {
 			// https://gstreamer.freedesktop.org/documentation/openh264/openh264enc.html?gi-language=c#openh264enc
 			// gstreamer1.0-plugins-bad
 			// openh264enc multi-thread=4 complexity=high bitrate=3072000 max-bitrate=4096000
 			if err := gst.CheckPlugins([]string{"openh264"}); err == nil {
-				pipelineStr = fmt.Sprintf(videoSrc+"openh264enc multi-thread=4 complexity=high bitrate=%d max-bitrate=%d ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, fps, bitrate*1000, (bitrate+1024)*1000)
+				pipelineStr = fmt.Sprintf(videoSrc+"openh264enc name=encoder multi-thread=4 complexity=high bitrate=%d max-bitrate=%d ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, showPointer, fps, scale, bitrate*1000, (bitrate+1024)*1000)
 				break
 			}
 
@@ -139,7 +145,7 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 				vbvbuf = bitrate
 			}
 
-			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! x264enc threads=4 bitrate=%d key-int-max=60 vbv-buf-capacity=%d byte-stream=true tune=zerolatency speed-preset=veryfast ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, fps, bitrate, vbvbuf)
+			pipelineStr = fmt.Sprintf(videoSrc+"video/x-raw,format=NV12 ! x264enc name=encoder threads=4 bitrate=%d key-int-max=60 vbv-buf-capacity=%d byte-stream=true tune=zerolatency speed-preset=veryfast ! video/x-h264,stream-format=byte-stream"+pipelineStr, display, showPointer, fps, scale, bitrate, vbvbuf)
 		}
 	default:
 		return "", fmt.Errorf("unknown codec %s", rtpCodec.Name)
@@ -148,6 +154,33 @@ func NewVideoPipeline(rtpCodec codec.RTPCodec, display string, pipelineSrc strin
 	return pipelineStr, nil
 }
 
+// VideoBitrateProp returns bitrate property of encoder in video pipeline
+// and its value, so that bitrate can be changed at runtime.
+func VideoBitrateProp(rtpCodec codec.RTPCodec, hwenc string, bitrate uint) (string, int) {
+	switch rtpCodec.Name {
+	case codec.VP8().Name:
+		if hwenc == "VAAPI" {
+			return "bitrate", int(bitrate)
+		}
+
+		return "target-bitrate", int(bitrate * 650)
+	case codec.VP9().Name:
+		return "target-bitrate", int(bitrate * 1000)
+	case codec.H264().Name:
+		if hwenc == "VAAPI" {
+			return "bitrate", int(bitrate)
+		}
+
+		if err := gst.CheckPlugins([]string{"openh264"}); err == nil {
+			return "bitrate", int(bitrate * 1000)
+		}
+
+		return "bitrate", int(bitrate)
+	}
+
+	return "", 0
+}
+
 func NewAudioPipeline(rtpCodec codec.RTPCodec, device string, pipelineSrc string, bitrate uint) (string, error) {
 	pipelineStr := " ! appsink name=appsink"
 
@@ -2,7 +2,9 @@ package capture
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -12,23 +14,36 @@ import (
 	"m1k1o/neko/internal/types/codec"
 )
 
+// keyframes are requested by every viewer, they are forced at most this
+// often, so that encoder shared by many viewers is not flooded. Requests
+// within the interval are merged into one keyframe at its end.
+const keyUnitInterval = 500 * time.Millisecond
+
 type StreamSinkManagerCtx struct {
 	logger zerolog.Logger
 	mu     sync.Mutex
 	wg     sync.WaitGroup
 
+	id         string
+	bitrate    uint
 	codec      codec.RTPCodec
 	pipeline   *gst.Pipeline
 	pipelineMu sync.Mutex
 	pipelineFn func() (string, error)
 
+	// encoder bitrate, that can be changed at runtime
+	bitrateFn      func(bitrate uint) (string, int)
+	encoderBitrate uint
+	lastKeyUnit    time.Time
+	keyUnitPending bool
+
 	listeners   int
 	listenersMu sync.Mutex
 
 	sampleFn func(sample types.Sample)
 }
 
-func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), video_id string) *StreamSinkManagerCtx {
+func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), video_id string, bitrate uint, bitrateFn func(bitrate uint) (string, int)) *StreamSinkManagerCtx {
 	logger := log.With().
 		Str("module", "capture").
 		Str("submodule", "stream-sink").
@@ -36,8 +51,11 @@ func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), vide
 
 	manager := &StreamSinkManagerCtx{
 		logger:     logger,
+		id:         video_id,
+		bitrate:    bitrate,
 		codec:      codec,
 		pipelineFn: pipelineFn,
+		bitrateFn:  bitrateFn,
 	}
 
 	return manager
@@ -54,6 +72,14 @@ func (manager *StreamSinkManagerCtx) OnSample(listener func(sample types.Sample)
 	manager.sampleFn = listener
 }
 
+func (manager *StreamSinkManagerCtx) ID() string {
+	return manager.id
+}
+
+func (manager *StreamSinkManagerCtx) Bitrate() uint {
+	return manager.bitrate
+}
+
 func (manager *StreamSinkManagerCtx) Codec() codec.RTPCodec {
 	return manager.codec
 }
@@ -154,6 +180,7 @@ func (manager *StreamSinkManagerCtx) createPipeline() error {
 
 	manager.pipeline.AttachAppsink("appsink")
 	manager.pipeline.Play()
+	manager.encoderBitrate = manager.bitrate
 
 	manager.wg.Add(1)
 	pipeline := manager.pipeline
@@ -176,6 +203,76 @@ func (manager *StreamSinkManagerCtx) createPipeline() error {
 	return nil
 }
 
+func (manager *StreamSinkManagerCtx) ForceKeyUnit() error {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if manager.pipeline == nil {
+		return types.ErrCapturePipelineNotRunning
+	}
+
+	if elapsed := time.Since(manager.lastKeyUnit); elapsed < keyUnitInterval {
+		if !manager.keyUnitPending {
+			manager.keyUnitPending = true
+			time.AfterFunc(keyUnitInterval-elapsed, manager.trailingKeyUnit)
+		}
+		return nil
+	}
+
+	if !manager.pipeline.ForceKeyUnit() {
+		return fmt.Errorf("unable to force key unit")
+	}
+
+	manager.lastKeyUnit = time.Now()
+	return nil
+}
+
+// trailingKeyUnit forces keyframe, that was requested while throttled, so
+// that viewer asking for it does not wait for keyframe-max-dist.
+func (manager *StreamSinkManagerCtx) trailingKeyUnit() {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	manager.keyUnitPending = false
+
+	if manager.pipeline == nil {
+		return
+	}
+
+	if !manager.pipeline.ForceKeyUnit() {
+		manager.logger.Warn().Msg("unable to force key unit")
+		return
+	}
+
+	manager.lastKeyUnit = time.Now()
+}
+
+func (manager *StreamSinkManagerCtx) SetEncoderBitrate(bitrate uint) error {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if manager.pipeline == nil {
+		return types.ErrCapturePipelineNotRunning
+	}
+
+	if manager.encoderBitrate == bitrate {
+		return nil
+	}
+
+	if manager.bitrateFn == nil {
+		return types.ErrCapturePipelineBitrateUnsupported
+	}
+
+	prop, value := manager.bitrateFn(bitrate)
+	if prop == "" || !manager.pipeline.SetPropInt("encoder", prop, value) {
+		return types.ErrCapturePipelineBitrateUnsupported
+	}
+
+	manager.logger.Debug().Uint("bitrate", bitrate).Msg("encoder bitrate changed")
+	manager.encoderBitrate = bitrate
+	return nil
+}
+
 func (manager *StreamSinkManagerCtx) destroyPipeline() {
 	manager.pipelineMu.Lock()
 	defer manager.pipelineMu.Unlock()
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Auth struct {
+	Provider string
+	File     string
+}
+
+func (Auth) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("auth_provider", "password", "how users are authenticated: password (shared passwords) or file (user accounts)")
+	if err := viper.BindPFlag("auth_provider", cmd.PersistentFlags().Lookup("auth_provider")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("auth_file", "", "path to a JSON file with user accounts, used by file auth provider")
+	if err := viper.BindPFlag("auth_file", cmd.PersistentFlags().Lookup("auth_file")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Auth) Set() {
+	s.Provider = viper.GetString("auth_provider")
+	s.File = viper.GetString("auth_file")
+
+	switch s.Provider {
+	case "password":
+	case "file":
+		if s.File == "" {
+			log.Panic().Msg("file auth provider is selected, but auth_file is missing")
+		}
+	default:
+		log.Panic().Str("auth_provider", s.Provider).Msg("unknown auth provider")
+	}
+}
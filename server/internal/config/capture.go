@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	"m1k1o/neko/internal/types/codec"
 
 	"github.com/pion/webrtc/v3"
@@ -17,6 +22,8 @@ type Capture struct {
 	VideoBitrate  uint   // TODO: Pipeline builder.
 	VideoMaxFPS   int16  // TODO: Pipeline builder.
 	VideoPipeline string
+	VideoLadder   []VideoLadderEntry
+	ShowPointer   bool
 
 	// audio
 	AudioDevice   string
@@ -29,6 +36,14 @@ type Capture struct {
 	BroadcastUrl      string
 }
 
+// VideoLadderEntry is one of video pipelines, that peers are switched
+// between based on their estimated bandwidth.
+type VideoLadderEntry struct {
+	ID      string
+	Height  int  // zero keeps screen resolution
+	Bitrate uint // kbit/s
+}
+
 func (Capture) Init(cmd *cobra.Command) error {
 	//
 	// video
@@ -72,11 +87,21 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().StringSlice("video_ladder", []string{}, "video pipelines for adaptive quality as <height>p:<bitrate>, e.g. 1080p:3000,720p:1500,360p:500")
+	if err := viper.BindPFlag("video_ladder", cmd.PersistentFlags().Lookup("video_ladder")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Int("max_fps", 25, "maximum fps delivered via WebRTC, 0 is for no maximum")
 	if err := viper.BindPFlag("max_fps", cmd.PersistentFlags().Lookup("max_fps")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("show_pointer", true, "capture mouse pointer in video, when disabled it is sent to clients separately and rendered locally")
+	if err := viper.BindPFlag("show_pointer", cmd.PersistentFlags().Lookup("show_pointer")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("video", "", "video codec parameters to use for streaming")
 	if err := viper.BindPFlag("video", cmd.PersistentFlags().Lookup("video")); err != nil {
 		return err
@@ -183,8 +208,33 @@ func (s *Capture) Set() {
 
 	s.VideoBitrate = viper.GetUint("video_bitrate")
 	s.VideoMaxFPS = int16(viper.GetInt("max_fps"))
+	s.ShowPointer = viper.GetBool("show_pointer")
 	s.VideoPipeline = viper.GetString("video")
 
+	ladder, err := parseVideoLadder(viper.GetStringSlice("video_ladder"))
+	if err != nil {
+		log.Panic().Err(err).Msg("invalid video ladder")
+	}
+	s.VideoLadder = ladder
+
+	if len(s.VideoLadder) > 0 && s.VideoPipeline != "" {
+		log.Warn().Msg("video ladder is ignored, when custom video pipeline is used")
+		s.VideoLadder = []VideoLadderEntry{}
+	}
+
+	// custom pipeline decides on its own, whether it captures pointer
+	if !s.ShowPointer && s.VideoPipeline != "" {
+		log.Warn().Msg("show_pointer is ignored, when custom video pipeline is used")
+		s.ShowPointer = true
+	}
+
+	if len(s.VideoLadder) == 0 {
+		s.VideoLadder = append(s.VideoLadder, VideoLadderEntry{
+			ID:      "main",
+			Bitrate: s.VideoBitrate,
+		})
+	}
+
 	//
 	// audio
 	//
@@ -222,3 +272,62 @@ func (s *Capture) Set() {
 	s.BroadcastPipeline = viper.GetString("broadcast_pipeline")
 	s.BroadcastUrl = viper.GetString("broadcast_url")
 }
+
+// parseVideoLadder returns entries sorted by bitrate, highest quality first.
+// Repeated entries are ignored.
+func parseVideoLadder(strs []string) ([]VideoLadderEntry, error) {
+	ladder := []VideoLadderEntry{}
+	for _, str := range strs {
+		entry, err := parseVideoLadderEntry(str)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", str, err)
+		}
+
+		duplicate := false
+		for _, e := range ladder {
+			if e.ID == entry.ID {
+				duplicate = true
+				break
+			}
+		}
+
+		if duplicate {
+			log.Warn().Str("entry", str).Msg("ignoring duplicate video ladder entry")
+			continue
+		}
+
+		ladder = append(ladder, entry)
+	}
+
+	sort.SliceStable(ladder, func(i, j int) bool {
+		return ladder[i].Bitrate > ladder[j].Bitrate
+	})
+
+	return ladder, nil
+}
+
+func parseVideoLadderEntry(str string) (VideoLadderEntry, error) {
+	var entry VideoLadderEntry
+
+	parts := strings.SplitN(str, ":", 2)
+	if len(parts) != 2 || !strings.HasSuffix(parts[0], "p") {
+		return entry, fmt.Errorf("expected <height>p:<bitrate>")
+	}
+
+	height, err := strconv.Atoi(strings.TrimSuffix(parts[0], "p"))
+	if err != nil || height <= 0 {
+		return entry, fmt.Errorf("invalid height %q", parts[0])
+	}
+
+	bitrate, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil || bitrate == 0 {
+		return entry, fmt.Errorf("invalid bitrate %q", parts[1])
+	}
+
+	// there can be more entries with the same height
+	return VideoLadderEntry{
+		ID:      fmt.Sprintf("%dp:%d", height, bitrate),
+		Height:  height,
+		Bitrate: uint(bitrate),
+	}, nil
+}
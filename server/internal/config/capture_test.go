@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVideoLadder(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []VideoLadderEntry
+		invalid bool
+	}{
+		{
+			name:    "empty",
+			entries: nil,
+			want:    []VideoLadderEntry{},
+		},
+		{
+			name:    "sorted by bitrate",
+			entries: []string{"360p:500", "1080p:3000", "720p:1500"},
+			want: []VideoLadderEntry{
+				{ID: "1080p:3000", Height: 1080, Bitrate: 3000},
+				{ID: "720p:1500", Height: 720, Bitrate: 1500},
+				{ID: "360p:500", Height: 360, Bitrate: 500},
+			},
+		},
+		{
+			name:    "same height",
+			entries: []string{"720p:1500", "720p:3000"},
+			want: []VideoLadderEntry{
+				{ID: "720p:3000", Height: 720, Bitrate: 3000},
+				{ID: "720p:1500", Height: 720, Bitrate: 1500},
+			},
+		},
+		{
+			name:    "same bitrate keeps order",
+			entries: []string{"720p:1500", "1080p:1500"},
+			want: []VideoLadderEntry{
+				{ID: "720p:1500", Height: 720, Bitrate: 1500},
+				{ID: "1080p:1500", Height: 1080, Bitrate: 1500},
+			},
+		},
+		{
+			name:    "duplicate",
+			entries: []string{"720p:1500", "0720p:01500"},
+			want: []VideoLadderEntry{
+				{ID: "720p:1500", Height: 720, Bitrate: 1500},
+			},
+		},
+		{name: "missing bitrate", entries: []string{"720p"}, invalid: true},
+		{name: "missing suffix", entries: []string{"720:1500"}, invalid: true},
+		{name: "zero height", entries: []string{"0p:1500"}, invalid: true},
+		{name: "negative height", entries: []string{"-720p:1500"}, invalid: true},
+		{name: "zero bitrate", entries: []string{"720p:0"}, invalid: true},
+		{name: "invalid bitrate", entries: []string{"720p:fast"}, invalid: true},
+		{name: "one invalid", entries: []string{"1080p:3000", "720p:"}, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ladder, err := parseVideoLadder(test.entries)
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("ladder = %+v, want error", ladder)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ladder, test.want) {
+				t.Fatalf("ladder = %+v, want %+v", ladder, test.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"net/url"
 	"path"
+	"strings"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -13,6 +16,8 @@ type Server struct {
 	Bind       string
 	Static     string
 	PathPrefix string
+	PublicURL  *url.URL
+	CSPReport  bool
 }
 
 func (Server) Init(cmd *cobra.Command) error {
@@ -41,6 +46,16 @@ func (Server) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("public_url", "", "public URL where clients reach neko, used in security headers, e.g. https://neko.example.com")
+	if err := viper.BindPFlag("public_url", cmd.PersistentFlags().Lookup("public_url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("csp_report", false, "collect content security policy violation reports and log them")
+	if err := viper.BindPFlag("csp_report", cmd.PersistentFlags().Lookup("csp_report")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -50,4 +65,30 @@ func (s *Server) Set() {
 	s.Bind = viper.GetString("bind")
 	s.Static = viper.GetString("static")
 	s.PathPrefix = path.Join("/", path.Clean(viper.GetString("path_prefix")))
+	s.CSPReport = viper.GetBool("csp_report")
+
+	s.PublicURL = nil
+	if publicURL := strings.TrimSpace(viper.GetString("public_url")); publicURL != "" {
+		u, err := url.Parse(publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Panic().Str("public_url", publicURL).Msg("public_url must be absolute http or https URL")
+		}
+
+		s.PublicURL = u
+	}
+}
+
+// Secure returns true, if neko is served over https, either directly or
+// by a reverse proxy according to the public URL.
+func (s *Server) Secure() bool {
+	if s.Cert != "" && s.Key != "" {
+		return true
+	}
+
+	return s.PublicURL != nil && s.PublicURL.Scheme == "https"
+}
+
+// PathPrefixed returns path as seen by clients, with path prefix.
+func (s *Server) PathPrefixed(p string) string {
+	return path.Join(s.PathPrefix, p)
 }
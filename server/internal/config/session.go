@@ -0,0 +1,53 @@
+package config
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Session struct {
+	ControlMaxHold     time.Duration
+	ControlIdleTimeout time.Duration
+	ReconnectGrace     time.Duration
+}
+
+func (Session) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Duration("control_max_hold", 0, "how long can host keep control before it is passed to the next in queue, 0 for unlimited")
+	if err := viper.BindPFlag("control_max_hold", cmd.PersistentFlags().Lookup("control_max_hold")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("control_idle_timeout", 0, "release control when host sends no input for this long, 0 to disable")
+	if err := viper.BindPFlag("control_idle_timeout", cmd.PersistentFlags().Lookup("control_idle_timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("reconnect_grace", 15*time.Second, "how long is session kept after its connection was lost, so that it can be resumed, 0 to disable")
+	if err := viper.BindPFlag("reconnect_grace", cmd.PersistentFlags().Lookup("reconnect_grace")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Session) Set() {
+	s.ControlMaxHold = viper.GetDuration("control_max_hold")
+	s.ControlIdleTimeout = viper.GetDuration("control_idle_timeout")
+	s.ReconnectGrace = viper.GetDuration("reconnect_grace")
+
+	if s.ControlMaxHold < 0 || s.ControlIdleTimeout < 0 {
+		log.Panic().
+			Dur("control_max_hold", s.ControlMaxHold).
+			Dur("control_idle_timeout", s.ControlIdleTimeout).
+			Msg("control timeouts cannot be negative")
+	}
+
+	if s.ReconnectGrace < 0 {
+		log.Panic().
+			Dur("reconnect_grace", s.ReconnectGrace).
+			Msg("reconnect grace period cannot be negative")
+	}
+}
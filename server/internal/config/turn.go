@@ -0,0 +1,151 @@
+package config
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type TURN struct {
+	Enabled bool
+	Host    string
+	Realm   string
+	Secret  string
+	TTL     time.Duration
+	RelayIP net.IP
+
+	UDP  int
+	TCP  int
+	TLS  int
+	Cert string
+	Key  string
+
+	RelayMin uint16
+	RelayMax uint16
+}
+
+func (TURN) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("turn_enabled", false, "enable embedded TURN server, clients get ephemeral credentials for it")
+	if err := viper.BindPFlag("turn_enabled", cmd.PersistentFlags().Lookup("turn_enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_host", "", "host of TURN server sent to clients, if empty the relay IP is used")
+	if err := viper.BindPFlag("turn_host", cmd.PersistentFlags().Lookup("turn_host")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_realm", "neko", "realm of TURN server")
+	if err := viper.BindPFlag("turn_realm", cmd.PersistentFlags().Lookup("turn_realm")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_secret", "", "shared secret for TURN credentials, if empty a random one is generated on every start")
+	if err := viper.BindPFlag("turn_secret", cmd.PersistentFlags().Lookup("turn_secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("turn_ttl", time.Hour, "how long are TURN credentials valid")
+	if err := viper.BindPFlag("turn_ttl", cmd.PersistentFlags().Lookup("turn_ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_relay_ip", "", "IPv4 address, that TURN relays are allocated on, if empty the first nat1to1 address or a local address is used")
+	if err := viper.BindPFlag("turn_relay_ip", cmd.PersistentFlags().Lookup("turn_relay_ip")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("turn_udp", 3478, "UDP port of TURN server, 0 to disable")
+	if err := viper.BindPFlag("turn_udp", cmd.PersistentFlags().Lookup("turn_udp")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("turn_tcp", 3478, "TCP port of TURN server, 0 to disable")
+	if err := viper.BindPFlag("turn_tcp", cmd.PersistentFlags().Lookup("turn_tcp")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("turn_tls", 5349, "TLS port of TURN server, used only with turn_cert and turn_key, 0 to disable")
+	if err := viper.BindPFlag("turn_tls", cmd.PersistentFlags().Lookup("turn_tls")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_cert", "", "path to the TLS cert of TURN server")
+	if err := viper.BindPFlag("turn_cert", cmd.PersistentFlags().Lookup("turn_cert")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_key", "", "path to the TLS key of TURN server")
+	if err := viper.BindPFlag("turn_key", cmd.PersistentFlags().Lookup("turn_key")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("turn_epr", "49160-49200", "limits the pool of ports, that TURN server can allocate relays from")
+	if err := viper.BindPFlag("turn_epr", cmd.PersistentFlags().Lookup("turn_epr")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *TURN) Set() {
+	s.Enabled = viper.GetBool("turn_enabled")
+	s.Host = viper.GetString("turn_host")
+	s.Realm = viper.GetString("turn_realm")
+	s.Secret = viper.GetString("turn_secret")
+	s.TTL = viper.GetDuration("turn_ttl")
+	s.UDP = viper.GetInt("turn_udp")
+	s.TCP = viper.GetInt("turn_tcp")
+	s.TLS = viper.GetInt("turn_tls")
+	s.Cert = viper.GetString("turn_cert")
+	s.Key = viper.GetString("turn_key")
+
+	// TLS listener needs both cert and key
+	if s.Cert == "" || s.Key == "" {
+		s.TLS = 0
+	}
+
+	if !s.Enabled {
+		return
+	}
+
+	if s.UDP == 0 && s.TCP == 0 && s.TLS == 0 {
+		log.Panic().Msg("TURN server needs at least one listener")
+	}
+
+	if s.TTL <= 0 {
+		log.Panic().Dur("turn_ttl", s.TTL).Msg("TURN credentials ttl must be positive")
+	}
+
+	// listeners and relays are IPv4 only
+	if relayIP := viper.GetString("turn_relay_ip"); relayIP != "" {
+		s.RelayIP = net.ParseIP(relayIP).To4()
+		if s.RelayIP == nil {
+			log.Panic().Str("turn_relay_ip", relayIP).Msg("invalid TURN relay IPv4 address")
+		}
+	}
+
+	epr := viper.GetString("turn_epr")
+	ports := strings.SplitN(epr, "-", 2)
+	if len(ports) != 2 {
+		log.Panic().Str("turn_epr", epr).Msg("invalid TURN relay port range")
+	}
+
+	min, err := strconv.ParseUint(ports[0], 10, 16)
+	if err != nil {
+		log.Panic().Err(err).Str("turn_epr", epr).Msg("invalid TURN relay port range")
+	}
+
+	max, err := strconv.ParseUint(ports[1], 10, 16)
+	if err != nil || min == 0 || min > max {
+		log.Panic().Err(err).Str("turn_epr", epr).Msg("invalid TURN relay port range")
+	}
+
+	s.RelayMin = uint16(min)
+	s.RelayMax = uint16(max)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
+	"time"
 
 	"m1k1o/neko/internal/utils"
 
@@ -23,7 +24,9 @@ type WebRTC struct {
 	TCPMUX       int
 	UDPMUX       int
 
-	ImplicitControl bool
+	ImplicitControl   bool
+	MultiControl      bool
+	ControlGrabWindow time.Duration
 }
 
 func (WebRTC) Init(cmd *cobra.Command) error {
@@ -73,6 +76,16 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("multi_control", false, "allow multiple members to control at once, implies implicit_control")
+	if err := viper.BindPFlag("multi_control", cmd.PersistentFlags().Lookup("multi_control")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("control_grab_window", 500*time.Millisecond, "in multi control mode, input of others is ignored for this long after a member took input or pressed a key")
+	if err := viper.BindPFlag("control_grab_window", cmd.PersistentFlags().Lookup("control_grab_window")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -131,4 +144,14 @@ func (s *WebRTC) Set() {
 
 	// TODO: Should be moved to session config.
 	s.ImplicitControl = viper.GetBool("implicit_control")
+	s.MultiControl = viper.GetBool("multi_control")
+	s.ControlGrabWindow = viper.GetDuration("control_grab_window")
+
+	if s.MultiControl {
+		s.ImplicitControl = true
+
+		if s.ControlGrabWindow <= 0 {
+			log.Panic().Dur("control_grab_window", s.ControlGrabWindow).Msg("control grab window must be positive")
+		}
+	}
 }
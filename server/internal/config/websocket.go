@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -10,8 +11,14 @@ type WebSocket struct {
 	AdminPassword string
 	Proxy         bool
 	Locks         []string
+	QueryAuth     bool
+	// pages, besides the server itself, that can use session cookie
+	AllowedOrigins []string
 
 	ControlProtection bool
+
+	StateStore string
+	StateFile  string
 }
 
 func (WebSocket) Init(cmd *cobra.Command) error {
@@ -40,6 +47,26 @@ func (WebSocket) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("query_auth", true, "DEPRECATED: allow passwords in query string (?pwd=, ?password=), use Authorization header or /login instead")
+	if err := viper.BindPFlag("query_auth", cmd.PersistentFlags().Lookup("query_auth")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("allowed_origins", []string{}, "origins of pages, besides the server itself, that can connect using session cookie, e.g. https://example.com")
+	if err := viper.BindPFlag("allowed_origins", cmd.PersistentFlags().Lookup("allowed_origins")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("state_store", "memory", "where bans, locks, mutes and invites are stored: memory, file (JSON file) or journal (append-only log)")
+	if err := viper.BindPFlag("state_store", cmd.PersistentFlags().Lookup("state_store")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("state_file", "", "path to a file used by file or journal state store")
+	if err := viper.BindPFlag("state_file", cmd.PersistentFlags().Lookup("state_file")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -48,6 +75,21 @@ func (s *WebSocket) Set() {
 	s.AdminPassword = viper.GetString("password_admin")
 	s.Proxy = viper.GetBool("proxy")
 	s.Locks = viper.GetStringSlice("locks")
+	s.QueryAuth = viper.GetBool("query_auth")
+	s.AllowedOrigins = viper.GetStringSlice("allowed_origins")
 
 	s.ControlProtection = viper.GetBool("control_protection")
+
+	s.StateStore = viper.GetString("state_store")
+	s.StateFile = viper.GetString("state_file")
+
+	switch s.StateStore {
+	case "memory":
+	case "file", "journal":
+		if s.StateFile == "" {
+			log.Panic().Str("state_store", s.StateStore).Msg("persistent state store is selected, but state_file is missing")
+		}
+	default:
+		log.Panic().Str("state_store", s.StateStore).Msg("unknown state store")
+	}
 }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Zoom struct {
+	Enabled      bool
+	Host         string
+	APIHost      string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	TokenFile    string
+	AdminRoles   []string
+
+	WebhookSecret string
+}
+
+func (Zoom) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("zm_enabled", false, "enable zoom app mode")
+	if err := viper.BindPFlag("zm_enabled", cmd.PersistentFlags().Lookup("zm_enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_host", "https://zoom.us", "zoom host used for oauth requests")
+	if err := viper.BindPFlag("zm_host", cmd.PersistentFlags().Lookup("zm_host")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_api_host", "https://api.zoom.us", "zoom host used for api requests")
+	if err := viper.BindPFlag("zm_api_host", cmd.PersistentFlags().Lookup("zm_api_host")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_client_id", "", "client id of the zoom app")
+	if err := viper.BindPFlag("zm_client_id", cmd.PersistentFlags().Lookup("zm_client_id")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_client_secret", "", "client secret of the zoom app")
+	if err := viper.BindPFlag("zm_client_secret", cmd.PersistentFlags().Lookup("zm_client_secret")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_redirect_url", "", "oauth redirect url registered for the zoom app, e.g. https://example.com/auth")
+	if err := viper.BindPFlag("zm_redirect_url", cmd.PersistentFlags().Lookup("zm_redirect_url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_token_file", "", "path to a file where oauth tokens are stored, if empty tokens are kept in memory")
+	if err := viper.BindPFlag("zm_token_file", cmd.PersistentFlags().Lookup("zm_token_file")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("zm_admin_roles", []string{"host", "cohost"}, "meeting roles of zoom users, that will join as admins")
+	if err := viper.BindPFlag("zm_admin_roles", cmd.PersistentFlags().Lookup("zm_admin_roles")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("zm_webhook_secret", "", "secret token used to verify zoom webhook requests, webhook is disabled if empty")
+	if err := viper.BindPFlag("zm_webhook_secret", cmd.PersistentFlags().Lookup("zm_webhook_secret")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Zoom) Set() {
+	s.Enabled = viper.GetBool("zm_enabled")
+	s.Host = strings.TrimRight(viper.GetString("zm_host"), "/")
+	s.APIHost = strings.TrimRight(viper.GetString("zm_api_host"), "/")
+	s.ClientID = viper.GetString("zm_client_id")
+	s.ClientSecret = viper.GetString("zm_client_secret")
+	s.RedirectURL = viper.GetString("zm_redirect_url")
+	s.TokenFile = viper.GetString("zm_token_file")
+	s.AdminRoles = viper.GetStringSlice("zm_admin_roles")
+	s.WebhookSecret = viper.GetString("zm_webhook_secret")
+
+	if !s.Enabled {
+		return
+	}
+
+	if s.ClientID == "" || s.ClientSecret == "" {
+		log.Panic().Msg("zoom mode is enabled, but zm_client_id or zm_client_secret is missing")
+	}
+
+	if s.RedirectURL == "" {
+		log.Panic().Msg("zoom mode is enabled, but zm_redirect_url is missing")
+	}
+}
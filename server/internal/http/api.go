@@ -0,0 +1,223 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/types"
+)
+
+// API_SESSION is used as session ID of admin actions done over REST API
+const API_SESSION = "by_api"
+
+func apiRoutes(router chi.Router, logger zerolog.Logger, webSocketHandler types.WebSocketHandler, desktop types.DesktopManager) {
+	admin := webSocketHandler.Admin()
+
+	router.With(adminAuth(webSocketHandler)).Get("/api/stats/peers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, logger, admin.PeerStats())
+	})
+
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(adminAuth(webSocketHandler))
+
+		r.Get("/members", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, logger, admin.Members())
+		})
+
+		memberAction := func(action func(id string, target string) error) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				apiResult(w, logger, action(API_SESSION, chi.URLParam(r, "id")))
+			}
+		}
+
+		r.Post("/members/{id}/kick", memberAction(admin.Kick))
+		r.Post("/members/{id}/ban", func(w http.ResponseWriter, r *http.Request) {
+			options := types.BanOptions{}
+
+			// options are optional, empty body is accepted
+			if err := json.NewDecoder(r.Body).Decode(&options); err != nil && !errors.Is(err, io.EOF) {
+				http.Error(w, "invalid ban payload", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.Ban(API_SESSION, chi.URLParam(r, "id"), options))
+		})
+		r.Post("/members/{id}/mute", memberAction(admin.Mute))
+		r.Post("/members/{id}/unmute", memberAction(admin.Unmute))
+		r.Post("/members/{id}/control", memberAction(admin.Give))
+
+		r.Put("/members/{id}/permissions", func(w http.ResponseWriter, r *http.Request) {
+			permissions := types.Permissions{}
+			if err := json.NewDecoder(r.Body).Decode(&permissions); err != nil {
+				http.Error(w, "invalid permissions", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.SetPermissions(API_SESSION, chi.URLParam(r, "id"), permissions))
+		})
+
+		r.Get("/bans", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, logger, admin.Bans())
+		})
+
+		r.Post("/bans", func(w http.ResponseWriter, r *http.Request) {
+			payload := struct {
+				Target string `json:"target"`
+				types.BanOptions
+			}{}
+
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid ban payload", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.BanTarget(API_SESSION, payload.Target, payload.BanOptions))
+		})
+
+		// ranges contain slash, so target is passed in query
+		r.Delete("/bans", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.Unban(API_SESSION, r.URL.Query().Get("target")))
+		})
+
+		r.Get("/invites", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, logger, admin.Invites())
+		})
+
+		r.Post("/invites", func(w http.ResponseWriter, r *http.Request) {
+			options := types.InviteOptions{}
+			if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+				http.Error(w, "invalid invite options", http.StatusBadRequest)
+				return
+			}
+
+			token, invite, err := admin.CreateInvite(API_SESSION, options)
+			if err != nil {
+				apiResult(w, logger, err)
+				return
+			}
+
+			w.Header().Set("Cache-Control", "no-store")
+			writeJSON(w, logger, struct {
+				Token  string        `json:"token"`
+				Invite *types.Invite `json:"invite"`
+			}{
+				Token:  token,
+				Invite: invite,
+			})
+		})
+
+		r.Delete("/invites/{id}", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.RevokeInvite(API_SESSION, chi.URLParam(r, "id")))
+		})
+
+		r.Delete("/control", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.Release(API_SESSION))
+		})
+
+		r.Put("/locks/{resource}", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.Lock(API_SESSION, chi.URLParam(r, "resource")))
+		})
+
+		r.Delete("/locks/{resource}", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.Unlock(API_SESSION, chi.URLParam(r, "resource")))
+		})
+
+		r.Get("/screen", func(w http.ResponseWriter, r *http.Request) {
+			size := desktop.GetScreenSize()
+			if size == nil {
+				http.Error(w, "unable to get screen size", http.StatusInternalServerError)
+				return
+			}
+
+			writeJSON(w, logger, size)
+		})
+
+		r.Put("/screen", func(w http.ResponseWriter, r *http.Request) {
+			size := types.ScreenSize{}
+			if err := json.NewDecoder(r.Body).Decode(&size); err != nil {
+				http.Error(w, "invalid screen size", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.SetScreenSize(API_SESSION, size))
+		})
+
+		r.Post("/broadcast", func(w http.ResponseWriter, r *http.Request) {
+			payload := struct {
+				URL string `json:"url"`
+			}{}
+
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid broadcast payload", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.BroadcastStart(payload.URL))
+		})
+
+		r.Delete("/broadcast", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.BroadcastStop())
+		})
+
+		r.Put("/meeting", func(w http.ResponseWriter, r *http.Request) {
+			payload := struct {
+				Meeting string `json:"meeting"`
+			}{}
+
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Meeting == "" {
+				http.Error(w, "invalid meeting payload", http.StatusBadRequest)
+				return
+			}
+
+			apiResult(w, logger, admin.BindMeeting(API_SESSION, payload.Meeting))
+		})
+
+		r.Delete("/meeting", func(w http.ResponseWriter, r *http.Request) {
+			apiResult(w, logger, admin.UnbindMeeting(API_SESSION))
+		})
+	})
+}
+
+func apiResult(w http.ResponseWriter, logger zerolog.Logger, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, types.ErrMemberNotFound),
+		errors.Is(err, types.ErrInviteNotFound),
+		errors.Is(err, types.ErrBanNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, types.ErrMemberIsAdmin),
+		errors.Is(err, types.ErrMemberNoPermission):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrResourceLocked),
+		errors.Is(err, types.ErrResourceNotLocked),
+		errors.Is(err, types.ErrBroadcastStarted),
+		errors.Is(err, types.ErrBroadcastNotStarted),
+		errors.Is(err, types.ErrMeetingBound),
+		errors.Is(err, types.ErrMeetingNotBound):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, types.ErrMemberNoAddress),
+		errors.Is(err, types.ErrUnknownResource),
+		errors.Is(err, types.ErrBroadcastMissingURL),
+		errors.Is(err, types.ErrInviteInvalid),
+		errors.Is(err, types.ErrMemberNoIdentity),
+		errors.Is(err, types.ErrBanInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Warn().Err(err).Msg("admin action failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, logger zerolog.Logger, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn().Err(err).Msg("failed writing json response")
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+// adminAuth allows only requests authenticated as admin.
+func adminAuth(webSocketHandler types.WebSocketHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := webSocketHandler.Authenticate(r)
+			if errors.Is(err, types.ErrTooManyAttempts) {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			if !user.Admin() {
+				http.Error(w, "bad authorization", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func authRoutes(router chi.Router, logger zerolog.Logger, conf *config.Server, webSocketHandler types.WebSocketHandler) {
+	router.Post("/login", func(w http.ResponseWriter, r *http.Request) {
+		username, password := "", ""
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			payload := struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			}{}
+
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid login payload", http.StatusBadRequest)
+				return
+			}
+
+			username, password = payload.Username, payload.Password
+		} else {
+			username, password = r.PostFormValue("username"), r.PostFormValue("password")
+		}
+
+		token, user, err := webSocketHandler.Login(r, username, password)
+		if errors.Is(err, types.ErrTooManyAttempts) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     types.SESSION_COOKIE,
+			Value:    token,
+			Path:     conf.PathPrefix,
+			Secure:   conf.Secure(),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, logger, struct {
+			Token string `json:"token"`
+			*types.User
+		}{
+			Token: token,
+			User:  user,
+		})
+	})
+
+	router.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(types.SESSION_COOKIE); err == nil {
+			webSocketHandler.Logout(cookie.Value)
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     types.SESSION_COOKIE,
+			Value:    "",
+			Path:     conf.PathPrefix,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			Secure:   conf.Secure(),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -27,32 +27,13 @@ type Server struct {
 
 const contextHeader = "x-zoom-app-context"
 
-func New(conf *config.Server, webSocketHandler types.WebSocketHandler, desktop types.DesktopManager) *Server {
+func New(conf *config.Server, zoomManager *zoom.ZoomManager, webSocketHandler types.WebSocketHandler, desktop types.DesktopManager) *Server {
 	logger := log.With().Str("module", "http").Logger()
 
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID) // Create a request ID for each request
 	router.Use(middleware.RequestLogger(&logformatter{logger}))
 	router.Use(middleware.Recoverer) // Recover from panics without crashing server
-	
-	// Set middleware response header for Zoom app
-	router.Use(middleware.SetHeader("Strict-Transport-Security", "max-age=31536000"))
-	router.Use(middleware.SetHeader("X-Content-Type-Options", "nosniff"))
-	router.Use(middleware.SetHeader("Content-Security-Policy", `default-src 'self';
-	style-src 'report-sample' 'self' 'unsafe-inline';
-	script-src * 'self' https://appssdk.zoom.us 'unsafe-inline';
-	object-src 'none';
-	base-uri 'self';
-	connect-src * 'self' ws://3c97-137-220-76-2.eu.ngrok.io;
-	font-src * 'self';
-	frame-src 'self';
-	img-src 'self';
-	manifest-src 'self';
-	media-src 'self';
-	worker-src 'none';`))
-
-	router.Use(middleware.SetHeader("Referrer-Policy", "same-origin"))
-	router.Use(middleware.SetHeader("X-Frame-Option", "same-origin"))
 
 	if conf.PathPrefix != "/" {
 		router.Use(func(h http.Handler) http.Handler {
@@ -60,26 +41,22 @@ func New(conf *config.Server, webSocketHandler types.WebSocketHandler, desktop t
 		})
 	}
 
-	router.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
+	apiHeaders, staticHeaders := newSecurityHeaders(conf, zoomManager.Enabled())
+
+	api := router.With(apiHeaders.Handler)
+
+	api.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
 		err := webSocketHandler.Upgrade(w, r)
 		if err != nil {
 			logger.Warn().Err(err).Msg("failed to upgrade websocket conection")
 		}
 	})
 
-	router.Get("/stats", func(w http.ResponseWriter, r *http.Request) {
-		password := r.URL.Query().Get("pwd")
-		isAdmin, err := webSocketHandler.IsAdmin(password)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
-			return
-		}
+	authRoutes(api, logger, conf, webSocketHandler)
 
-		if !isAdmin {
-			http.Error(w, "bad authorization", http.StatusUnauthorized)
-			return
-		}
+	admin := api.With(adminAuth(webSocketHandler))
 
+	admin.Get("/stats", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		stats := webSocketHandler.Stats()
@@ -88,19 +65,7 @@ func New(conf *config.Server, webSocketHandler types.WebSocketHandler, desktop t
 		}
 	})
 
-	router.Get("/screenshot.jpg", func(w http.ResponseWriter, r *http.Request) {
-		password := r.URL.Query().Get("pwd")
-		isAdmin, err := webSocketHandler.IsAdmin(password)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
-			return
-		}
-
-		if !isAdmin {
-			http.Error(w, "bad authorization", http.StatusUnauthorized)
-			return
-		}
-
+	admin.Get("/screenshot.jpg", func(w http.ResponseWriter, r *http.Request) {
 		if webSocketHandler.IsLocked("login") {
 			http.Error(w, "room is locked", http.StatusLocked)
 			return
@@ -121,35 +86,22 @@ func New(conf *config.Server, webSocketHandler types.WebSocketHandler, desktop t
 		}
 	})
 
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+	api.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("true"))
 	})
 
-	router.Get("/auth", func (w http.ResponseWriter, r *http.Request)  {
-		code := r.URL.Query().Get("code")
-		logger.Info().Msgf("Code : %s", code)
-		
-		zoomClient := zoom.NewClient()
-
-		if zoomClient != nil {
-			// Get access token from zoom
-			accessToken, err := zoomClient.GetToken(code)
-
-			if err != nil {
-				logger.Err(err).Msg("Retrieving token failed")
-			}
+	apiRoutes(api, logger, webSocketHandler, desktop)
 
-			deeplink, err := zoomClient.GetDeepLink(accessToken)
-			if err != nil {
-				logger.Err(err).Msg("Retrieving deep link failed")
-			}
+	if conf.CSPReport {
+		cspReportRoute(api, logger)
+	}
 
-			http.Redirect(w, r, deeplink, http.StatusSeeOther)
-		}
-	})
+	if zoomManager.Enabled() {
+		zoomRoutes(api, logger, zoomManager)
+	}
 
 	fs := http.FileServer(http.Dir(conf.Static))
-	router.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+	router.With(staticHeaders.Handler).Get("/*", func(w http.ResponseWriter, r *http.Request) {
 		if _, err := os.Stat(conf.Static + r.URL.Path); !os.IsNotExist(err) {
 			fs.ServeHTTP(w, r)
 		} else {
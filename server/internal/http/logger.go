@@ -30,7 +30,7 @@ func (l *logformatter) NewLogEntry(r *http.Request) middleware.LogEntry {
 	req["method"] = r.Method
 	req["remote"] = r.RemoteAddr
 	req["agent"] = r.UserAgent()
-	req["uri"] = fmt.Sprintf("%s://%s%s", scheme, r.Host, r.RequestURI)
+	req["uri"] = fmt.Sprintf("%s://%s%s", scheme, r.Host, redactedURI(r))
 
 	fields := map[string]interface{}{}
 	fields["req"] = req
@@ -41,6 +41,27 @@ func (l *logformatter) NewLogEntry(r *http.Request) middleware.LogEntry {
 	}
 }
 
+// query parameters, that can contain credentials
+var redactedParams = []string{"pwd", "password", "ticket", "token", "resume"}
+
+func redactedURI(r *http.Request) string {
+	query := r.URL.Query()
+
+	redacted := false
+	for _, key := range redactedParams {
+		if _, ok := query[key]; ok {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return r.RequestURI
+	}
+
+	return r.URL.EscapedPath() + "?" + query.Encode()
+}
+
 type logentry struct {
 	logger zerolog.Logger
 	fields map[string]interface{}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/config"
+)
+
+const cspReportPath = "/csp-report"
+
+const cspReportMaxBodySize = 64 << 10
+
+// CSP builds Content-Security-Policy header value, directives are
+// rendered in order they were first added.
+type CSP struct {
+	directives []string
+	sources    map[string][]string
+}
+
+func NewCSP() *CSP {
+	return &CSP{
+		sources: map[string][]string{},
+	}
+}
+
+// Add appends sources to a directive, duplicates are ignored.
+func (c *CSP) Add(directive string, sources ...string) *CSP {
+	current, ok := c.sources[directive]
+	if !ok {
+		c.directives = append(c.directives, directive)
+	}
+
+	for _, source := range sources {
+		exists := false
+		for _, s := range current {
+			if s == source {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			current = append(current, source)
+		}
+	}
+
+	c.sources[directive] = current
+	return c
+}
+
+func (c *CSP) String() string {
+	parts := make([]string, 0, len(c.directives))
+	for _, directive := range c.directives {
+		sources := c.sources[directive]
+		if len(sources) == 0 {
+			parts = append(parts, directive)
+		} else {
+			parts = append(parts, directive+" "+strings.Join(sources, " "))
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// securityHeaders are set on every response of a route group.
+type securityHeaders map[string]string
+
+func (h securityHeaders) with(key, value string) securityHeaders {
+	headers := securityHeaders{}
+	for k, v := range h {
+		headers[k] = v
+	}
+
+	if value == "" {
+		delete(headers, key)
+	} else {
+		headers[key] = value
+	}
+
+	return headers
+}
+
+func (h securityHeaders) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range h {
+			w.Header().Set(key, value)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newSecurityHeaders returns headers for API routes and for static client.
+// Full set of OWASP headers, that is required by Zoom, is only sent in
+// Zoom mode, because CSP and frame options could break custom clients
+// or embedding neko in other pages.
+func newSecurityHeaders(conf *config.Server, zoomEnabled bool) (api securityHeaders, static securityHeaders) {
+	base := securityHeaders{
+		"X-Content-Type-Options": "nosniff",
+		"Referrer-Policy":        "same-origin",
+	}
+
+	// browsers ignore HSTS received over plain HTTP
+	if conf.Secure() {
+		base["Strict-Transport-Security"] = "max-age=31536000"
+	}
+
+	// API responses are never rendered as documents
+	apiCSP := NewCSP().
+		Add("default-src", "'none'").
+		Add("frame-ancestors", "'none'")
+
+	if conf.CSPReport {
+		apiCSP.Add("report-uri", conf.PathPrefixed(cspReportPath))
+	}
+
+	api = base.with("Content-Security-Policy", apiCSP.String())
+
+	if !zoomEnabled {
+		return api, base
+	}
+
+	staticCSP := NewCSP().
+		Add("default-src", "'self'").
+		Add("style-src", "'report-sample'", "'self'", "'unsafe-inline'").
+		Add("script-src", "'self'", "'unsafe-inline'", "https://appssdk.zoom.us").
+		Add("object-src", "'none'").
+		Add("base-uri", "'self'").
+		Add("connect-src", "'self'").
+		Add("font-src", "'self'", "data:").
+		Add("frame-src", "'self'").
+		Add("img-src", "'self'", "data:", "blob:").
+		Add("manifest-src", "'self'").
+		Add("media-src", "'self'", "blob:").
+		Add("worker-src", "'none'")
+
+	// older browsers do not match websocket to 'self'
+	if conf.PublicURL != nil {
+		scheme := "ws"
+		if conf.PublicURL.Scheme == "https" {
+			scheme = "wss"
+		}
+
+		staticCSP.Add("connect-src", conf.PublicURL.Scheme+"://"+conf.PublicURL.Host, scheme+"://"+conf.PublicURL.Host)
+	}
+
+	if conf.CSPReport {
+		staticCSP.Add("report-uri", conf.PathPrefixed(cspReportPath))
+	}
+
+	static = base.
+		with("Content-Security-Policy", staticCSP.String()).
+		with("X-Frame-Options", "SAMEORIGIN")
+	return api, static
+}
+
+func cspReportRoute(router chi.Router, logger zerolog.Logger) {
+	router.Post(cspReportPath, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cspReportMaxBodySize))
+		if err != nil {
+			http.Error(w, "unable to read body", http.StatusBadRequest)
+			return
+		}
+
+		if !json.Valid(body) {
+			http.Error(w, "invalid report", http.StatusBadRequest)
+			return
+		}
+
+		logger.Warn().
+			Str("user_agent", r.UserAgent()).
+			RawJSON("report", body).
+			Msg("content security policy violation")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
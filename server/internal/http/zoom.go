@@ -0,0 +1,136 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/zoom"
+)
+
+const webhookMaxBodySize = 1 << 20
+
+func zoomRoutes(router chi.Router, logger zerolog.Logger, zoomManager *zoom.ZoomManager) {
+	router.Get("/install", func(w http.ResponseWriter, r *http.Request) {
+		authorizeURL, err := zoomManager.AuthorizeURL()
+		if err != nil {
+			logger.Err(err).Msg("starting authorization failed")
+			http.Error(w, "starting authorization failed", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, authorizeURL, http.StatusFound)
+	})
+
+	router.Get("/auth", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// user denied authorization
+		if reason := query.Get("error"); reason != "" {
+			logger.Warn().Str("reason", reason).Msg("authorization was not granted")
+			http.Error(w, "authorization was not granted", http.StatusForbidden)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			http.Error(w, "missing authorization code", http.StatusBadRequest)
+			return
+		}
+
+		userID, token, err := zoomManager.Exchange(r.Context(), query.Get("state"), code)
+		if errors.Is(err, zoom.ErrInvalidState) {
+			logger.Warn().Err(err).Msg("authorization callback rejected")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			logger.Err(err).Msg("retrieving token failed")
+			http.Error(w, "retrieving token failed", http.StatusBadGateway)
+			return
+		}
+
+		deeplink, err := zoomManager.Client().GetDeepLink(r.Context(), token.AccessToken)
+		if err != nil {
+			logger.Err(err).Str("user_id", userID).Msg("retrieving deep link failed")
+			http.Error(w, "retrieving deep link failed", http.StatusBadGateway)
+			return
+		}
+
+		http.Redirect(w, r, deeplink, http.StatusSeeOther)
+	})
+
+	router.Post("/zoom/ticket", func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(contextHeader)
+		if header == "" {
+			http.Error(w, "missing app context", http.StatusBadRequest)
+			return
+		}
+
+		ticket, payload, err := zoomManager.NewTicket(header)
+		if err != nil {
+			logger.Warn().Err(err).Msg("unable to issue session ticket")
+			http.Error(w, "invalid app context", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(struct {
+			Ticket    string    `json:"ticket"`
+			Admin     bool      `json:"admin"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}{
+			Ticket:    ticket,
+			Admin:     payload.Admin,
+			ExpiresAt: payload.ExpiresAt,
+		}); err != nil {
+			logger.Warn().Err(err).Msg("failed writing json response")
+		}
+	})
+
+	if !zoomManager.WebhookEnabled() {
+		logger.Info().Msg("zoom webhook secret not set, webhook is disabled")
+		return
+	}
+
+	router.Post("/zoom/webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxBodySize))
+		if err != nil {
+			http.Error(w, "unable to read body", http.StatusBadRequest)
+			return
+		}
+
+		signature := r.Header.Get("x-zm-signature")
+		timestamp := r.Header.Get("x-zm-request-timestamp")
+		if err := zoomManager.VerifyWebhook(signature, timestamp, body); err != nil {
+			logger.Warn().Err(err).Msg("webhook rejected")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		res, err := zoomManager.HandleWebhook(body)
+		if err != nil {
+			logger.Warn().Err(err).Msg("webhook handling failed")
+			http.Error(w, "invalid webhook payload", http.StatusBadRequest)
+			return
+		}
+
+		if res == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			logger.Warn().Err(err).Msg("failed writing json response")
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package session
+
+import (
+	"time"
+
+	"m1k1o/neko/internal/types"
+)
+
+const (
+	ControlExpiredMaxHold = "max_hold"
+	ControlExpiredIdle    = "idle"
+)
+
+// QueueControl adds session to the end of control queue, if it is not
+// queued already. It returns 1-based position in the queue.
+func (manager *SessionManager) QueueControl(id string) int {
+	manager.mu.Lock()
+
+	for i, queued := range manager.queue {
+		if queued == id {
+			manager.mu.Unlock()
+			return i + 1
+		}
+	}
+
+	manager.queue = append(manager.queue, id)
+	position := len(manager.queue)
+	host, expired := manager.host, manager.holdExpired && manager.host != ""
+	manager.mu.Unlock()
+
+	manager.emitControlQueue()
+
+	if expired {
+		manager.logger.Info().Str("id", host).Str("reason", ControlExpiredMaxHold).Msg("control expired")
+		manager.emmiter.Emit("control_expired", host, ControlExpiredMaxHold)
+	}
+
+	return position
+}
+
+// DequeueControl removes session from control queue.
+func (manager *SessionManager) DequeueControl(id string) bool {
+	manager.mu.Lock()
+	ok := manager.dequeue(id)
+	manager.mu.Unlock()
+
+	if ok {
+		manager.emitControlQueue()
+	}
+
+	return ok
+}
+
+// NextHost passes control to the first session in queue, that can control.
+// Sessions, that cannot control anymore, are removed from the queue. It does
+// nothing when there is a host already.
+func (manager *SessionManager) NextHost() (types.Session, bool) {
+	manager.mu.Lock()
+	if manager.host != "" || len(manager.queue) == 0 {
+		manager.mu.Unlock()
+		return nil, false
+	}
+
+	var next *Session
+	for len(manager.queue) > 0 {
+		id := manager.queue[0]
+		manager.queue = manager.queue[1:]
+
+		session, ok := manager.members[id]
+		if ok && session.Connected() && manager.canControl(session) {
+			next = session
+			break
+		}
+	}
+
+	if next != nil {
+		manager.setHost(next.id)
+	}
+	manager.mu.Unlock()
+
+	if next == nil {
+		manager.emitControlQueue()
+		return nil, false
+	}
+
+	manager.emmiter.Emit("host", next.id)
+	manager.emitControlQueue()
+	return next, true
+}
+
+func (manager *SessionManager) ControlQueue() types.ControlQueue {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	queue := types.ControlQueue{
+		Host:        manager.host,
+		Queue:       append([]string{}, manager.queue...),
+		IdleTimeout: manager.config.ControlIdleTimeout,
+	}
+
+	if manager.host != "" && manager.config.ControlMaxHold > 0 {
+		queue.Remaining = manager.config.ControlMaxHold - time.Since(manager.hostSince)
+		if queue.Remaining < 0 {
+			queue.Remaining = 0
+		}
+	}
+
+	return queue
+}
+
+// ControlActivity records input of the host, it postpones release on inactivity.
+func (manager *SessionManager) ControlActivity(id string) {
+	if manager.config.ControlIdleTimeout == 0 {
+		return
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.host == id {
+		manager.lastInput = time.Now()
+	}
+}
+
+func (manager *SessionManager) OnControlQueue(listener func(queue types.ControlQueue)) {
+	manager.emmiter.On("control_queue", func(payload ...interface{}) {
+		listener(payload[0].(types.ControlQueue))
+	})
+}
+
+// OnControlExpired is called when host held control for too long while
+// someone waits in queue or when host was inactive, host is not released
+// by the manager.
+func (manager *SessionManager) OnControlExpired(listener func(id string, reason string)) {
+	manager.emmiter.On("control_expired", func(payload ...interface{}) {
+		listener(payload[0].(string), payload[1].(string))
+	})
+}
+
+func (manager *SessionManager) emitControlQueue() {
+	manager.emmiter.Emit("control_queue", manager.ControlQueue())
+}
+
+// setHost changes host and starts its timers, must be called with lock held.
+func (manager *SessionManager) setHost(id string) {
+	manager.host = id
+	manager.hostGen++
+	manager.holdExpired = false
+
+	if manager.holdTimer != nil {
+		manager.holdTimer.Stop()
+		manager.holdTimer = nil
+	}
+
+	if manager.idleTimer != nil {
+		manager.idleTimer.Stop()
+		manager.idleTimer = nil
+	}
+
+	if id == "" {
+		return
+	}
+
+	manager.dequeue(id)
+	manager.hostSince = time.Now()
+	manager.lastInput = manager.hostSince
+
+	gen := manager.hostGen
+	if manager.config.ControlMaxHold > 0 {
+		manager.holdTimer = time.AfterFunc(manager.config.ControlMaxHold, func() {
+			manager.expire(gen, ControlExpiredMaxHold)
+		})
+	}
+
+	if manager.config.ControlIdleTimeout > 0 {
+		manager.idleTimer = time.AfterFunc(manager.config.ControlIdleTimeout, func() {
+			manager.checkIdle(gen)
+		})
+	}
+}
+
+// checkIdle is called when host could be inactive for long enough, timer is
+// started again if there was input in the meantime.
+func (manager *SessionManager) checkIdle(gen uint64) {
+	manager.mu.Lock()
+	if manager.hostGen != gen {
+		manager.mu.Unlock()
+		return
+	}
+
+	idle := time.Since(manager.lastInput)
+	if idle < manager.config.ControlIdleTimeout {
+		manager.idleTimer = time.AfterFunc(manager.config.ControlIdleTimeout-idle, func() {
+			manager.checkIdle(gen)
+		})
+		manager.mu.Unlock()
+		return
+	}
+	manager.mu.Unlock()
+
+	manager.expire(gen, ControlExpiredIdle)
+}
+
+func (manager *SessionManager) expire(gen uint64, reason string) {
+	manager.mu.Lock()
+	id := manager.host
+	current := manager.hostGen == gen && id != ""
+
+	// nobody waits for control, host keeps it until someone queues
+	if current && reason == ControlExpiredMaxHold && len(manager.queue) == 0 {
+		manager.holdExpired = true
+		current = false
+	}
+	manager.mu.Unlock()
+
+	if current {
+		manager.logger.Info().Str("id", id).Str("reason", reason).Msg("control expired")
+		manager.emmiter.Emit("control_expired", id, reason)
+	}
+}
+
+// dequeue must be called with lock held.
+func (manager *SessionManager) dequeue(id string) bool {
+	for i, queued := range manager.queue {
+		if queued == id {
+			manager.queue = append(manager.queue[:i], manager.queue[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,52 +1,89 @@
 package session
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/kataras/go-events"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"m1k1o/neko/internal/config"
 	"m1k1o/neko/internal/types"
 	"m1k1o/neko/internal/utils"
 )
 
-func New(capture types.CaptureManager) *SessionManager {
+func New(capture types.CaptureManager, config *config.Session) *SessionManager {
 	return &SessionManager{
 		logger:  log.With().Str("module", "session").Logger(),
 		host:    "",
 		capture: capture,
+		config:  config,
 		members: make(map[string]*Session),
+		queue:   []string{},
 		emmiter: events.New(),
+
+		reconnects: make(map[string]*reconnect),
 	}
 }
 
+// SessionManager guards members, host and control lock by mutex. Events
+// are emitted and messages sent after the mutex is released.
 type SessionManager struct {
 	mu      sync.Mutex
 	logger  zerolog.Logger
 	host    string
 	capture types.CaptureManager
+	config  *config.Session
 	members map[string]*Session
 	emmiter events.EventEmmiter
 	// TODO: Handle locks in sessions as flags.
 	controlLocked bool
+
+	// control queue, see control.go
+	queue     []string
+	hostSince time.Time
+	lastInput time.Time
+	hostGen   uint64
+	// host held control for too long, it is released once someone queues
+	holdExpired bool
+	holdTimer   *time.Timer
+	idleTimer   *time.Timer
+
+	// sessions waiting for reconnect, see reconnect.go
+	reconnects map[string]*reconnect
 }
 
-func (manager *SessionManager) New(id string, admin bool, socket types.WebSocket) types.Session {
+func (manager *SessionManager) New(id string, user *types.User, identity *types.Identity, socket types.WebSocket) types.Session {
+	name := ""
+	if identity != nil {
+		name = identity.Name
+	}
+
+	// session cannot be resumed without token
+	token, err := utils.NewUID(32)
+	if err != nil {
+		manager.logger.Warn().Err(err).Str("id", id).Msg("unable to generate resume token")
+	}
+
 	session := &Session{
-		id:        id,
-		admin:     admin,
-		manager:   manager,
-		socket:    socket,
-		logger:    manager.logger.With().Str("id", id).Logger(),
-		connected: false,
+		id:          id,
+		token:       token,
+		name:        name,
+		user:        *user,
+		identity:    identity,
+		permissions: user.Role.Permissions(),
+		manager:     manager,
+		socket:      socket,
+		logger:      manager.logger.With().Str("id", id).Logger(),
+		connected:   false,
 	}
 
 	manager.mu.Lock()
 	manager.members[id] = session
 	manager.capture.Audio().AddListener()
-	manager.capture.Video().AddListener()
 	manager.mu.Unlock()
 
 	manager.emmiter.Emit("created", id, session)
@@ -54,27 +91,80 @@ func (manager *SessionManager) New(id string, admin bool, socket types.WebSocket
 }
 
 func (manager *SessionManager) HasHost() bool {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
 	return manager.host != ""
 }
 
 func (manager *SessionManager) IsHost(id string) bool {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
 	return manager.host == id
 }
 
 func (manager *SessionManager) SetHost(id string) error {
 	manager.mu.Lock()
 	_, ok := manager.members[id]
+	if ok {
+		manager.setHost(id)
+	}
 	manager.mu.Unlock()
 
 	if ok {
-		manager.host = id
 		manager.emmiter.Emit("host", id)
+		manager.emitControlQueue()
 		return nil
 	}
 
 	return fmt.Errorf("invalid session id %s", id)
 }
 
+// SetHostIfNone sets host only if there is no host, so that two sessions
+// requesting control at the same time cannot both take it.
+func (manager *SessionManager) SetHostIfNone(id string) bool {
+	return manager.swapHost("", id)
+}
+
+// SetHostIf passes control only if the host is still the expected session.
+func (manager *SessionManager) SetHostIf(current string, id string) bool {
+	return current != "" && manager.swapHost(current, id)
+}
+
+// ClearHostIf clears host only if it is the session.
+func (manager *SessionManager) ClearHostIf(id string) bool {
+	return id != "" && manager.swapHost(id, "")
+}
+
+// swapHost changes host from current to id atomically.
+func (manager *SessionManager) swapHost(current string, id string) bool {
+	manager.mu.Lock()
+	if manager.host != current {
+		manager.mu.Unlock()
+		return false
+	}
+
+	if id != "" {
+		if _, ok := manager.members[id]; !ok {
+			manager.mu.Unlock()
+			return false
+		}
+	}
+
+	manager.setHost(id)
+	manager.mu.Unlock()
+
+	if id != "" {
+		manager.emmiter.Emit("host", id)
+	} else {
+		manager.emmiter.Emit("host_cleared", current)
+	}
+
+	manager.emitControlQueue()
+	return true
+}
+
 func (manager *SessionManager) GetHost() (types.Session, bool) {
 	manager.mu.Lock()
 	defer manager.mu.Unlock()
@@ -84,9 +174,13 @@ func (manager *SessionManager) GetHost() (types.Session, bool) {
 }
 
 func (manager *SessionManager) ClearHost() {
+	manager.mu.Lock()
 	id := manager.host
-	manager.host = ""
+	manager.setHost("")
+	manager.mu.Unlock()
+
 	manager.emmiter.Emit("host_cleared", id)
+	manager.emitControlQueue()
 }
 
 func (manager *SessionManager) Has(id string) bool {
@@ -107,21 +201,30 @@ func (manager *SessionManager) Get(id string) (types.Session, bool) {
 
 // TODO: Handle locks in sessions as flags.
 func (manager *SessionManager) SetControlLocked(locked bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
 	manager.controlLocked = locked
 }
 
 func (manager *SessionManager) CanControl(id string) bool {
-	session, ok := manager.Get(id)
-	return ok && (!manager.controlLocked || session.Admin())
-}
-
-func (manager *SessionManager) Admins() []*types.Member {
 	manager.mu.Lock()
 	defer manager.mu.Unlock()
 
+	session, ok := manager.members[id]
+	return ok && manager.canControl(session)
+}
+
+// canControl must be called with lock held.
+func (manager *SessionManager) canControl(session *Session) bool {
+	permissions := session.Permissions()
+	return permissions.CanControl && (!manager.controlLocked || permissions.CanModerate)
+}
+
+func (manager *SessionManager) Admins() []*types.Member {
 	members := []*types.Member{}
-	for _, session := range manager.members {
-		if !session.connected || !session.admin {
+	for _, session := range manager.connected() {
+		if !session.Admin() {
 			continue
 		}
 
@@ -135,14 +238,8 @@ func (manager *SessionManager) Admins() []*types.Member {
 }
 
 func (manager *SessionManager) Members() []*types.Member {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
-
 	members := []*types.Member{}
-	for _, session := range manager.members {
-		if !session.connected {
-			continue
-		}
+	for _, session := range manager.connected() {
 
 		member := session.Member()
 		if member != nil {
@@ -155,20 +252,32 @@ func (manager *SessionManager) Members() []*types.Member {
 func (manager *SessionManager) Destroy(id string) {
 	manager.mu.Lock()
 	session, ok := manager.members[id]
+	queued := false
 	if ok {
-		err := session.destroy()
 		delete(manager.members, id)
+		queued = manager.dequeue(id)
+
+		if reconnect, ok := manager.reconnects[id]; ok {
+			reconnect.timer.Stop()
+			delete(manager.reconnects, id)
+		}
 
 		manager.capture.Audio().RemoveListener()
-		manager.capture.Video().RemoveListener()
-		manager.mu.Unlock()
+	}
+	manager.mu.Unlock()
 
-		manager.emmiter.Emit("destroyed", id, session)
-		manager.logger.Err(err).Str("session_id", id).Msg("destroying session")
+	if !ok {
 		return
 	}
 
-	manager.mu.Unlock()
+	if queued {
+		manager.emitControlQueue()
+	}
+
+	// socket and peer are closed outside of the lock, they call back to the manager
+	err := session.destroy()
+	manager.emmiter.Emit("destroyed", id, session)
+	manager.logger.Err(err).Str("session_id", id).Msg("destroying session")
 }
 
 func (manager *SessionManager) Clear() error {
@@ -176,16 +285,30 @@ func (manager *SessionManager) Clear() error {
 }
 
 func (manager *SessionManager) Broadcast(v interface{}, exclude interface{}) error {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
+	for _, session := range manager.connected() {
+		if exclude != nil {
+			if in, _ := utils.ArrayIn(session.id, exclude); in {
+				continue
+			}
+		}
 
-	for id, session := range manager.members {
-		if !session.connected {
+		if err := session.Send(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PermissionBroadcast sends message only to sessions whose permissions pass the check.
+func (manager *SessionManager) PermissionBroadcast(v interface{}, check func(permissions types.Permissions) bool, exclude interface{}) error {
+	for _, session := range manager.connected() {
+		if !check(session.Permissions()) {
 			continue
 		}
 
 		if exclude != nil {
-			if in, _ := utils.ArrayIn(id, exclude); in {
+			if in, _ := utils.ArrayIn(session.id, exclude); in {
 				continue
 			}
 		}
@@ -198,22 +321,27 @@ func (manager *SessionManager) Broadcast(v interface{}, exclude interface{}) err
 	return nil
 }
 
-func (manager *SessionManager) AdminBroadcast(v interface{}, exclude interface{}) error {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
-
-	for id, session := range manager.members {
-		if !session.connected || !session.admin {
+// DataBroadcast writes binary payload to data channels, peers that do not
+// keep up are skipped. Sessions without open data channel get fallback
+// message over websocket instead, if it is set.
+func (manager *SessionManager) DataBroadcast(v interface{}, fallback interface{}, check func(permissions types.Permissions) bool, exclude interface{}) error {
+	for _, session := range manager.connected() {
+		if !check(session.Permissions()) {
 			continue
 		}
 
 		if exclude != nil {
-			if in, _ := utils.ArrayIn(id, exclude); in {
+			if in, _ := utils.ArrayIn(session.id, exclude); in {
 				continue
 			}
 		}
 
-		if err := session.Send(v); err != nil {
+		err := session.SendData(v)
+		if errors.Is(err, types.ErrDataChannelNotOpen) && fallback != nil {
+			err = session.Send(fallback)
+		}
+
+		if err != nil && !errors.Is(err, types.ErrDataChannelNotOpen) && !errors.Is(err, types.ErrDataChannelBusy) {
 			return err
 		}
 	}
@@ -221,6 +349,22 @@ func (manager *SessionManager) AdminBroadcast(v interface{}, exclude interface{}
 	return nil
 }
 
+// connected returns snapshot of connected sessions, so that they can be
+// iterated without holding the mutex.
+func (manager *SessionManager) connected() []*Session {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(manager.members))
+	for _, session := range manager.members {
+		if session.Connected() {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions
+}
+
 func (manager *SessionManager) OnHost(listener func(id string)) {
 	manager.emmiter.On("host", func(payload ...interface{}) {
 		listener(payload[0].(string))
@@ -0,0 +1,338 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+// tests are meant to be run with -race, they hammer the manager from many
+// goroutines and check, that invariants hold afterwards.
+
+const (
+	raceSessions   = 32
+	raceIterations = 50
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+type fakeSocket struct {
+	sent      int32
+	destroyed int32
+}
+
+func (s *fakeSocket) Address() string {
+	return "192.0.2.1"
+}
+
+func (s *fakeSocket) Send(v interface{}) error {
+	atomic.AddInt32(&s.sent, 1)
+	return nil
+}
+
+func (s *fakeSocket) Destroy() error {
+	atomic.AddInt32(&s.destroyed, 1)
+	return nil
+}
+
+type fakePeer struct {
+	written   int32
+	destroyed int32
+}
+
+func (p *fakePeer) CreateOffer(restart bool) (string, error) { return "offer", nil }
+func (p *fakePeer) CreateAnswer() (string, error)            { return "answer", nil }
+func (p *fakePeer) SetOffer(sdp string) error                { return nil }
+func (p *fakePeer) SetAnswer(sdp string) error               { return nil }
+func (p *fakePeer) SetMediaEnabled(enabled bool) error       { return nil }
+func (p *fakePeer) GetStats() types.PeerStats                { return types.PeerStats{} }
+
+func (p *fakePeer) WriteData(v interface{}) error {
+	atomic.AddInt32(&p.written, 1)
+	return nil
+}
+
+func (p *fakePeer) Destroy() error {
+	atomic.AddInt32(&p.destroyed, 1)
+	return nil
+}
+
+// fakeSink counts audio listeners, other methods are not used by the manager.
+type fakeSink struct {
+	types.StreamSinkManager
+	listeners int32
+}
+
+func (s *fakeSink) AddListener() error {
+	atomic.AddInt32(&s.listeners, 1)
+	return nil
+}
+
+func (s *fakeSink) RemoveListener() error {
+	atomic.AddInt32(&s.listeners, -1)
+	return nil
+}
+
+type fakeCapture struct {
+	types.CaptureManager
+	audio fakeSink
+}
+
+func (c *fakeCapture) Audio() types.StreamSinkManager {
+	return &c.audio
+}
+
+func allowAll(types.Session) bool {
+	return true
+}
+
+func newTestManager(conf *config.Session) (*SessionManager, *fakeCapture) {
+	capture := &fakeCapture{}
+	return New(capture, conf), capture
+}
+
+func newTestSession(manager *SessionManager, id string) (*Session, *fakeSocket, *fakePeer) {
+	socket, peer := &fakeSocket{}, &fakePeer{}
+	session := manager.New(id, &types.User{Role: types.RoleParticipant}, nil, socket).(*Session)
+	session.SetPeer(peer)
+	session.SetConnected(true)
+	return session, socket, peer
+}
+
+func TestSetHostIfNone(t *testing.T) {
+	manager, _ := newTestManager(&config.Session{})
+
+	var events int32
+	manager.OnHost(func(id string) {
+		atomic.AddInt32(&events, 1)
+	})
+
+	for i := 0; i < raceSessions; i++ {
+		newTestSession(manager, fmt.Sprint(i))
+	}
+
+	var wg sync.WaitGroup
+	var winners int32
+	for i := 0; i < raceSessions; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if manager.SetHostIfNone(id) {
+				atomic.AddInt32(&winners, 1)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+
+	if winners != 1 || events != 1 {
+		t.Fatalf("winners = %d, host events = %d, want exactly one", winners, events)
+	}
+
+	if !manager.HasHost() {
+		t.Fatal("no host")
+	}
+
+	if manager.SetHostIfNone("unknown") {
+		t.Fatal("host replaced")
+	}
+}
+
+func TestClearHostIf(t *testing.T) {
+	manager, _ := newTestManager(&config.Session{})
+	newTestSession(manager, "host")
+	newTestSession(manager, "other")
+
+	if manager.ClearHostIf("host") {
+		t.Fatal("cleared host, that was not set")
+	}
+
+	for i := 0; i < raceIterations; i++ {
+		if !manager.SetHostIfNone("host") {
+			t.Fatal("unable to set host")
+		}
+
+		var wg sync.WaitGroup
+		var cleared, given int32
+		for j := 0; j < raceSessions; j++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if manager.ClearHostIf("host") {
+					atomic.AddInt32(&cleared, 1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if manager.SetHostIf("host", "other") {
+					atomic.AddInt32(&given, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// host is either released or given away, never both
+		if cleared+given != 1 {
+			t.Fatalf("cleared = %d, given = %d, want one change", cleared, given)
+		}
+
+		if manager.IsHost("host") {
+			t.Fatal("host was not changed")
+		}
+
+		manager.ClearHost()
+	}
+}
+
+func TestSessionsConcurrent(t *testing.T) {
+	manager, capture := newTestManager(&config.Session{})
+
+	var wg sync.WaitGroup
+	sockets := make([]*fakeSocket, raceSessions)
+	peers := make([]*fakePeer, raceSessions)
+	for i := 0; i < raceSessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprint(i)
+			session, socket, peer := newTestSession(manager, id)
+			sockets[i], peers[i] = socket, peer
+
+			for j := 0; j < raceIterations; j++ {
+				session.SetName(fmt.Sprint("name", j))
+				session.SetMuted(j%2 == 0)
+				session.SetPermissions(types.RoleParticipant.Permissions())
+				manager.SetControlLocked(j%3 == 0)
+
+				if !manager.SetHostIfNone(id) {
+					manager.QueueControl(id)
+				}
+
+				manager.Members()
+				manager.ControlQueue()
+				manager.CanControl(id)
+				manager.Broadcast(struct{}{}, nil)
+				manager.DataBroadcast(struct{}{}, nil, func(types.Permissions) bool { return true }, nil)
+
+				if manager.ClearHostIf(id) {
+					manager.NextHost()
+				}
+
+				manager.DequeueControl(id)
+			}
+
+			manager.Destroy(id)
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(manager.Members()); n != 0 {
+		t.Fatalf("members = %d, want 0", n)
+	}
+
+	if n := atomic.LoadInt32(&capture.audio.listeners); n != 0 {
+		t.Fatalf("audio listeners = %d, want 0", n)
+	}
+
+	for i := range sockets {
+		if sockets[i].destroyed != 1 || peers[i].destroyed != 1 {
+			t.Fatalf("session %d: socket destroyed %d times, peer %d times", i, sockets[i].destroyed, peers[i].destroyed)
+		}
+	}
+}
+
+func TestResumeConcurrent(t *testing.T) {
+	manager, _ := newTestManager(&config.Session{ReconnectGrace: time.Minute})
+
+	session, socket, _ := newTestSession(manager, "id")
+	token := session.ResumeToken()
+
+	var wg sync.WaitGroup
+	resumed := make([]*fakeSocket, raceSessions)
+	for i := 0; i < raceSessions; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			manager.Detach("id", socket)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			resumed[i] = &fakeSocket{}
+			if _, err := manager.Resume("id", token, resumed[i], allowAll); err != nil {
+				t.Errorf("unable to resume: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// all resumed sockets but the last attached one are closed, detached
+	// socket is closed by its owner
+	open := 0
+	for _, s := range resumed {
+		if atomic.LoadInt32(&s.destroyed) == 0 {
+			open++
+		}
+	}
+
+	if open != 1 {
+		t.Fatalf("%d sockets left open, want 1", open)
+	}
+
+	if _, err := manager.Resume("id", "invalid", &fakeSocket{}, allowAll); err != types.ErrSessionInvalidToken {
+		t.Fatalf("error = %v, want invalid token", err)
+	}
+
+	deny := func(types.Session) bool { return false }
+	if _, err := manager.Resume("id", token, &fakeSocket{}, deny); err != types.ErrSessionRejected {
+		t.Fatalf("error = %v, want rejected", err)
+	}
+
+	manager.Destroy("id")
+	if _, err := manager.Resume("id", token, &fakeSocket{}, allowAll); err != types.ErrSessionNotFound {
+		t.Fatalf("error = %v, want not found", err)
+	}
+}
+
+func TestControlMaxHoldWithoutQueue(t *testing.T) {
+	manager, _ := newTestManager(&config.Session{ControlMaxHold: 10 * time.Millisecond})
+	newTestSession(manager, "host")
+	newTestSession(manager, "other")
+
+	expired := make(chan string, 1)
+	manager.OnControlExpired(func(id string, reason string) {
+		if reason == ControlExpiredMaxHold {
+			expired <- id
+		}
+	})
+
+	manager.SetHostIfNone("host")
+
+	// nobody waits, host keeps control
+	select {
+	case <-expired:
+		t.Fatal("control expired without queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	manager.QueueControl("other")
+
+	select {
+	case id := <-expired:
+		if id != "host" {
+			t.Fatalf("expired host = %q, want %q", id, "host")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("control did not expire after someone queued")
+	}
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"m1k1o/neko/internal/types"
+)
+
+// reconnect of a session, that lost its socket or its peer connection.
+type reconnect struct {
+	lostAt time.Time
+	timer  *time.Timer
+}
+
+// Detach is called when socket of the session was closed. Session is kept
+// without socket for reconnect grace period and it can be resumed meanwhile.
+// It returns false, if socket was replaced by a resumed one already.
+func (manager *SessionManager) Detach(id string, socket types.WebSocket) bool {
+	manager.mu.Lock()
+	session, ok := manager.members[id]
+	manager.mu.Unlock()
+
+	if !ok || !session.detach(socket) {
+		return false
+	}
+
+	manager.interrupt(id)
+	return true
+}
+
+// Interrupt is called when peer of the session lost connection. Session is
+// destroyed, unless peer connects again within reconnect grace period.
+func (manager *SessionManager) Interrupt(id string) {
+	manager.mu.Lock()
+	session, ok := manager.members[id]
+	manager.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	session.interrupt()
+	manager.interrupt(id)
+}
+
+// Resume attaches new socket to the session, if token matches and allow
+// accepts it. Previous socket is closed, if it was not noticed to be closed yet.
+func (manager *SessionManager) Resume(id string, token string, socket types.WebSocket, allow func(session types.Session) bool) (types.Session, error) {
+	manager.mu.Lock()
+	session, ok := manager.members[id]
+	manager.mu.Unlock()
+
+	if !ok {
+		return nil, types.ErrSessionNotFound
+	}
+
+	if session.token == "" || subtle.ConstantTimeCompare([]byte(session.token), []byte(token)) != 1 {
+		return nil, types.ErrSessionInvalidToken
+	}
+
+	if !allow(session) {
+		return nil, types.ErrSessionRejected
+	}
+
+	if prev := session.attach(socket); prev != nil {
+		if err := prev.Destroy(); err != nil {
+			manager.logger.Debug().Err(err).Str("id", id).Msg("closing previous socket has failed")
+		}
+	}
+
+	manager.logger.Info().Str("id", id).Msg("session resumed")
+	return session, nil
+}
+
+// interrupt starts reconnect grace period of the session, if it is not
+// running already. Without grace period, session is destroyed right away.
+func (manager *SessionManager) interrupt(id string) {
+	grace := manager.config.ReconnectGrace
+	if grace == 0 {
+		manager.Destroy(id)
+		return
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if _, ok := manager.members[id]; !ok {
+		return
+	}
+
+	r, ok := manager.reconnects[id]
+	if !ok {
+		r = &reconnect{
+			timer: time.AfterFunc(grace, func() {
+				manager.checkReconnect(id)
+			}),
+		}
+		manager.reconnects[id] = r
+	}
+
+	r.lostAt = time.Now()
+	manager.logger.Info().Str("id", id).Dur("grace", grace).Msg("session waiting for reconnect")
+}
+
+// checkReconnect is called when session could be disconnected for long
+// enough, timer is started again if it lost connection in the meantime.
+func (manager *SessionManager) checkReconnect(id string) {
+	grace := manager.config.ReconnectGrace
+
+	manager.mu.Lock()
+	session, ok := manager.members[id]
+	r, waiting := manager.reconnects[id]
+	if !ok || !waiting {
+		manager.mu.Unlock()
+		return
+	}
+
+	if session.restored() {
+		delete(manager.reconnects, id)
+		manager.mu.Unlock()
+		return
+	}
+
+	lost := time.Since(r.lostAt)
+	if lost < grace {
+		r.timer = time.AfterFunc(grace-lost, func() {
+			manager.checkReconnect(id)
+		})
+		manager.mu.Unlock()
+		return
+	}
+
+	delete(manager.reconnects, id)
+	manager.mu.Unlock()
+
+	manager.logger.Info().Str("id", id).Msg("session did not reconnect in time")
+	manager.Destroy(id)
+}
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"sync"
+
 	"m1k1o/neko/internal/types"
 	"m1k1o/neko/internal/types/event"
 	"m1k1o/neko/internal/types/message"
@@ -8,16 +10,24 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// Session fields, that can change, are guarded by mutex. Socket and peer
+// are never called with the mutex held, they can call back to the session.
 type Session struct {
-	logger    zerolog.Logger
-	id        string
-	name      string
-	admin     bool
-	muted     bool
-	connected bool
-	manager   *SessionManager
-	socket    types.WebSocket
-	peer      types.Peer
+	logger      zerolog.Logger
+	id          string
+	token       string
+	user        types.User
+	identity    *types.Identity
+	manager     *SessionManager
+	mu          sync.RWMutex
+	name        string
+	muted       bool
+	permissions types.Permissions
+	connected   bool
+	socket      types.WebSocket
+	peer        types.Peer
+	// peer lost connection and was not connected again yet
+	interrupted bool
 }
 
 func (session *Session) ID() string {
@@ -25,58 +35,132 @@ func (session *Session) ID() string {
 }
 
 func (session *Session) Name() string {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
 	return session.name
 }
 
+func (session *Session) Username() string {
+	return session.user.Username
+}
+
+func (session *Session) Role() types.Role {
+	return session.user.Role
+}
+
 func (session *Session) Admin() bool {
-	return session.admin
+	return session.user.Admin()
+}
+
+func (session *Session) Identity() *types.Identity {
+	return session.identity
 }
 
 func (session *Session) Muted() bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
 	return session.muted
 }
 
+func (session *Session) Permissions() types.Permissions {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	return session.permissions
+}
+
 func (session *Session) Connected() bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
 	return session.connected
 }
 
 func (session *Session) Address() string {
-	if session.socket == nil {
+	socket := session.getSocket()
+	if socket == nil {
 		return ""
 	}
-	return session.socket.Address()
+	return socket.Address()
 }
 
 func (session *Session) Member() *types.Member {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
 	return &types.Member{
-		ID:    session.id,
-		Name:  session.name,
-		Admin: session.admin,
-		Muted: session.muted,
+		ID:          session.id,
+		Name:        session.name,
+		Username:    session.user.Username,
+		Admin:       session.user.Admin(),
+		Muted:       session.muted,
+		Permissions: session.permissions,
 	}
 }
 
+// ResumeToken is given only to the client of the session, it is used
+// to reattach new socket after the connection was lost.
+func (session *Session) ResumeToken() string {
+	return session.token
+}
+
 func (session *Session) SetMuted(muted bool) {
+	session.mu.Lock()
 	session.muted = muted
+	session.mu.Unlock()
+}
+
+func (session *Session) SetPermissions(permissions types.Permissions) error {
+	session.mu.Lock()
+	session.permissions = permissions
+	peer := session.peer
+	session.mu.Unlock()
+
+	// media are not sent to sessions that cannot watch
+	if peer != nil {
+		return peer.SetMediaEnabled(permissions.CanWatch)
+	}
+
+	return nil
 }
 
 func (session *Session) SetName(name string) error {
+	session.mu.Lock()
 	session.name = name
+	session.mu.Unlock()
 	return nil
 }
 
 func (session *Session) SetSocket(socket types.WebSocket) error {
+	session.mu.Lock()
 	session.socket = socket
+	session.mu.Unlock()
 	return nil
 }
 
 func (session *Session) SetPeer(peer types.Peer) error {
+	session.mu.Lock()
 	session.peer = peer
+	session.mu.Unlock()
 	return nil
 }
 
 func (session *Session) SetConnected(connected bool) error {
+	session.mu.Lock()
+	reconnected := session.connected && connected
 	session.connected = connected
+	if connected {
+		session.interrupted = false
+	}
+	session.mu.Unlock()
+
+	// peer connected again after ICE restart
+	if reconnected {
+		return nil
+	}
+
 	if connected {
 		session.manager.emmiter.Emit("connected", session.id, session)
 	}
@@ -84,57 +168,71 @@ func (session *Session) SetConnected(connected bool) error {
 }
 
 func (session *Session) Kick(reason string) error {
-	if session.socket == nil {
-		return nil
-	}
-	if err := session.socket.Send(&message.SystemMessage{
-		Event:   event.SYSTEM_DISCONNECT,
-		Message: reason,
-	}); err != nil {
-		return err
+	if socket := session.getSocket(); socket != nil {
+		if err := socket.Send(&message.SystemMessage{
+			Event:   event.SYSTEM_DISCONNECT,
+			Message: reason,
+		}); err != nil {
+			return err
+		}
 	}
 
-	return session.destroy()
+	// kicked session is not kept for reconnect
+	session.manager.Destroy(session.id)
+	return nil
 }
 
 func (session *Session) Send(v interface{}) error {
-	if session.socket == nil {
+	socket := session.getSocket()
+	if socket == nil {
 		return nil
 	}
-	return session.socket.Send(v)
+	return socket.Send(v)
+}
+
+// SendData writes binary payload to data channel of the peer.
+func (session *Session) SendData(v interface{}) error {
+	peer := session.getPeer()
+	if peer == nil {
+		return types.ErrDataChannelNotOpen
+	}
+	return peer.WriteData(v)
 }
 
 func (session *Session) SignalLocalOffer(sdp string) error {
-	if session.peer == nil {
+	socket, peer := session.getSocket(), session.getPeer()
+	if peer == nil || socket == nil {
 		return nil
 	}
 	session.logger.Info().Msg("signal update - LocalOffer")
-	return session.socket.Send(&message.SignalOffer{
+	return socket.Send(&message.SignalOffer{
 		Event: event.SIGNAL_OFFER,
 		SDP:   sdp,
 	})
 }
 
 func (session *Session) SignalLocalAnswer(sdp string) error {
-	if session.peer == nil {
+	socket, peer := session.getSocket(), session.getPeer()
+	if peer == nil || socket == nil {
 		return nil
 	}
 
 	session.logger.Info().Msg("signal update - LocalAnswer")
-	return session.socket.Send(&message.SignalAnswer{
+	return socket.Send(&message.SignalAnswer{
 		Event: event.SIGNAL_ANSWER,
 		SDP:   sdp,
 	})
 }
 
 func (session *Session) SignalRemoteOffer(sdp string) error {
-	if session.peer == nil {
+	peer := session.getPeer()
+	if peer == nil {
 		return nil
 	}
-	if err := session.peer.SetOffer(sdp); err != nil {
+	if err := peer.SetOffer(sdp); err != nil {
 		return err
 	}
-	sdp, err := session.peer.CreateAnswer()
+	sdp, err := peer.CreateAnswer()
 	if err != nil {
 		return err
 	}
@@ -143,32 +241,100 @@ func (session *Session) SignalRemoteOffer(sdp string) error {
 }
 
 func (session *Session) SignalRemoteAnswer(sdp string) error {
-	if session.peer == nil {
+	peer := session.getPeer()
+	if peer == nil {
 		return nil
 	}
 	session.logger.Info().Msg("signal update - RemoteAnswer")
-	return session.peer.SetAnswer(sdp)
+	return peer.SetAnswer(sdp)
 }
 
 func (session *Session) SignalCandidate(data string) error {
-	if session.socket == nil {
+	socket := session.getSocket()
+	if socket == nil {
 		return nil
 	}
-	return session.socket.Send(&message.SignalCandidate{
+	return socket.Send(&message.SignalCandidate{
 		Event: event.SIGNAL_CANDIDATE,
 		Data:  data,
 	})
 }
 
+// SignalRestart sends offer with new ICE credentials, so that peer can
+// connect again, possibly over a different network path.
+func (session *Session) SignalRestart() error {
+	peer := session.getPeer()
+	if peer == nil {
+		return nil
+	}
+	sdp, err := peer.CreateOffer(true)
+	if err != nil {
+		return err
+	}
+	session.logger.Info().Msg("signal update - ICE restart")
+	return session.SignalLocalOffer(sdp)
+}
+
+// attach replaces socket of the session and returns the previous one.
+func (session *Session) attach(socket types.WebSocket) types.WebSocket {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	prev := session.socket
+	session.socket = socket
+	return prev
+}
+
+// detach removes socket from the session, unless it was replaced already.
+func (session *Session) detach(socket types.WebSocket) bool {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.socket != socket {
+		return false
+	}
+
+	session.socket = nil
+	return true
+}
+
+func (session *Session) interrupt() {
+	session.mu.Lock()
+	session.interrupted = true
+	session.mu.Unlock()
+}
+
+// restored reports whether session has socket and its peer is connected.
+func (session *Session) restored() bool {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	return session.socket != nil && !session.interrupted
+}
+
+func (session *Session) getSocket() types.WebSocket {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	return session.socket
+}
+
+func (session *Session) getPeer() types.Peer {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	return session.peer
+}
+
 func (session *Session) destroy() error {
-	if session.socket != nil {
-		if err := session.socket.Destroy(); err != nil {
+	if socket := session.getSocket(); socket != nil {
+		if err := socket.Destroy(); err != nil {
 			return err
 		}
 	}
 
-	if session.peer != nil {
-		if err := session.peer.Destroy(); err != nil {
+	if peer := session.getPeer(); peer != nil {
+		if err := peer.Destroy(); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,260 @@
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pion/turn/v2"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/utils"
+	"m1k1o/neko/internal/webrtc/pionlog"
+)
+
+// TURNManager runs embedded TURN server for clients behind restricted
+// networks. Clients get ephemeral credentials using TURN REST API scheme,
+// username is expiry timestamp and session ID and password is its HMAC.
+// Listeners and relays are IPv4 only.
+type TURNManager struct {
+	logger   zerolog.Logger
+	config   *config.TURN
+	webrtc   *config.WebRTC
+	localIPs []net.IP
+	secret   string
+	urls     []string
+	server   *turn.Server
+	closers  []func() error
+}
+
+func New(config *config.TURN, webrtc *config.WebRTC) *TURNManager {
+	return &TURNManager{
+		logger: log.With().Str("module", "turn").Logger(),
+		config: config,
+		webrtc: webrtc,
+	}
+}
+
+func (manager *TURNManager) Start() {
+	if !manager.config.Enabled {
+		return
+	}
+
+	manager.secret = manager.config.Secret
+	if manager.secret == "" {
+		secret, err := utils.NewUID(32)
+		if err != nil {
+			manager.logger.Panic().Err(err).Msg("unable to generate TURN secret")
+		}
+
+		manager.secret = secret
+	}
+
+	manager.localIPs = localIPs(manager.webrtc.NAT1To1IPs)
+
+	// relays are allocated on public address, nat1to1 can be mapping external/internal
+	relayIP := manager.config.RelayIP
+	if relayIP == nil && len(manager.webrtc.NAT1To1IPs) > 0 {
+		relayIP = net.ParseIP(strings.SplitN(manager.webrtc.NAT1To1IPs[0], "/", 2)[0]).To4()
+	}
+
+	if relayIP == nil {
+		relayIP = net.IPv4(127, 0, 0, 1)
+		for _, ip := range manager.localIPs {
+			if ip.To4() != nil {
+				relayIP = ip
+				break
+			}
+		}
+
+		manager.logger.Warn().Str("relay_ip", relayIP.String()).Msg("TURN relays are allocated on local address, set turn_relay_ip for clients outside of local network")
+	}
+
+	manager.localIPs = append(manager.localIPs, relayIP)
+
+	host := manager.config.Host
+	if host == "" {
+		host = relayIP.String()
+	}
+
+	relay := &relayGenerator{
+		RelayAddressGenerator: &turn.RelayAddressGeneratorPortRange{
+			RelayAddress: relayIP,
+			Address:      "0.0.0.0",
+			MinPort:      manager.config.RelayMin,
+			MaxPort:      manager.config.RelayMax,
+		},
+		allow: manager.allowPeer,
+	}
+
+	serverConfig := turn.ServerConfig{
+		Realm:         manager.config.Realm,
+		AuthHandler:   manager.authenticate,
+		LoggerFactory: pionlog.New(manager.logger),
+	}
+
+	if port := manager.config.UDP; port != 0 {
+		conn, err := net.ListenPacket("udp4", fmt.Sprintf("0.0.0.0:%d", port))
+		if err != nil {
+			manager.logger.Panic().Err(err).Int("port", port).Msg("unable to listen on TURN UDP port")
+		}
+
+		manager.closers = append(manager.closers, conn.Close)
+		serverConfig.PacketConnConfigs = append(serverConfig.PacketConnConfigs, turn.PacketConnConfig{
+			PacketConn:            conn,
+			RelayAddressGenerator: relay,
+		})
+		manager.urls = append(manager.urls, fmt.Sprintf("turn:%s:%d?transport=udp", host, port))
+	}
+
+	if port := manager.config.TCP; port != 0 {
+		listener, err := net.Listen("tcp4", fmt.Sprintf("0.0.0.0:%d", port))
+		if err != nil {
+			manager.logger.Panic().Err(err).Int("port", port).Msg("unable to listen on TURN TCP port")
+		}
+
+		manager.closers = append(manager.closers, listener.Close)
+		serverConfig.ListenerConfigs = append(serverConfig.ListenerConfigs, turn.ListenerConfig{
+			Listener:              listener,
+			RelayAddressGenerator: relay,
+		})
+		manager.urls = append(manager.urls, fmt.Sprintf("turn:%s:%d?transport=tcp", host, port))
+	}
+
+	if port := manager.config.TLS; port != 0 {
+		cert, err := tls.LoadX509KeyPair(manager.config.Cert, manager.config.Key)
+		if err != nil {
+			manager.logger.Panic().Err(err).Msg("unable to load TURN TLS certificate")
+		}
+
+		listener, err := tls.Listen("tcp4", fmt.Sprintf("0.0.0.0:%d", port), &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err != nil {
+			manager.logger.Panic().Err(err).Int("port", port).Msg("unable to listen on TURN TLS port")
+		}
+
+		manager.closers = append(manager.closers, listener.Close)
+		serverConfig.ListenerConfigs = append(serverConfig.ListenerConfigs, turn.ListenerConfig{
+			Listener:              listener,
+			RelayAddressGenerator: relay,
+		})
+		manager.urls = append(manager.urls, fmt.Sprintf("turns:%s:%d?transport=tcp", host, port))
+	}
+
+	server, err := turn.NewServer(serverConfig)
+	if err != nil {
+		manager.close()
+		manager.logger.Panic().Err(err).Msg("unable to start TURN server")
+	}
+
+	manager.server = server
+
+	manager.logger.Info().
+		Strs("urls", manager.urls).
+		Str("relay_ip", relayIP.String()).
+		Str("relay_ports", fmt.Sprintf("%d-%d", manager.config.RelayMin, manager.config.RelayMax)).
+		Msg("TURN server started")
+}
+
+func (manager *TURNManager) Shutdown() error {
+	if manager.server == nil {
+		return nil
+	}
+
+	manager.logger.Info().Msgf("TURN server shutting down")
+	return manager.server.Close()
+}
+
+func (manager *TURNManager) Enabled() bool {
+	return manager.config.Enabled
+}
+
+// ICEServers mints TURN credentials for the session, they are valid for
+// configured TTL. Nothing is returned, if TURN server is not enabled.
+func (manager *TURNManager) ICEServers(id string) []webrtc.ICEServer {
+	if manager.server == nil {
+		return nil
+	}
+
+	username := fmt.Sprintf("%d:%s", time.Now().Add(manager.config.TTL).Unix(), id)
+
+	return []webrtc.ICEServer{
+		{
+			URLs:           manager.urls,
+			Username:       username,
+			Credential:     manager.password(username),
+			CredentialType: webrtc.ICECredentialTypePassword,
+		},
+	}
+}
+
+func (manager *TURNManager) password(username string) string {
+	mac := hmac.New(sha1.New, []byte(manager.secret))
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// authenticate accepts usernames, that have not expired yet, and returns key
+// derived from their password. Request is rejected, if its integrity does
+// not match the key.
+func (manager *TURNManager) authenticate(username string, realm string, srcAddr net.Addr) ([]byte, bool) {
+	expiry, _, _ := strings.Cut(username, ":")
+
+	timestamp, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		manager.logger.Debug().Str("username", username).Str("address", srcAddr.String()).Msg("invalid TURN username")
+		return nil, false
+	}
+
+	if time.Now().Unix() > timestamp {
+		manager.logger.Debug().Str("username", username).Str("address", srcAddr.String()).Msg("expired TURN username")
+		return nil, false
+	}
+
+	return turn.GenerateAuthKey(username, realm, manager.password(username)), true
+}
+
+// localIPs returns addresses of this server, that are not loopback, including
+// both sides of nat1to1 mappings.
+func localIPs(natIPs []string) []net.IP {
+	ips := []net.IP{}
+
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, addr := range addrs {
+			if n, ok := addr.(*net.IPNet); ok && !n.IP.IsLoopback() {
+				ips = append(ips, n.IP)
+			}
+		}
+	}
+
+	for _, mapping := range natIPs {
+		for _, str := range strings.SplitN(mapping, "/", 2) {
+			if ip := net.ParseIP(str); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips
+}
+
+// close releases listeners, when TURN server could not be started.
+func (manager *TURNManager) close() {
+	for _, close := range manager.closers {
+		if err := close(); err != nil {
+			manager.logger.Warn().Err(err).Msg("unable to close TURN listener")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package turn
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pion/turn/v2"
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/config"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// freePort returns UDP port, that was free a moment ago.
+func freePort(t *testing.T) int {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func newTestManager(t *testing.T) *TURNManager {
+	relayPort := freePort(t)
+
+	manager := New(&config.TURN{
+		Enabled:  true,
+		Realm:    "neko",
+		Secret:   "secret",
+		TTL:      time.Hour,
+		RelayIP:  net.IPv4(127, 0, 0, 1),
+		UDP:      freePort(t),
+		RelayMin: uint16(relayPort),
+		RelayMax: uint16(relayPort),
+	}, &config.WebRTC{
+		EphemeralMin: 59000,
+		EphemeralMax: 59100,
+	})
+
+	manager.Start()
+	t.Cleanup(func() {
+		manager.Shutdown()
+	})
+
+	return manager
+}
+
+func newTestClient(t *testing.T, manager *TURNManager, username string, password string) *turn.Client {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(manager.config.UDP))
+	client, err := turn.NewClient(&turn.ClientConfig{
+		STUNServerAddr: addr,
+		TURNServerAddr: addr,
+		Conn:           conn,
+		Username:       username,
+		Password:       password,
+		Realm:          manager.config.Realm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		conn.Close()
+	})
+
+	return client
+}
+
+func TestAllocate(t *testing.T) {
+	manager := newTestManager(t)
+
+	servers := manager.ICEServers("id")
+	if len(servers) != 1 {
+		t.Fatalf("ice servers = %d, want 1", len(servers))
+	}
+
+	client := newTestClient(t, manager, servers[0].Username, servers[0].Credential.(string))
+
+	relay, err := client.Allocate()
+	if err != nil {
+		t.Fatalf("unable to allocate: %v", err)
+	}
+	defer relay.Close()
+
+	if ip := relay.LocalAddr().(*net.UDPAddr).IP; !ip.Equal(manager.config.RelayIP) {
+		t.Fatalf("relay ip = %s, want %s", ip, manager.config.RelayIP)
+	}
+
+	// loopback peer is not reachable through relay
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	if _, err := relay.WriteTo([]byte("ping"), peer.LocalAddr()); err != nil {
+		t.Fatalf("unable to write to relay: %v", err)
+	}
+
+	if err := peer.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	if n, _, err := peer.ReadFrom(buf); err == nil {
+		t.Fatalf("loopback peer received %q", buf[:n])
+	}
+}
+
+func TestAllocateExpired(t *testing.T) {
+	manager := newTestManager(t)
+
+	username := "1:id"
+	client := newTestClient(t, manager, username, manager.password(username))
+
+	if relay, err := client.Allocate(); err == nil {
+		relay.Close()
+		t.Fatal("allocated with expired credentials")
+	}
+}
+
+func TestAllowPeer(t *testing.T) {
+	manager := New(&config.TURN{}, &config.WebRTC{
+		EphemeralMin: 59000,
+		EphemeralMax: 59100,
+	})
+	manager.localIPs = []net.IP{net.ParseIP("172.17.0.2"), net.ParseIP("203.0.113.1")}
+
+	tests := []struct {
+		addr  string
+		allow bool
+	}{
+		{"198.51.100.1:3478", true},
+		{"[2001:db8::1]:3478", true},
+		{"127.0.0.1:59000", false},
+		{"[::1]:59000", false},
+		{"10.0.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+		{"224.0.0.1:80", false},
+		{"0.0.0.0:80", false},
+		// webrtc ports of this server are allowed
+		{"172.17.0.2:59000", true},
+		{"172.17.0.2:22", false},
+		{"203.0.113.1:59100", true},
+		{"203.0.113.1:8080", false},
+	}
+
+	for _, test := range tests {
+		addr, err := net.ResolveUDPAddr("udp", test.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if allow := manager.allowPeer(addr); allow != test.allow {
+			t.Errorf("allowPeer(%s) = %v, want %v", test.addr, allow, test.allow)
+		}
+	}
+}
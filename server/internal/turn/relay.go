@@ -0,0 +1,111 @@
+package turn
+
+import (
+	"errors"
+	"net"
+
+	"github.com/pion/turn/v2"
+)
+
+var errPeerNotAllowed = errors.New("TURN peer is not allowed")
+
+// peers in these ranges are not reachable through relays, in addition to
+// private, loopback, link local and multicast addresses
+var deniedNets = parseNets(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"198.18.0.0/15",
+)
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// relayGenerator allocates relays, that exchange packets only with peers
+// accepted by allow.
+type relayGenerator struct {
+	turn.RelayAddressGenerator
+	allow func(addr net.Addr) bool
+}
+
+func (g *relayGenerator) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	conn, addr, err := g.RelayAddressGenerator.AllocatePacketConn(network, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &relayConn{PacketConn: conn, allow: g.allow}, addr, nil
+}
+
+// relayConn drops packets from peers, that are not allowed, and refuses
+// to send packets to them.
+type relayConn struct {
+	net.PacketConn
+	allow func(addr net.Addr) bool
+}
+
+func (c *relayConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || c.allow(addr) {
+			return n, addr, err
+		}
+	}
+}
+
+func (c *relayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if !c.allow(addr) {
+		return 0, errPeerNotAllowed
+	}
+
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+// allowPeer denies relaying to addresses, that are not public, so that TURN
+// server cannot be used to reach internal network. Addresses of this server
+// are reachable only on WebRTC ports.
+func (manager *TURNManager) allowPeer(addr net.Addr) bool {
+	udp, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+
+	ip := udp.IP
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, local := range manager.localIPs {
+		if local.Equal(ip) {
+			return manager.webrtcPort(udp.Port)
+		}
+	}
+
+	if ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+
+	for _, n := range deniedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (manager *TURNManager) webrtcPort(port int) bool {
+	if manager.webrtc.UDPMUX != 0 {
+		return port == manager.webrtc.UDPMUX
+	}
+
+	return port >= int(manager.webrtc.EphemeralMin) && port <= int(manager.webrtc.EphemeralMax)
+}
@@ -0,0 +1,67 @@
+package types
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts")
+)
+
+type Role string
+
+const (
+	RoleViewer      Role = "viewer"
+	RoleParticipant Role = "participant"
+	RoleAdmin       Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleViewer || r == RoleParticipant || r == RoleAdmin
+}
+
+// Permissions returns default permissions of a role.
+func (r Role) Permissions() Permissions {
+	switch r {
+	case RoleAdmin:
+		return Permissions{
+			CanWatch:        true,
+			CanControl:      true,
+			CanChat:         true,
+			CanUseClipboard: true,
+			CanChangeScreen: true,
+			CanBroadcast:    true,
+			CanModerate:     true,
+		}
+	case RoleParticipant:
+		return Permissions{
+			CanWatch:        true,
+			CanControl:      true,
+			CanChat:         true,
+			CanUseClipboard: true,
+		}
+	case RoleViewer:
+		return Permissions{
+			CanWatch: true,
+			CanChat:  true,
+		}
+	}
+
+	return Permissions{}
+}
+
+// User authenticated by an auth provider. Username is empty for users
+// that logged in using a shared password.
+type User struct {
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+}
+
+func (u *User) Admin() bool {
+	return u.Role == RoleAdmin
+}
+
+type AuthProvider interface {
+	Authenticate(username string, password string) (*User, error)
+	// Role returns role of a known user, without verifying credentials.
+	Role(username string) (Role, bool)
+}
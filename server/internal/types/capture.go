@@ -7,7 +7,9 @@ import (
 )
 
 var (
-	ErrCapturePipelineAlreadyExists = errors.New("capture pipeline already exists")
+	ErrCapturePipelineAlreadyExists      = errors.New("capture pipeline already exists")
+	ErrCapturePipelineNotRunning         = errors.New("capture pipeline is not running")
+	ErrCapturePipelineBitrateUnsupported = errors.New("capture pipeline does not support changing bitrate")
 )
 
 type BroadcastManager interface {
@@ -18,21 +20,46 @@ type BroadcastManager interface {
 }
 
 type StreamSinkManager interface {
+	ID() string
+	// Bitrate is target bitrate of the pipeline in kbit/s.
+	Bitrate() uint
 	Codec() codec.RTPCodec
 	OnSample(listener func(sample Sample))
 
 	AddListener() error
 	RemoveListener() error
 
+	// ForceKeyUnit asks running encoder for a keyframe.
+	ForceKeyUnit() error
+	// SetEncoderBitrate changes bitrate of running encoder in kbit/s, it is
+	// reset to Bitrate when pipeline is recreated.
+	SetEncoderBitrate(bitrate uint) error
+
 	ListenersCount() int
 	Started() bool
 }
 
+// CursorManager tracks cursor, that is not captured in video, so that
+// clients can render it locally.
+type CursorManager interface {
+	Enabled() bool
+	Position() (x int, y int)
+	// Image returns current cursor image and its PNG encoding.
+	Image() (*CursorImage, []byte)
+
+	OnPosition(listener func(x int, y int))
+	OnImage(listener func(image *CursorImage, png []byte))
+}
+
 type CaptureManager interface {
 	Start()
 	Shutdown() error
 
 	Broadcast() BroadcastManager
 	Audio() StreamSinkManager
+	// Video returns the highest quality pipeline of video ladder.
 	Video() StreamSinkManager
+	// VideoLadder returns video pipelines ordered from the highest quality.
+	VideoLadder() []StreamSinkManager
+	Cursor() CursorManager
 }
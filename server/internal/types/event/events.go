@@ -27,6 +27,14 @@ const (
 	CONTROL_GIVE       = "control/give"
 	CONTROL_CLIPBOARD  = "control/clipboard"
 	CONTROL_KEYBOARD   = "control/keyboard"
+	CONTROL_QUEUE      = "control/queue"
+	CONTROL_DEQUEUE    = "control/dequeue"
+	CONTROL_INPUT      = "control/input"
+)
+
+const (
+	CURSOR_POSITION = "cursor/position"
+	CURSOR_IMAGE    = "cursor/image"
 )
 
 const (
@@ -47,13 +55,23 @@ const (
 )
 
 const (
-	ADMIN_BAN     = "admin/ban"
-	ADMIN_KICK    = "admin/kick"
-	ADMIN_LOCK    = "admin/lock"
-	ADMIN_MUTE    = "admin/mute"
-	ADMIN_UNLOCK  = "admin/unlock"
-	ADMIN_UNMUTE  = "admin/unmute"
-	ADMIN_CONTROL = "admin/control"
-	ADMIN_RELEASE = "admin/release"
-	ADMIN_GIVE    = "admin/give"
+	ADMIN_BAN         = "admin/ban"
+	ADMIN_KICK        = "admin/kick"
+	ADMIN_LOCK        = "admin/lock"
+	ADMIN_MUTE        = "admin/mute"
+	ADMIN_UNLOCK      = "admin/unlock"
+	ADMIN_UNMUTE      = "admin/unmute"
+	ADMIN_CONTROL     = "admin/control"
+	ADMIN_RELEASE     = "admin/release"
+	ADMIN_GIVE        = "admin/give"
+	ADMIN_PERMISSIONS = "admin/permissions"
+	ADMIN_INVITE      = "admin/invite"
+	ADMIN_INVITES     = "admin/invites"
+	ADMIN_REVOKE      = "admin/revoke"
+	ADMIN_UNBAN       = "admin/unban"
+	ADMIN_BANS        = "admin/bans"
+	ADMIN_PEER_STATS  = "admin/peer_stats"
+	ADMIN_BIND        = "admin/bind"
+	ADMIN_UNBIND      = "admin/unbind"
+	ADMIN_ERROR       = "admin/error"
 )
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteExpired  = errors.New("invite expired")
+	ErrInviteUsedUp   = errors.New("invite has no uses left")
+	ErrInviteInvalid  = errors.New("invalid invite options")
+)
+
+type InviteOptions struct {
+	Role Role `json:"role"`
+	// Name is enforced as display name, if not empty.
+	Name string `json:"name,omitempty"`
+	// ExpiresIn is lifetime of the invite in seconds, default is used if zero.
+	ExpiresIn int `json:"expires_in,omitempty"`
+	// MaxUses limits how many times can be the invite used, zero means unlimited.
+	MaxUses int `json:"max_uses,omitempty"`
+}
+
+type Invite struct {
+	ID        string `json:"id"`
+	Role      Role   `json:"role"`
+	Name      string `json:"name,omitempty"`
+	MaxUses   int    `json:"max_uses"`
+	Uses      int    `json:"uses"`
+	CreatedBy string `json:"created_by"`
+	// CreatedByAdmin invites can be revoked only by admins.
+	CreatedByAdmin bool      `json:"created_by_admin"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type InviteManager interface {
+	SetStore(store InviteStore)
+	CreateInvite(createdBy string, createdByAdmin bool, options InviteOptions) (string, *Invite, error)
+	VerifyInvite(token string) (*Invite, error)
+	UseInvite(id string) error
+	RevokeInvite(id string, admin bool) error
+	Invites() []Invite
+}
+
+// InviteStore persists outstanding invites and the key, that signs them.
+type InviteStore interface {
+	InviteKey() []byte
+	SetInviteKey(key []byte)
+	StoredInvites() []Invite
+	StoreInvite(invite Invite)
+	RemoveInvite(id string)
+}
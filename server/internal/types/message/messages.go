@@ -13,6 +13,7 @@ type Message struct {
 type SystemInit struct {
 	Event           string            `json:"event"`
 	ImplicitHosting bool              `json:"implicit_hosting"`
+	MultiControl    bool              `json:"multi_control"`
 	Locks           map[string]string `json:"locks"`
 }
 
@@ -23,11 +24,12 @@ type SystemMessage struct {
 }
 
 type SignalProvide struct {
-	Event string             `json:"event"`
-	ID    string             `json:"id"`
-	SDP   string             `json:"sdp"`
-	Lite  bool               `json:"lite"`
-	ICE   []webrtc.ICEServer `json:"ice"`
+	Event  string             `json:"event"`
+	ID     string             `json:"id"`
+	SDP    string             `json:"sdp"`
+	Lite   bool               `json:"lite"`
+	ICE    []webrtc.ICEServer `json:"ice"`
+	Resume string             `json:"resume,omitempty"` // token to resume session
 }
 
 type SignalOffer struct {
@@ -84,6 +86,16 @@ type ControlTarget struct {
 	Target string `json:"target"`
 }
 
+// ControlQueue tells members who waits for control, queue position is
+// index in the list. Times are in seconds, zero means disabled.
+type ControlQueue struct {
+	Event       string   `json:"event"`
+	Host        string   `json:"host,omitempty"`
+	Queue       []string `json:"queue"`
+	Remaining   int64    `json:"remaining,omitempty"`
+	IdleTimeout int64    `json:"idle_timeout,omitempty"`
+}
+
 type ChatReceive struct {
 	Event   string `json:"event"`
 	Content string `json:"content"`
@@ -111,18 +123,93 @@ type Admin struct {
 	ID    string `json:"id"`
 }
 
+type AdminBan struct {
+	Event    string `json:"event"`
+	ID       string `json:"id,omitempty"`     // member to be banned
+	Target   string `json:"target,omitempty"` // or address, range or identity
+	Reason   string `json:"reason,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+	Identity bool   `json:"identity,omitempty"`
+}
+
+type AdminUnban struct {
+	Event  string `json:"event"`
+	Target string `json:"target"`
+}
+
+type AdminBans struct {
+	Event string      `json:"event"`
+	Bans  []types.Ban `json:"bans"`
+}
+
+type AdminPeerStats struct {
+	Event string            `json:"event"`
+	Peers []types.PeerStats `json:"peers"`
+}
+
 type AdminTarget struct {
 	Event  string `json:"event"`
 	Target string `json:"target"`
 	ID     string `json:"id"`
 }
 
+type AdminPermissions struct {
+	Event       string            `json:"event"`
+	ID          string            `json:"id"`
+	Target      string            `json:"target,omitempty"`
+	Permissions types.Permissions `json:"permissions"`
+}
+
+type AdminInvite struct {
+	Event string `json:"event"`
+	types.InviteOptions
+}
+
+type AdminInviteCreated struct {
+	Event  string        `json:"event"`
+	Token  string        `json:"token"`
+	Invite *types.Invite `json:"invite"`
+}
+
+type AdminError struct {
+	Event   string `json:"event"`
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+type AdminInvites struct {
+	Event   string         `json:"event"`
+	Invites []types.Invite `json:"invites"`
+}
+
+type AdminMeeting struct {
+	Event   string `json:"event"`
+	Meeting string `json:"meeting,omitempty"`
+	ID      string `json:"id"`
+}
+
 type AdminLock struct {
 	Event    string `json:"event"`
 	Resource string `json:"resource"`
 	ID       string `json:"id"`
 }
 
+type CursorPosition struct {
+	Event string `json:"event"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+}
+
+// CursorImage carries PNG image as data URI, X and Y are its hotspot.
+type CursorImage struct {
+	Event  string `json:"event"`
+	URI    string `json:"uri"`
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+	X      uint16 `json:"x"`
+	Y      uint16 `json:"y"`
+}
+
 type ScreenResolution struct {
 	Event  string `json:"event"`
 	ID     string `json:"id,omitempty"`
@@ -1,20 +1,95 @@
 package types
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionInvalidToken = errors.New("invalid resume token")
+	ErrSessionRejected     = errors.New("session rejected")
+)
+
 type Member struct {
-	ID    string `json:"id"`
-	Name  string `json:"displayname"`
-	Admin bool   `json:"admin"`
-	Muted bool   `json:"muted"`
+	ID          string      `json:"id"`
+	Name        string      `json:"displayname"`
+	Username    string      `json:"username,omitempty"`
+	Admin       bool        `json:"admin"`
+	Muted       bool        `json:"muted"`
+	Permissions Permissions `json:"permissions"`
+}
+
+// Permissions of a session, defaults are given by role of the user
+// and they can be changed by moderators at runtime.
+type Permissions struct {
+	CanWatch        bool `json:"can_watch"`
+	CanControl      bool `json:"can_control"`
+	CanChat         bool `json:"can_chat"`
+	CanUseClipboard bool `json:"can_use_clipboard"`
+	CanChangeScreen bool `json:"can_change_screen"`
+	CanBroadcast    bool `json:"can_broadcast"`
+	CanModerate     bool `json:"can_moderate"`
+}
+
+func (p Permissions) flags() []bool {
+	return []bool{
+		p.CanWatch,
+		p.CanControl,
+		p.CanChat,
+		p.CanUseClipboard,
+		p.CanChangeScreen,
+		p.CanBroadcast,
+		p.CanModerate,
+	}
+}
+
+// CanChange reports whether holder of p can change permissions from old to
+// new, only permissions that are held can be granted or revoked.
+func (p Permissions) CanChange(old Permissions, new Permissions) bool {
+	held, from, to := p.flags(), old.flags(), new.flags()
+	for i := range held {
+		if from[i] != to[i] && !held[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Identity of a session authenticated by other means than a shared password.
+type Identity struct {
+	Provider  string
+	UserID    string
+	MeetingID string
+	// Name is enforced as display name, if not empty.
+	Name string
+}
+
+// ControlQueue is a snapshot of the host and members waiting for control.
+type ControlQueue struct {
+	Host  string
+	Queue []string
+	// Remaining is time until host is released, zero if hold time is unlimited.
+	Remaining time.Duration
+	// IdleTimeout releases host, that sends no input for this long.
+	IdleTimeout time.Duration
 }
 
 type Session interface {
 	ID() string
 	Name() string
+	Username() string
+	Role() Role
 	Admin() bool
+	Identity() *Identity
 	Muted() bool
+	Permissions() Permissions
 	Connected() bool
 	Member() *Member
+	ResumeToken() string
 	SetMuted(muted bool)
+	SetPermissions(permissions Permissions) error
 	SetName(name string) error
 	SetConnected(connected bool) error
 	SetSocket(socket WebSocket) error
@@ -22,33 +97,49 @@ type Session interface {
 	Address() string
 	Kick(message string) error
 	Send(v interface{}) error
+	SendData(v interface{}) error
 	SignalLocalOffer(sdp string) error
 	SignalLocalAnswer(sdp string) error
 	SignalRemoteOffer(sdp string) error
 	SignalRemoteAnswer(sdp string) error
 	SignalCandidate(data string) error
+	SignalRestart() error
 }
 
 type SessionManager interface {
-	New(id string, admin bool, socket WebSocket) Session
+	New(id string, user *User, identity *Identity, socket WebSocket) Session
 	HasHost() bool
 	IsHost(id string) bool
 	SetHost(id string) error
+	SetHostIfNone(id string) bool
+	SetHostIf(current string, id string) bool
 	GetHost() (Session, bool)
 	ClearHost()
+	ClearHostIf(id string) bool
 	Has(id string) bool
 	Get(id string) (Session, bool)
 	SetControlLocked(locked bool)
 	CanControl(id string) bool
+	QueueControl(id string) int
+	DequeueControl(id string) bool
+	NextHost() (Session, bool)
+	ControlQueue() ControlQueue
+	ControlActivity(id string)
 	Members() []*Member
 	Admins() []*Member
 	Destroy(id string)
+	Detach(id string, socket WebSocket) bool
+	Interrupt(id string)
+	Resume(id string, token string, socket WebSocket, allow func(session Session) bool) (Session, error)
 	Clear() error
 	Broadcast(v interface{}, exclude interface{}) error
-	AdminBroadcast(v interface{}, exclude interface{}) error
+	PermissionBroadcast(v interface{}, check func(permissions Permissions) bool, exclude interface{}) error
+	DataBroadcast(v interface{}, fallback interface{}, check func(permissions Permissions) bool, exclude interface{}) error
 	OnHost(listener func(id string))
 	OnHostCleared(listener func(id string))
 	OnDestroy(listener func(id string, session Session))
 	OnCreated(listener func(id string, session Session))
 	OnConnected(listener func(id string, session Session))
+	OnControlQueue(listener func(queue ControlQueue))
+	OnControlExpired(listener func(id string, reason string))
 }
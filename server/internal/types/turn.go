@@ -0,0 +1,10 @@
+package types
+
+import "github.com/pion/webrtc/v3"
+
+type TURNManager interface {
+	Start()
+	Shutdown() error
+	Enabled() bool
+	ICEServers(id string) []webrtc.ICEServer
+}
@@ -1,26 +1,75 @@
 package types
 
 import (
+	"errors"
+	"time"
+
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
 )
 
+var (
+	ErrDataChannelNotOpen = errors.New("data channel is not open")
+	ErrDataChannelBusy    = errors.New("data channel buffer is full")
+)
+
 type Sample media.Sample
 
+// ICECandidateStats describes one side of selected ICE candidate pair.
+type ICECandidateStats struct {
+	Type     string `json:"type"`     // host, srflx, prflx or relay
+	Protocol string `json:"protocol"` // udp or tcp
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+}
+
+// PeerStats is a summary of peer connection, it is collected periodically.
+// Loss, jitter and RTT are taken from receiver reports of video sent by client,
+// audio loss from receiver reports of audio.
+type PeerStats struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+	// Video is ID of video ladder entry, that peer receives.
+	Video string `json:"video,omitempty"`
+
+	RTT          float64 `json:"rtt"`    // ms
+	Jitter       float64 `json:"jitter"` // ms
+	PacketsLost  uint32  `json:"packets_lost"`
+	FractionLost float64 `json:"fraction_lost"`
+
+	AudioPacketsLost  uint32  `json:"audio_packets_lost"`
+	AudioFractionLost float64 `json:"audio_fraction_lost"`
+
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+	Bitrate       uint64 `json:"bitrate"`           // bit/s sent since previous summary
+	Estimated     int    `json:"estimated_bitrate"` // bit/s, zero if unknown
+
+	Local   *ICECandidateStats `json:"local,omitempty"`
+	Remote  *ICECandidateStats `json:"remote,omitempty"`
+	Relayed bool               `json:"relayed"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type WebRTCManager interface {
 	Start()
 	Shutdown() error
 	CreatePeer(id string, session Session) (Peer, error)
 	ICELite() bool
-	ICEServers() []webrtc.ICEServer
+	ICEServers(id string) []webrtc.ICEServer
 	ImplicitControl() bool
+	MultiControl() bool
+	PeerStats() []PeerStats
 }
 
 type Peer interface {
-	CreateOffer() (string, error)
+	CreateOffer(restart bool) (string, error)
 	CreateAnswer() (string, error)
 	SetOffer(sdp string) error
 	SetAnswer(sdp string) error
 	WriteData(v interface{}) error
+	SetMediaEnabled(enabled bool) error
+	GetStats() PeerStats
 	Destroy() error
 }
@@ -1,10 +1,53 @@
 package types
 
 import (
+	"errors"
 	"net/http"
 	"time"
 )
 
+// SESSION_COOKIE holds token of a session created by login
+const SESSION_COOKIE = "NEKO_SESSION"
+
+var (
+	ErrMemberNotFound      = errors.New("member not found")
+	ErrMemberIsAdmin       = errors.New("member is an admin")
+	ErrMemberNoAddress     = errors.New("member has no remote address")
+	ErrMemberNoIdentity    = errors.New("member has no identity")
+	ErrMemberNoPermission  = errors.New("member does not have permission")
+	ErrUnknownResource     = errors.New("unknown lock resource")
+	ErrResourceLocked      = errors.New("resource already locked")
+	ErrResourceNotLocked   = errors.New("resource not locked")
+	ErrBroadcastMissingURL = errors.New("missing broadcast URL")
+	ErrBroadcastStarted    = errors.New("server is already broadcasting")
+	ErrBroadcastNotStarted = errors.New("server is not broadcasting")
+	ErrBanInvalid          = errors.New("invalid ban target or duration")
+	ErrBanNotFound         = errors.New("ban not found")
+	ErrMeetingBound        = errors.New("room is already bound to a meeting")
+	ErrMeetingNotBound     = errors.New("room is not bound to a meeting")
+)
+
+type BanOptions struct {
+	Reason string `json:"reason,omitempty"`
+	// Duration of the ban in seconds, zero means permanent.
+	Duration int `json:"duration,omitempty"`
+	// Identity bans authenticated identity of a member instead of its address.
+	Identity bool `json:"identity,omitempty"`
+}
+
+// Ban record of an address, address range or identity.
+type Ban struct {
+	Target    string     `json:"target"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy string     `json:"created_by"` // session ID (that banned it)
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (b Ban) Expired(now time.Time) bool {
+	return b.ExpiresAt != nil && now.After(*b.ExpiresAt)
+}
+
 type Stats struct {
 	Connections uint32    `json:"connections"`
 	Host        string    `json:"host"`
@@ -13,12 +56,15 @@ type Stats struct {
 	Banned map[string]string `json:"banned"` // IP -> session ID (that banned it)
 	Locked map[string]string `json:"locked"` // resource name -> session ID (that locked it)
 
+	Meeting string `json:"meeting,omitempty"` // zoom meeting UUID the room is bound to
+
 	ServerStartedAt time.Time  `json:"server_started_at"`
 	LastAdminLeftAt *time.Time `json:"last_admin_left_at"`
 	LastUserLeftAt  *time.Time `json:"last_user_left_at"`
 
 	ControlProtection bool `json:"control_protection"`
 	ImplicitControl   bool `json:"implicit_control"`
+	MultiControl      bool `json:"multi_control"`
 }
 
 type WebSocket interface {
@@ -27,11 +73,41 @@ type WebSocket interface {
 	Destroy() error
 }
 
+// RoomAdmin performs admin actions on behalf of a session ID, connected
+// members are notified by the same events as if it was done over websocket.
+type RoomAdmin interface {
+	Members() []*Member
+	Kick(id string, target string) error
+	Ban(id string, target string, options BanOptions) error
+	BanTarget(id string, target string, options BanOptions) error
+	Unban(id string, target string) error
+	Bans() []Ban
+	Mute(id string, target string) error
+	Unmute(id string, target string) error
+	Lock(id string, resource string) error
+	Unlock(id string, resource string) error
+	Give(id string, target string) error
+	SetPermissions(id string, target string, permissions Permissions) error
+	CreateInvite(id string, options InviteOptions) (string, *Invite, error)
+	Invites() []Invite
+	RevokeInvite(id string, invite string) error
+	Release(id string) error
+	SetScreenSize(id string, size ScreenSize) error
+	BroadcastStart(url string) error
+	BroadcastStop() error
+	PeerStats() []PeerStats
+	BindMeeting(id string, meeting string) error
+	UnbindMeeting(id string) error
+}
+
 type WebSocketHandler interface {
 	Start()
 	Shutdown() error
 	Upgrade(w http.ResponseWriter, r *http.Request) error
 	Stats() Stats
 	IsLocked(resource string) bool
-	IsAdmin(password string) (bool, error)
+	Authenticate(r *http.Request) (*User, error)
+	Login(r *http.Request, username string, password string) (token string, user *User, err error)
+	Logout(token string)
+	Admin() RoomAdmin
 }
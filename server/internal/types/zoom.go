@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+type ZoomTicket struct {
+	Nonce     string    `json:"jti"`
+	UserID    string    `json:"uid"`
+	MeetingID string    `json:"mid"`
+	Type      string    `json:"typ"`
+	Role      string    `json:"role"`
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+type ZoomMeeting struct {
+	ID     string `json:"id"`
+	UUID   string `json:"uuid"`
+	HostID string `json:"host_id"`
+	Topic  string `json:"topic"`
+}
+
+type ZoomParticipant struct {
+	ID              string `json:"id"`
+	UserID          string `json:"user_id"`
+	ParticipantUUID string `json:"participant_uuid"`
+	Name            string `json:"user_name"`
+	Email           string `json:"email"`
+}
+
+type ZoomManager interface {
+	Enabled() bool
+	VerifyTicket(ticket string) (*ZoomTicket, error)
+
+	// webhook events
+	OnMeetingStarted(listener func(meeting ZoomMeeting))
+	OnMeetingEnded(listener func(meeting ZoomMeeting))
+	OnParticipantJoined(listener func(meeting ZoomMeeting, participant ZoomParticipant))
+	OnParticipantLeft(listener func(meeting ZoomMeeting, participant ZoomParticipant))
+	OnAppDeauthorized(listener func(userID string))
+}
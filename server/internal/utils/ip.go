@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -39,13 +40,28 @@ func GetIP(serverUrl string) (string, error) {
 	return string(bytes.TrimSpace(buf)), nil
 }
 
+// GetHttpRequestIP returns address of the client without port. Headers set
+// by reverse proxy are trusted only in proxy mode, that is X-Real-Ip and
+// the address appended to X-Forwarded-For by the proxy.
 func GetHttpRequestIP(r *http.Request, proxy bool) string {
-	IPAddress := r.Header.Get("X-Real-Ip")
-	if IPAddress == "" {
-		IPAddress = r.Header.Get("X-Forwarded-For")
+	if proxy {
+		if address := strings.TrimSpace(r.Header.Get("X-Real-Ip")); address != "" {
+			return address
+		}
+
+		// entries are appended by every proxy, leading ones are sent by the
+		// client and can be spoofed, so only the last one is trusted
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		if address := strings.TrimSpace(forwarded[len(forwarded)-1]); address != "" {
+			return address
+		}
 	}
-	if IPAddress == "" || !proxy {
-		IPAddress = r.RemoteAddr
+
+	// works for IPv6 addresses in brackets as well
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	return IPAddress
+
+	return host
 }
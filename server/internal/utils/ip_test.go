@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetHttpRequestIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     bool
+		remote    string
+		realIP    string
+		forwarded []string
+		want      string
+	}{
+		{"remote", false, "192.0.2.1:1234", "", nil, "192.0.2.1"},
+		{"remote ipv6", false, "[2001:db8::1]:1234", "", nil, "2001:db8::1"},
+		{"headers without proxy", false, "192.0.2.1:1234", "198.51.100.1", []string{"198.51.100.2"}, "192.0.2.1"},
+		{"real ip", true, "192.0.2.1:1234", "198.51.100.1", []string{"198.51.100.2"}, "198.51.100.1"},
+		{"forwarded", true, "192.0.2.1:1234", "", []string{"198.51.100.2"}, "198.51.100.2"},
+		// client sends its own header, proxy appends the real address
+		{"spoofed forwarded", true, "192.0.2.1:1234", "", []string{"10.0.0.1, 198.51.100.2"}, "198.51.100.2"},
+		{"spoofed forwarded header", true, "192.0.2.1:1234", "", []string{"10.0.0.1", "198.51.100.2"}, "198.51.100.2"},
+		{"empty forwarded", true, "192.0.2.1:1234", "", []string{"10.0.0.1, "}, "192.0.2.1"},
+		{"no headers", true, "192.0.2.1:1234", "", nil, "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remote
+			if test.realIP != "" {
+				r.Header.Set("X-Real-Ip", test.realIP)
+			}
+			for _, forwarded := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+
+			if address := GetHttpRequestIP(r, test.proxy); address != test.want {
+				t.Fatalf("address = %q, want %q", address, test.want)
+			}
+		})
+	}
+}
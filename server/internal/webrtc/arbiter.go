@@ -0,0 +1,62 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// pointer position is sent to others at most this often
+const arbiterMoveInterval = time.Second / 30
+
+// arbiter serializes input in multi control mode. Session, that sent input,
+// holds a grab for a short window and input of others is ignored meanwhile.
+type arbiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	holder string
+	until  time.Time
+	// last time, when pointer position was sent
+	moved time.Time
+}
+
+func newArbiter(window time.Duration) *arbiter {
+	return &arbiter{
+		window: window,
+	}
+}
+
+// grab reports whether session can send input now and whether it took
+// the grab over from someone else. Any input takes a free grab, but only
+// discrete input (keys and buttons) extends the window, so that moving
+// the pointer does not keep others out.
+func (a *arbiter) grab(id string, discrete bool) (ok bool, changed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.holder != id && now.Before(a.until) {
+		return false, false
+	}
+
+	changed = a.holder != id
+	if changed || discrete {
+		a.until = now.Add(a.window)
+	}
+
+	a.holder = id
+	return true, changed
+}
+
+// move reports whether pointer position should be sent to others.
+func (a *arbiter) move() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(a.moved) < arbiterMoveInterval {
+		return false
+	}
+
+	a.moved = now
+	return true
+}
@@ -0,0 +1,49 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbiterMoveDoesNotExtend(t *testing.T) {
+	window := 50 * time.Millisecond
+	a := newArbiter(window)
+
+	if ok, changed := a.grab("a", false); !ok || !changed {
+		t.Fatalf("first grab = %v, %v, want taken", ok, changed)
+	}
+
+	if ok, _ := a.grab("b", true); ok {
+		t.Fatal("grab taken over within window")
+	}
+
+	// holder keeps moving past the window
+	deadline := time.Now().Add(2 * window)
+	for time.Now().Before(deadline) {
+		if ok, _ := a.grab("a", false); !ok {
+			t.Fatal("holder input ignored")
+		}
+		time.Sleep(window / 10)
+	}
+
+	if ok, changed := a.grab("b", false); !ok || !changed {
+		t.Fatalf("grab after window = %v, %v, want taken over", ok, changed)
+	}
+}
+
+func TestArbiterKeyExtends(t *testing.T) {
+	window := 50 * time.Millisecond
+	a := newArbiter(window)
+
+	deadline := time.Now().Add(2 * window)
+	for time.Now().Before(deadline) {
+		if ok, _ := a.grab("a", true); !ok {
+			t.Fatal("holder input ignored")
+		}
+		time.Sleep(window / 10)
+	}
+
+	if ok, _ := a.grab("b", false); ok {
+		t.Fatal("grab taken over while holder presses keys")
+	}
+}
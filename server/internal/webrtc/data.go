@@ -0,0 +1,297 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
+)
+
+// server to client opcodes have high bit set, so that they never collide
+// with client to server opcodes, payloads start with PayloadHeader as well
+const (
+	OP_CURSOR_POSITION = 0x81
+	OP_CURSOR_IMAGE    = 0x82
+	OP_KEYBOARD_LEDS   = 0x83
+	OP_CONTROL_ACK     = 0x84
+)
+
+const (
+	LED_CAPS_LOCK = 0x01
+	LED_NUM_LOCK  = 0x02
+)
+
+const (
+	ACK_OK         = 0x00
+	ACK_NO_CONTROL = 0x01
+	ACK_INPUT_BUSY = 0x02
+	ACK_FAILED     = 0x03
+)
+
+// cursor position is sent over websocket at most this often, it is only
+// a fallback for sessions without open data channel and would flood them
+const cursorFallbackInterval = time.Second / 15
+
+// data channel stops accepting messages above high mark and peer
+// is synchronized again, when buffered amount drops under low mark
+const (
+	dataBufferedHigh = 64 * 1024
+	dataBufferedLow  = 16 * 1024
+)
+
+type PayloadCursorPosition struct {
+	PayloadHeader
+	X uint16
+	Y uint16
+}
+
+// PayloadCursorImage is followed by PNG image, X and Y are its hotspot.
+type PayloadCursorImage struct {
+	PayloadHeader
+	Width  uint16
+	Height uint16
+	X      uint16
+	Y      uint16
+}
+
+type PayloadKeyboardLEDs struct {
+	PayloadHeader
+	LEDs uint8
+}
+
+// PayloadControlAck tells client, whether its key down was applied.
+type PayloadControlAck struct {
+	PayloadHeader
+	Op     uint8
+	Status uint8
+}
+
+func cursorPosition(x int, y int) PayloadCursorPosition {
+	return PayloadCursorPosition{
+		PayloadHeader: PayloadHeader{
+			Event:  OP_CURSOR_POSITION,
+			Length: 4,
+		},
+		X: uint16(x),
+		Y: uint16(y),
+	}
+}
+
+func cursorImage(image *types.CursorImage, png []byte) ([]byte, error) {
+	if len(png) > 0xFFFF-8 {
+		return nil, fmt.Errorf("cursor image too large: %d bytes", len(png))
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := binary.Write(buffer, binary.LittleEndian, PayloadCursorImage{
+		PayloadHeader: PayloadHeader{
+			Event:  OP_CURSOR_IMAGE,
+			Length: uint16(8 + len(png)),
+		},
+		Width:  image.Width,
+		Height: image.Height,
+		X:      image.Xhot,
+		Y:      image.Yhot,
+	}); err != nil {
+		return nil, err
+	}
+
+	buffer.Write(png)
+	return buffer.Bytes(), nil
+}
+
+// cursorPositionMessage is sent over websocket to sessions without data channel.
+func cursorPositionMessage(x int, y int) message.CursorPosition {
+	return message.CursorPosition{
+		Event: event.CURSOR_POSITION,
+		X:     x,
+		Y:     y,
+	}
+}
+
+// cursorImageMessage is sent over websocket to sessions without data channel.
+func cursorImageMessage(image *types.CursorImage, png []byte) message.CursorImage {
+	return message.CursorImage{
+		Event:  event.CURSOR_IMAGE,
+		URI:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		Width:  image.Width,
+		Height: image.Height,
+		X:      image.Xhot,
+		Y:      image.Yhot,
+	}
+}
+
+func keyboardLEDs(modifiers types.KeyboardModifiers) PayloadKeyboardLEDs {
+	var leds uint8
+	if modifiers.CapsLock != nil && *modifiers.CapsLock {
+		leds |= LED_CAPS_LOCK
+	}
+	if modifiers.NumLock != nil && *modifiers.NumLock {
+		leds |= LED_NUM_LOCK
+	}
+
+	return PayloadKeyboardLEDs{
+		PayloadHeader: PayloadHeader{
+			Event:  OP_KEYBOARD_LEDS,
+			Length: 1,
+		},
+		LEDs: leds,
+	}
+}
+
+func controlAckPayload(op uint8, status uint8) PayloadControlAck {
+	return PayloadControlAck{
+		PayloadHeader: PayloadHeader{
+			Event:  OP_CONTROL_ACK,
+			Length: 2,
+		},
+		Op:     op,
+		Status: status,
+	}
+}
+
+// encodeData returns message as it is sent over data channel, payloads
+// are encoded in little endian.
+func encodeData(v interface{}) ([]byte, error) {
+	if data, ok := v.([]byte); ok {
+		return data, nil
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.LittleEndian, v); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func canWatch(permissions types.Permissions) bool {
+	return permissions.CanWatch
+}
+
+func canControl(permissions types.Permissions) bool {
+	return permissions.CanControl
+}
+
+// sendCursorPosition tells members, that can watch, where is the cursor.
+// Members, whose data channel is not open yet, receive it over websocket.
+func (manager *WebRTCManager) sendCursorPosition(x int, y int) {
+	var fallback interface{}
+
+	manager.cursorMu.Lock()
+	manager.cursorX, manager.cursorY = x, y
+	if elapsed := time.Since(manager.cursorSentAt); elapsed >= cursorFallbackInterval {
+		manager.cursorSentAt = time.Now()
+		fallback = cursorPositionMessage(x, y)
+	} else if !manager.cursorPending {
+		manager.cursorPending = true
+		time.AfterFunc(cursorFallbackInterval-elapsed, manager.trailingCursorPosition)
+	}
+	manager.cursorMu.Unlock()
+
+	if err := manager.sessions.DataBroadcast(cursorPosition(x, y), fallback, canWatch, nil); err != nil {
+		manager.logger.Warn().Err(err).Msg("broadcasting cursor position has failed")
+	}
+}
+
+// trailingCursorPosition sends position, that was throttled, so that cursor
+// of members without data channel does not stay behind, when it stops.
+func (manager *WebRTCManager) trailingCursorPosition() {
+	manager.cursorMu.Lock()
+	x, y := manager.cursorX, manager.cursorY
+	manager.cursorPending = false
+	manager.cursorSentAt = time.Now()
+	manager.cursorMu.Unlock()
+
+	if err := manager.sessions.DataBroadcast(cursorPosition(x, y), cursorPositionMessage(x, y), canWatch, nil); err != nil {
+		manager.logger.Warn().Err(err).Msg("broadcasting cursor position has failed")
+	}
+}
+
+// sendCursorImage tells members, that can watch, how does the cursor look like.
+func (manager *WebRTCManager) sendCursorImage(image *types.CursorImage, png []byte) {
+	data, err := cursorImage(image, png)
+	if err != nil {
+		manager.logger.Warn().Err(err).Msg("unable to encode cursor image")
+		return
+	}
+
+	if err := manager.sessions.DataBroadcast(data, cursorImageMessage(image, png), canWatch, nil); err != nil {
+		manager.logger.Warn().Err(err).Msg("broadcasting cursor image has failed")
+	}
+}
+
+// sendKeyboardLEDs tells members, that can control, when lock keys on the
+// desktop changed, so that their local state can be synchronized.
+func (manager *WebRTCManager) sendKeyboardLEDs() {
+	payload := keyboardLEDs(manager.desktop.GetKeyboardModifiers())
+
+	manager.ledsMu.Lock()
+	if manager.leds == payload.LEDs {
+		manager.ledsMu.Unlock()
+		return
+	}
+
+	manager.leds = payload.LEDs
+	manager.ledsMu.Unlock()
+
+	if err := manager.sessions.DataBroadcast(payload, nil, canControl, nil); err != nil {
+		manager.logger.Warn().Err(err).Msg("broadcasting keyboard leds has failed")
+	}
+}
+
+// controlAck tells session, whether its key down was applied. Other input
+// is sent too often to be acknowledged.
+func (manager *WebRTCManager) controlAck(session types.Session, op uint8, status uint8) {
+	if op != OP_KEY_DOWN {
+		return
+	}
+
+	if err := session.SendData(controlAckPayload(op, status)); err != nil {
+		manager.logger.Debug().Err(err).Str("id", session.ID()).Msg("sending control ack has failed")
+	}
+}
+
+// dataSync sends current state to peer, when its data channel is opened
+// or when it was congested and messages were dropped.
+func (manager *WebRTCManager) dataSync(peer *Peer) {
+	session, ok := manager.sessions.Get(peer.id)
+	if !ok {
+		return
+	}
+
+	permissions := session.Permissions()
+
+	if cursor := manager.capture.Cursor(); permissions.CanWatch && cursor.Enabled() {
+		if image, png := cursor.Image(); image != nil {
+			data, err := cursorImage(image, png)
+			if err != nil {
+				manager.logger.Warn().Err(err).Msg("unable to encode cursor image")
+				return
+			}
+
+			if err := peer.WriteData(data); err != nil {
+				manager.logger.Debug().Err(err).Str("id", peer.id).Msg("sending cursor image has failed")
+				return
+			}
+		}
+
+		if err := peer.WriteData(cursorPosition(cursor.Position())); err != nil {
+			manager.logger.Debug().Err(err).Str("id", peer.id).Msg("sending cursor position has failed")
+			return
+		}
+	}
+
+	if permissions.CanControl {
+		if err := peer.WriteData(keyboardLEDs(manager.desktop.GetKeyboardModifiers())); err != nil {
+			manager.logger.Debug().Err(err).Str("id", peer.id).Msg("sending keyboard leds has failed")
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"bytes"
+	"testing"
+
+	"m1k1o/neko/internal/types"
+)
+
+// Bytes must match parsing in client/src/neko/base.ts onData: event is
+// uint8, length and all other fields are little endian uint16 or uint8.
+func TestDataGolden(t *testing.T) {
+	yes, no := true, false
+
+	image, err := cursorImage(&types.CursorImage{
+		Width:  0x20,
+		Height: 0x0130,
+		Xhot:   3,
+		Yhot:   4,
+	}, []byte("PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    []byte
+	}{
+		{
+			name:    "cursor position",
+			payload: cursorPosition(0x1234, 0x0506),
+			want:    []byte{0x81, 0x04, 0x00, 0x34, 0x12, 0x06, 0x05},
+		},
+		{
+			name:    "cursor image",
+			payload: image,
+			want:    []byte{0x82, 0x0b, 0x00, 0x20, 0x00, 0x30, 0x01, 0x03, 0x00, 0x04, 0x00, 'P', 'N', 'G'},
+		},
+		{
+			name:    "keyboard leds",
+			payload: keyboardLEDs(types.KeyboardModifiers{CapsLock: &yes, NumLock: &yes}),
+			want:    []byte{0x83, 0x01, 0x00, 0x03},
+		},
+		{
+			name:    "keyboard leds num lock",
+			payload: keyboardLEDs(types.KeyboardModifiers{CapsLock: &no, NumLock: &yes}),
+			want:    []byte{0x83, 0x01, 0x00, 0x02},
+		},
+		{
+			name:    "keyboard leds unknown",
+			payload: keyboardLEDs(types.KeyboardModifiers{}),
+			want:    []byte{0x83, 0x01, 0x00, 0x00},
+		},
+		{
+			name:    "control ack",
+			payload: controlAckPayload(OP_KEY_DOWN, ACK_INPUT_BUSY),
+			want:    []byte{0x84, 0x02, 0x00, 0x03, 0x02},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := encodeData(test.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(data, test.want) {
+				t.Fatalf("data = % x, want % x", data, test.want)
+			}
+
+			// length in header is the number of bytes, that follow it
+			if length := int(data[1]) | int(data[2])<<8; length != len(data)-3 {
+				t.Fatalf("length = %d, want %d", length, len(data)-3)
+			}
+		})
+	}
+}
+
+func TestCursorImageTooLarge(t *testing.T) {
+	if _, err := cursorImage(&types.CursorImage{}, make([]byte, 0xFFFF-7)); err == nil {
+		t.Fatal("cursor image longer than length field accepted")
+	}
+
+	if _, err := cursorImage(&types.CursorImage{}, make([]byte, 0xFFFF-8)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"strconv"
 
 	"github.com/pion/webrtc/v3"
+
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
 )
 
 const (
@@ -39,10 +42,6 @@ type PayloadKey struct {
 }
 
 func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) error {
-	if (!manager.config.ImplicitControl && !manager.sessions.IsHost(id)) || (manager.config.ImplicitControl && !manager.sessions.CanControl(id)) {
-		return nil
-	}
-
 	buffer := bytes.NewBuffer(msg.Data)
 	header := &PayloadHeader{}
 	hbytes := make([]byte, 3)
@@ -55,6 +54,32 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 		return err
 	}
 
+	session, ok := manager.sessions.Get(id)
+	if !ok {
+		return nil
+	}
+
+	if !session.Permissions().CanControl || (!manager.config.ImplicitControl && !manager.sessions.IsHost(id)) || (manager.config.ImplicitControl && !manager.sessions.CanControl(id)) {
+		manager.controlAck(session, header.Event, ACK_NO_CONTROL)
+		return nil
+	}
+
+	// host is released when inactive
+	manager.sessions.ControlActivity(id)
+
+	// releasing keys is always allowed, so that nothing stays pressed
+	if manager.config.MultiControl && header.Event != OP_KEY_UP {
+		ok, changed := manager.arbiter.grab(id, header.Event == OP_KEY_DOWN)
+		if !ok {
+			manager.controlAck(session, header.Event, ACK_INPUT_BUSY)
+			return nil
+		}
+
+		if changed {
+			manager.inputChanged(id)
+		}
+	}
+
 	buffer = bytes.NewBuffer(msg.Data)
 
 	switch header.Event {
@@ -65,6 +90,12 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 		}
 
 		manager.desktop.Move(int(payload.X), int(payload.Y))
+
+		// others see, where the member using the cursor points, even when
+		// cursor is captured in video
+		if manager.config.MultiControl && !manager.capture.Cursor().Enabled() && manager.arbiter.move() {
+			manager.sendCursorPosition(int(payload.X), int(payload.Y))
+		}
 	case OP_SCROLL:
 		payload := &PayloadScroll{}
 		if err := binary.Read(buffer, binary.LittleEndian, payload); err != nil {
@@ -88,6 +119,7 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 			err := manager.desktop.ButtonDown(uint32(payload.Key))
 			if err != nil {
 				manager.logger.Warn().Err(err).Msg("button down failed")
+				manager.controlAck(session, header.Event, ACK_FAILED)
 				return nil
 			}
 
@@ -96,11 +128,15 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 			err := manager.desktop.KeyDown(uint32(payload.Key))
 			if err != nil {
 				manager.logger.Warn().Err(err).Msg("key down failed")
+				manager.controlAck(session, header.Event, ACK_FAILED)
 				return nil
 			}
 
 			manager.logger.Debug().Msgf("key down %d", payload.Key)
+			manager.sendKeyboardLEDs()
 		}
+
+		manager.controlAck(session, header.Event, ACK_OK)
 	case OP_KEY_UP:
 		payload := &PayloadKey{}
 		err := binary.Read(buffer, binary.LittleEndian, payload)
@@ -124,6 +160,7 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 			}
 
 			manager.logger.Debug().Msgf("key up %d", payload.Key)
+			manager.sendKeyboardLEDs()
 		}
 	case OP_KEY_CLK:
 		// unused
@@ -132,3 +169,17 @@ func (manager *WebRTCManager) handle(id string, msg webrtc.DataChannelMessage) e
 
 	return nil
 }
+
+// inputChanged is called when another member took over input in multi
+// control mode, keys held by previous one are released.
+func (manager *WebRTCManager) inputChanged(id string) {
+	manager.desktop.ResetKeys()
+
+	if err := manager.sessions.Broadcast(
+		message.Control{
+			Event: event.CONTROL_INPUT,
+			ID:    id,
+		}, nil); err != nil {
+		manager.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_INPUT)
+	}
+}
@@ -0,0 +1,107 @@
+package webrtc
+
+import (
+	"time"
+)
+
+// peers are switched between video ladder entries based on estimated
+// bandwidth, downgrade happens quickly and upgrade only after estimate
+// has been high enough for a while, so that quality does not flap
+const (
+	ladderDowngradeRatio = 0.85
+	ladderDowngradeDelay = 2 * time.Second
+	ladderUpgradeRatio   = 1.3
+	ladderUpgradeDelay   = 10 * time.Second
+)
+
+// ladder switches peer between video ladder entries based on its estimated
+// bandwidth in bit/s. It returns false, while peer waits for downgrade.
+// Mutex must be held.
+func (peer *Peer) ladder(bitrate int) bool {
+	videos := peer.manager.videos
+	if len(videos) < 2 || peer.video < 0 {
+		return true
+	}
+
+	now := time.Now()
+	current := float64(videos[peer.video].Bitrate() * 1000)
+
+	// downgrade to the highest entry, that fits in estimate
+	if float64(bitrate) < current*ladderDowngradeRatio {
+		peer.headroomSince = time.Time{}
+
+		if peer.video == len(videos)-1 {
+			return true
+		}
+
+		if now.Sub(peer.videoSwitched) < ladderDowngradeDelay {
+			return false
+		}
+
+		target := len(videos) - 1
+		for i := peer.video + 1; i < len(videos); i++ {
+			if uint(bitrate) >= videos[i].Bitrate()*1000 {
+				target = i
+				break
+			}
+		}
+
+		peer.switchVideo(target)
+		return true
+	}
+
+	// upgrade by one entry, when there is enough headroom for a while
+	if peer.video == 0 || float64(bitrate) < current*ladderUpgradeRatio {
+		peer.headroomSince = time.Time{}
+		return true
+	}
+
+	if peer.headroomSince.IsZero() {
+		peer.headroomSince = now
+		return true
+	}
+
+	if now.Sub(peer.headroomSince) < ladderUpgradeDelay || now.Sub(peer.videoSwitched) < ladderUpgradeDelay {
+		return true
+	}
+
+	peer.headroomSince = time.Time{}
+	peer.switchVideo(peer.video - 1)
+	return true
+}
+
+// switchVideo moves peer to another ladder entry, its pipeline is started
+// if needed and the previous one is stopped, if it was the last listener.
+// Mutex must be held.
+func (peer *Peer) switchVideo(index int) {
+	prev, next := peer.manager.videos[peer.video], peer.manager.videos[index]
+
+	if err := next.AddListener(); err != nil {
+		peer.manager.logger.Warn().Err(err).Str("id", peer.id).Str("video_id", next.ID()).Msg("unable to start video")
+		return
+	}
+
+	if peer.started && peer.mediaEnabled {
+		if err := peer.videoSender.ReplaceTrack(peer.manager.videoTracks[index]); err != nil {
+			peer.manager.logger.Warn().Err(err).Str("id", peer.id).Str("video_id", next.ID()).Msg("unable to switch video")
+			next.RemoveListener()
+			return
+		}
+	}
+
+	prev.RemoveListener()
+
+	// running encoder would send keyframe only after keyframe-max-dist
+	if err := next.ForceKeyUnit(); err != nil {
+		peer.manager.logger.Warn().Err(err).Str("video_id", next.ID()).Msg("unable to force key unit")
+	}
+
+	peer.video = index
+	peer.videoSwitched = time.Now()
+
+	peer.manager.logger.Info().
+		Str("id", peer.id).
+		Str("from", prev.ID()).
+		Str("to", next.ID()).
+		Msg("switched video")
+}
@@ -0,0 +1,151 @@
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/codec"
+)
+
+// fakeVideo is video ladder entry, that counts its listeners.
+type fakeVideo struct {
+	id        string
+	bitrate   uint
+	listeners int
+	keyUnits  int
+	encoderMu sync.Mutex
+	encoder   []uint
+}
+
+func (v *fakeVideo) ID() string                                  { return v.id }
+func (v *fakeVideo) Bitrate() uint                               { return v.bitrate }
+func (v *fakeVideo) Codec() codec.RTPCodec                       { return codec.VP8() }
+func (v *fakeVideo) OnSample(listener func(sample types.Sample)) {}
+func (v *fakeVideo) AddListener() error                          { v.listeners++; return nil }
+func (v *fakeVideo) RemoveListener() error                       { v.listeners--; return nil }
+func (v *fakeVideo) ForceKeyUnit() error                         { v.keyUnits++; return nil }
+func (v *fakeVideo) SetEncoderBitrate(bitrate uint) error {
+	v.encoderMu.Lock()
+	defer v.encoderMu.Unlock()
+
+	v.encoder = append(v.encoder, bitrate)
+	return nil
+}
+
+func (v *fakeVideo) encoderBitrates() []uint {
+	v.encoderMu.Lock()
+	defer v.encoderMu.Unlock()
+
+	return append([]uint{}, v.encoder...)
+}
+func (v *fakeVideo) ListenersCount() int { return v.listeners }
+func (v *fakeVideo) Started() bool       { return v.listeners > 0 }
+
+// newLadderPeer returns peer watching the first entry of ladder with given
+// bitrates in kbit/s, that was switched to it long ago.
+func newLadderPeer(bitrates ...uint) (*Peer, []*fakeVideo) {
+	videos := make([]*fakeVideo, len(bitrates))
+	manager := &WebRTCManager{logger: zerolog.Nop()}
+	for i, bitrate := range bitrates {
+		videos[i] = &fakeVideo{id: string(rune('a' + i)), bitrate: bitrate}
+		manager.videos = append(manager.videos, videos[i])
+	}
+
+	videos[0].listeners = 1
+	return &Peer{
+		id:            "peer",
+		manager:       manager,
+		videoSwitched: time.Now().Add(-time.Hour),
+	}, videos
+}
+
+func TestLadderDowngrade(t *testing.T) {
+	peer, videos := newLadderPeer(3000, 1500, 500)
+
+	// estimate within downgrade ratio keeps current entry
+	if !peer.ladder(2_700_000) || peer.video != 0 {
+		t.Fatalf("video = %d, want 0", peer.video)
+	}
+
+	// downgrade skips entries, that do not fit either
+	if !peer.ladder(800_000) || peer.video != 2 {
+		t.Fatalf("video = %d, want 2", peer.video)
+	}
+
+	if videos[0].listeners != 0 || videos[2].listeners != 1 || videos[2].keyUnits != 1 {
+		t.Fatalf("listeners = %d, %d, key units = %d", videos[0].listeners, videos[2].listeners, videos[2].keyUnits)
+	}
+
+	// nothing lower, peer stays on the last entry
+	if !peer.ladder(100_000) || peer.video != 2 {
+		t.Fatalf("video = %d, want 2", peer.video)
+	}
+}
+
+func TestLadderDowngradeDelay(t *testing.T) {
+	peer, _ := newLadderPeer(3000, 1500, 500)
+	peer.videoSwitched = time.Now()
+
+	// recently switched peer waits, it is not settled
+	if peer.ladder(1_000_000) || peer.video != 0 {
+		t.Fatalf("video = %d, want 0 and unsettled", peer.video)
+	}
+
+	peer.videoSwitched = time.Now().Add(-ladderDowngradeDelay)
+	if !peer.ladder(1_000_000) || peer.video != 2 {
+		t.Fatalf("video = %d, want 2", peer.video)
+	}
+}
+
+func TestLadderUpgrade(t *testing.T) {
+	peer, videos := newLadderPeer(3000, 1500, 500)
+	peer.switchVideo(2)
+	peer.videoSwitched = time.Now().Add(-time.Hour)
+
+	// headroom is needed for a while
+	if !peer.ladder(5_000_000) || peer.video != 2 || peer.headroomSince.IsZero() {
+		t.Fatalf("video = %d, want 2 with headroom", peer.video)
+	}
+
+	// estimate dropped, headroom starts again
+	if !peer.ladder(600_000) || !peer.headroomSince.IsZero() {
+		t.Fatal("headroom is kept")
+	}
+
+	peer.ladder(5_000_000)
+	peer.headroomSince = time.Now().Add(-ladderUpgradeDelay)
+
+	// upgrade by one entry only
+	if !peer.ladder(5_000_000) || peer.video != 1 {
+		t.Fatalf("video = %d, want 1", peer.video)
+	}
+
+	if videos[2].listeners != 0 || videos[1].listeners != 1 {
+		t.Fatalf("listeners = %d, %d", videos[2].listeners, videos[1].listeners)
+	}
+
+	// next upgrade waits for delay after switch
+	peer.headroomSince = time.Now().Add(-ladderUpgradeDelay)
+	if peer.ladder(5_000_000); peer.video != 1 {
+		t.Fatalf("video = %d, want 1", peer.video)
+	}
+}
+
+func TestLadderSingle(t *testing.T) {
+	peer, _ := newLadderPeer(3000)
+
+	if !peer.ladder(100_000) || peer.video != 0 {
+		t.Fatalf("video = %d, want 0", peer.video)
+	}
+
+	// released peer is not switched
+	peer, _ = newLadderPeer(3000, 500)
+	peer.video = -1
+	if !peer.ladder(100_000) || peer.video != -1 {
+		t.Fatalf("video = %d, want -1", peer.video)
+	}
+}
@@ -2,19 +2,51 @@ package webrtc
 
 import (
 	"sync"
+	"time"
 
 	"github.com/pion/webrtc/v3"
+
+	"m1k1o/neko/internal/types"
 )
 
 type Peer struct {
-	id         string
-	mu         sync.Mutex
-	manager    *WebRTCManager
-	connection *webrtc.PeerConnection
+	id          string
+	mu          sync.Mutex
+	manager     *WebRTCManager
+	connection  *webrtc.PeerConnection
+	videoSender *webrtc.RTPSender
+	audioSender *webrtc.RTPSender
+	channel     *webrtc.DataChannel
+	// media are sent only if enabled, senders can be changed
+	// after they were started by setting remote answer
+	mediaEnabled bool
+	started      bool
+	// messages were dropped, because client did not keep up
+	dropped bool
+	// index of video ladder entry, -1 when released
+	video         int
+	videoSwitched time.Time
+	headroomSince time.Time
+	// last bandwidth estimate in bit/s, zero if unknown
+	estimated int
+	// whether estimate limits encoder, see estimate
+	settled     bool
+	videoSSRC   uint32
+	audioSSRC   uint32
+	report      receiverReport
+	audioReport receiverReport
+	stats       types.PeerStats
 }
 
-func (peer *Peer) CreateOffer() (string, error) {
-	desc, err := peer.connection.CreateOffer(nil)
+// CreateOffer creates local offer, with new ICE credentials if restart
+// is requested, so that peer can connect again after connection was lost.
+func (peer *Peer) CreateOffer(restart bool) (string, error) {
+	var options *webrtc.OfferOptions
+	if restart {
+		options = &webrtc.OfferOptions{ICERestart: true}
+	}
+
+	desc, err := peer.connection.CreateOffer(options)
 	if err != nil {
 		return "", err
 	}
@@ -46,16 +78,117 @@ func (peer *Peer) SetOffer(sdp string) error {
 }
 
 func (peer *Peer) SetAnswer(sdp string) error {
-	return peer.connection.SetRemoteDescription(webrtc.SessionDescription{SDP: sdp, Type: webrtc.SDPTypeAnswer})
+	err := peer.connection.SetRemoteDescription(webrtc.SessionDescription{SDP: sdp, Type: webrtc.SDPTypeAnswer})
+	if err != nil {
+		return err
+	}
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	peer.started = true
+	if peer.mediaEnabled {
+		return nil
+	}
+
+	return peer.replaceTracks()
+}
+
+// SetMediaEnabled starts or stops sending audio and video to the peer,
+// tracks are replaced so that no renegotiation is needed.
+func (peer *Peer) SetMediaEnabled(enabled bool) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.mediaEnabled == enabled {
+		return nil
+	}
+
+	peer.mediaEnabled = enabled
+	if !peer.started {
+		return nil
+	}
+
+	return peer.replaceTracks()
+}
+
+func (peer *Peer) replaceTracks() error {
+	var video, audio webrtc.TrackLocal
+	if peer.mediaEnabled && peer.video >= 0 {
+		video, audio = peer.manager.videoTracks[peer.video], peer.manager.audioTrack
+	}
+
+	if err := peer.videoSender.ReplaceTrack(video); err != nil {
+		return err
+	}
+
+	return peer.audioSender.ReplaceTrack(audio)
 }
 
+// WriteData sends binary payload to the client, v is either encoded payload
+// or a struct of fixed size fields. Messages are dropped while too much data
+// is buffered, peer is synchronized again when buffer drains.
 func (peer *Peer) WriteData(v interface{}) error {
+	if peer.channel.ReadyState() != webrtc.DataChannelStateOpen {
+		return types.ErrDataChannelNotOpen
+	}
+
+	if peer.channel.BufferedAmount() > dataBufferedHigh {
+		peer.mu.Lock()
+		peer.dropped = true
+		peer.mu.Unlock()
+
+		return types.ErrDataChannelBusy
+	}
+
+	data, err := encodeData(v)
+	if err != nil {
+		return err
+	}
+
+	return peer.channel.Send(data)
+}
+
+// GetStats returns last summary of peer connection, it is collected
+// right away if there is none yet.
+func (peer *Peer) GetStats() types.PeerStats {
+	peer.mu.Lock()
+	stats := peer.stats
+	peer.mu.Unlock()
+
+	if !stats.Timestamp.IsZero() {
+		return stats
+	}
+
+	peer.collectStats()
+
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
-	return nil
+
+	return peer.stats
+}
+
+func (peer *Peer) bufferedAmountLow() {
+	peer.mu.Lock()
+	dropped := peer.dropped
+	peer.dropped = false
+	peer.mu.Unlock()
+
+	if dropped {
+		peer.manager.dataSync(peer)
+	}
 }
 
 func (peer *Peer) Destroy() error {
+	peer.mu.Lock()
+	if peer.video >= 0 {
+		peer.manager.videos[peer.video].RemoveListener()
+		peer.video = -1
+	}
+	peer.mu.Unlock()
+
+	peer.manager.removePeer(peer)
+
 	if peer.connection != nil && peer.connection.ConnectionState() != webrtc.PeerConnectionStateClosed {
 		if err := peer.connection.Close(); err != nil {
 			return err
@@ -0,0 +1,154 @@
+package webrtc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"m1k1o/neko/internal/types"
+)
+
+// encoder bitrate is changed in steps of its nominal bitrate, so that it
+// is not reconfigured on every estimate, and it is never lowered below
+// minimal step
+const (
+	bitrateSteps    = 20
+	bitrateMinSteps = 2
+)
+
+// estimates come with feedback of every peer, encoder bitrates are adjusted
+// at most this often and estimates within the interval are merged into one
+// adjustment at its end
+const bitrateAdjustInterval = time.Second
+
+func (peer *Peer) handleRTCP(packets []rtcp.Packet) {
+	for _, packet := range packets {
+		switch p := packet.(type) {
+		case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+			peer.keyUnit()
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			peer.estimate(int(p.Bitrate))
+		case *rtcp.ReceiverReport:
+			peer.receiverReport(p)
+		}
+	}
+}
+
+// keyUnit forces keyframe on encoder, that peer currently receives.
+func (peer *Peer) keyUnit() {
+	peer.mu.Lock()
+	video := peer.video
+	peer.mu.Unlock()
+
+	if video < 0 {
+		return
+	}
+
+	err := peer.manager.videos[video].ForceKeyUnit()
+	if err != nil && !errors.Is(err, types.ErrCapturePipelineNotRunning) {
+		peer.manager.logger.Warn().Err(err).Str("id", peer.id).Msg("unable to force key unit")
+	}
+}
+
+// estimate is called with bandwidth estimated from REMB or TWCC in bit/s.
+// It limits encoder bitrate only after ladder decided, that peer stays on
+// its entry, peer waiting for downgrade does not slow down others.
+func (peer *Peer) estimate(bitrate int) {
+	peer.mu.Lock()
+	peer.estimated = bitrate
+	peer.settled = peer.ladder(bitrate)
+	peer.mu.Unlock()
+
+	peer.manager.adjustBitrates()
+}
+
+// adjustBitrates lowers bitrate of each running encoder to the lowest
+// estimate of peers settled on it, so that congested links do not freeze.
+func (manager *WebRTCManager) adjustBitrates() {
+	manager.bitratesMu.Lock()
+	defer manager.bitratesMu.Unlock()
+
+	if elapsed := time.Since(manager.bitratesAdjusted); elapsed < bitrateAdjustInterval {
+		if !manager.bitratesPending {
+			manager.bitratesPending = true
+			time.AfterFunc(bitrateAdjustInterval-elapsed, manager.trailingBitrates)
+		}
+		return
+	}
+
+	manager.setBitrates()
+}
+
+// trailingBitrates applies estimates, that came while throttled.
+func (manager *WebRTCManager) trailingBitrates() {
+	manager.bitratesMu.Lock()
+	defer manager.bitratesMu.Unlock()
+
+	manager.bitratesPending = false
+	manager.setBitrates()
+}
+
+// setBitrates changes encoder bitrate only when it differs from the one set
+// before. Bitrates mutex must be held.
+func (manager *WebRTCManager) setBitrates() {
+	manager.bitratesAdjusted = time.Now()
+	lowest := make([]int, len(manager.videos))
+
+	manager.peersMu.Lock()
+	for _, peer := range manager.peers {
+		peer.mu.Lock()
+		video, estimated, settled := peer.video, peer.estimated, peer.settled
+		peer.mu.Unlock()
+
+		if video < 0 || estimated == 0 || !settled {
+			continue
+		}
+
+		if lowest[video] == 0 || estimated < lowest[video] {
+			lowest[video] = estimated
+		}
+	}
+	manager.peersMu.Unlock()
+
+	for i, video := range manager.videos {
+		// stopped encoder starts with nominal bitrate
+		if !video.Started() {
+			manager.bitrates[i] = 0
+			continue
+		}
+
+		bitrate := video.Bitrate()
+		if lowest[i] > 0 {
+			steps := uint(lowest[i]) * bitrateSteps / (bitrate * 1000)
+			if steps < bitrateMinSteps {
+				steps = bitrateMinSteps
+			}
+
+			if steps < bitrateSteps {
+				bitrate = bitrate * steps / bitrateSteps
+			}
+		}
+
+		if manager.bitrates[i] == bitrate {
+			continue
+		}
+
+		err := video.SetEncoderBitrate(bitrate)
+		if err == nil || errors.Is(err, types.ErrCapturePipelineBitrateUnsupported) {
+			// unsupported encoder is not asked again, until bitrate changes
+			manager.bitrates[i] = bitrate
+		} else if !errors.Is(err, types.ErrCapturePipelineNotRunning) {
+			manager.logger.Warn().Err(err).Str("video_id", video.ID()).Msg("unable to change encoder bitrate")
+		}
+	}
+}
+
+func (manager *WebRTCManager) removePeer(peer *Peer) {
+	manager.peersMu.Lock()
+	defer manager.peersMu.Unlock()
+
+	if manager.peers[peer.id] == peer {
+		delete(manager.peers, peer.id)
+	}
+}
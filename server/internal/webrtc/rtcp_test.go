@@ -0,0 +1,64 @@
+package webrtc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAdjustBitrates(t *testing.T) {
+	peer, videos := newLadderPeer(3000)
+	peer.estimated = 1_500_000
+	peer.settled = true
+
+	manager := peer.manager
+	manager.peers = map[string]*Peer{peer.id: peer}
+	manager.bitrates = make([]uint, len(manager.videos))
+
+	manager.adjustBitrates()
+	if got := videos[0].encoderBitrates(); !reflect.DeepEqual(got, []uint{1500}) {
+		t.Fatalf("encoder bitrates = %v, want [1500]", got)
+	}
+
+	// estimates within interval are applied at its end
+	peer.mu.Lock()
+	peer.estimated = 900_000
+	peer.mu.Unlock()
+
+	manager.adjustBitrates()
+	manager.adjustBitrates()
+	if got := videos[0].encoderBitrates(); len(got) != 1 {
+		t.Fatalf("encoder bitrates = %v, want throttled", got)
+	}
+
+	deadline := time.Now().Add(bitrateAdjustInterval + 5*time.Second)
+	for len(videos[0].encoderBitrates()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := videos[0].encoderBitrates(); !reflect.DeepEqual(got, []uint{1500, 900}) {
+		t.Fatalf("encoder bitrates = %v, want [1500 900]", got)
+	}
+
+	// encoder is not changed, when bitrate stays the same
+	manager.bitratesMu.Lock()
+	manager.bitratesAdjusted = time.Now().Add(-bitrateAdjustInterval)
+	manager.bitratesMu.Unlock()
+
+	manager.adjustBitrates()
+	if got := videos[0].encoderBitrates(); len(got) != 2 {
+		t.Fatalf("encoder bitrates = %v, want unchanged", got)
+	}
+
+	// stopped encoder is set again, once it runs
+	videos[0].listeners = 0
+	manager.bitratesMu.Lock()
+	manager.setBitrates()
+	videos[0].listeners = 1
+	manager.setBitrates()
+	manager.bitratesMu.Unlock()
+
+	if got := videos[0].encoderBitrates(); !reflect.DeepEqual(got, []uint{1500, 900, 900}) {
+		t.Fatalf("encoder bitrates = %v, want [1500 900 900]", got)
+	}
+}
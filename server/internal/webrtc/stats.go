@@ -0,0 +1,165 @@
+package webrtc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+
+	"m1k1o/neko/internal/types"
+)
+
+// summaries of peer connections are collected periodically, so that
+// requesting them does not wait for every peer connection
+const statsInterval = 5 * time.Second
+
+const (
+	// seconds between NTP epoch (1900) and unix epoch (1970)
+	ntpEpochOffset = 2208988800
+	// video is always sent with 90kHz clock rate
+	videoClockRate = 90000
+)
+
+// receiverReport is the last reception report of video or audio sent by client.
+type receiverReport struct {
+	rtt          time.Duration
+	jitter       uint32
+	packetsLost  uint32
+	fractionLost uint8
+}
+
+// ntpMiddle returns middle 32 bits of NTP timestamp, as used in LSR and DLSR.
+func ntpMiddle(t time.Time) uint32 {
+	secs := uint64(t.Unix()) + ntpEpochOffset
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return uint32((secs<<32 | frac) >> 16)
+}
+
+func candidateStats(candidate *webrtc.ICECandidate) *types.ICECandidateStats {
+	if candidate == nil {
+		return nil
+	}
+
+	return &types.ICECandidateStats{
+		Type:     candidate.Typ.String(),
+		Protocol: candidate.Protocol.String(),
+		Address:  candidate.Address,
+		Port:     candidate.Port,
+	}
+}
+
+func (peer *Peer) receiverReport(report *rtcp.ReceiverReport) {
+	now := ntpMiddle(time.Now())
+
+	for _, r := range report.Reports {
+		peer.mu.Lock()
+
+		var last *receiverReport
+		switch r.SSRC {
+		case peer.videoSSRC:
+			last = &peer.report
+		case peer.audioSSRC:
+			last = &peer.audioReport
+		}
+
+		if last != nil {
+			last.jitter = r.Jitter
+			last.packetsLost = r.TotalLost
+			last.fractionLost = r.FractionLost
+
+			// round trip is known only after client received sender report
+			if r.LastSenderReport != 0 {
+				rtt := now - r.LastSenderReport - r.Delay
+				last.rtt = time.Duration(rtt) * time.Second / 65536
+			}
+		}
+
+		peer.mu.Unlock()
+	}
+}
+
+// collectStats summarizes current state of peer connection.
+func (peer *Peer) collectStats() {
+	now := time.Now()
+
+	stats := types.PeerStats{
+		ID:        peer.id,
+		State:     peer.connection.ICEConnectionState().String(),
+		Timestamp: now,
+	}
+
+	if transport, ok := peer.connection.GetStats()["iceTransport"].(webrtc.TransportStats); ok {
+		stats.BytesSent = transport.BytesSent
+		stats.BytesReceived = transport.BytesReceived
+	}
+
+	pair, err := peer.connection.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil {
+		peer.manager.logger.Debug().Err(err).Str("id", peer.id).Msg("unable to get selected candidate pair")
+	}
+
+	if pair != nil {
+		stats.Local = candidateStats(pair.Local)
+		stats.Remote = candidateStats(pair.Remote)
+		stats.Relayed = (pair.Local != nil && pair.Local.Typ == webrtc.ICECandidateTypeRelay) ||
+			(pair.Remote != nil && pair.Remote.Typ == webrtc.ICECandidateTypeRelay)
+	}
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.video >= 0 {
+		stats.Video = peer.manager.videos[peer.video].ID()
+	}
+
+	stats.RTT = float64(peer.report.rtt) / float64(time.Millisecond)
+	stats.Jitter = float64(peer.report.jitter) * 1000 / videoClockRate
+	stats.PacketsLost = peer.report.packetsLost
+	stats.FractionLost = float64(peer.report.fractionLost) / 256
+	stats.AudioPacketsLost = peer.audioReport.packetsLost
+	stats.AudioFractionLost = float64(peer.audioReport.fractionLost) / 256
+	stats.Estimated = peer.estimated
+
+	if prev := peer.stats; !prev.Timestamp.IsZero() && stats.BytesSent >= prev.BytesSent {
+		if elapsed := now.Sub(prev.Timestamp).Seconds(); elapsed > 0 {
+			stats.Bitrate = uint64(float64(stats.BytesSent-prev.BytesSent) * 8 / elapsed)
+		}
+	}
+
+	peer.stats = stats
+}
+
+// collectStats updates summaries of all peers.
+func (manager *WebRTCManager) collectStats() {
+	for _, peer := range manager.allPeers() {
+		peer.collectStats()
+	}
+}
+
+// PeerStats returns last summaries of all peers.
+func (manager *WebRTCManager) PeerStats() []types.PeerStats {
+	peers := manager.allPeers()
+	stats := make([]types.PeerStats, 0, len(peers))
+	for _, peer := range peers {
+		stats = append(stats, peer.GetStats())
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ID < stats[j].ID
+	})
+
+	return stats
+}
+
+func (manager *WebRTCManager) allPeers() []*Peer {
+	manager.peersMu.Lock()
+	defer manager.peersMu.Unlock()
+
+	peers := make([]*Peer, 0, len(manager.peers))
+	for _, peer := range manager.peers {
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
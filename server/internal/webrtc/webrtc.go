@@ -7,9 +7,12 @@ import (
 	"io"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/rs/zerolog"
@@ -20,25 +23,55 @@ import (
 	"m1k1o/neko/internal/webrtc/pionlog"
 )
 
-func New(sessions types.SessionManager, capture types.CaptureManager, desktop types.DesktopManager, config *config.WebRTC) *WebRTCManager {
+func New(sessions types.SessionManager, capture types.CaptureManager, desktop types.DesktopManager, turn types.TURNManager, config *config.WebRTC) *WebRTCManager {
 	return &WebRTCManager{
 		logger:   log.With().Str("module", "webrtc").Logger(),
 		capture:  capture,
 		desktop:  desktop,
+		turn:     turn,
 		sessions: sessions,
 		config:   config,
+		arbiter:  newArbiter(config.ControlGrabWindow),
+		peers:    map[string]*Peer{},
+		done:     make(chan struct{}),
 	}
 }
 
 type WebRTCManager struct {
 	logger     zerolog.Logger
-	videoTrack *webrtc.TrackLocalStaticSample
 	audioTrack *webrtc.TrackLocalStaticSample
 	sessions   types.SessionManager
 	capture    types.CaptureManager
 	desktop    types.DesktopManager
+	turn       types.TURNManager
 	config     *config.WebRTC
 	api        *webrtc.API
+	arbiter    *arbiter
+	// video ladder, each entry has its own track
+	videos      []types.StreamSinkManager
+	videoTracks []*webrtc.TrackLocalStaticSample
+	// bandwidth estimator of peer connection, that is being created
+	estimatorMu sync.Mutex
+	estimator   cc.BandwidthEstimator
+	peersMu     sync.Mutex
+	peers       map[string]*Peer
+	// last keyboard leds sent to clients
+	ledsMu sync.Mutex
+	leds   uint8
+	// encoder bitrates set from estimates, zero when not known
+	bitratesMu       sync.Mutex
+	bitrates         []uint
+	bitratesAdjusted time.Time
+	bitratesPending  bool
+	// last cursor position and when it was sent over websocket
+	cursorMu      sync.Mutex
+	cursorX       int
+	cursorY       int
+	cursorSentAt  time.Time
+	cursorPending bool
+
+	wg   sync.WaitGroup
+	done chan struct{}
 }
 
 func (manager *WebRTCManager) Start() {
@@ -65,19 +98,76 @@ func (manager *WebRTCManager) Start() {
 	// video
 	//
 
-	videoCodec := manager.capture.Video().Codec()
-	manager.videoTrack, err = webrtc.NewTrackLocalStaticSample(videoCodec.Capability, "video", "stream")
-	if err != nil {
-		manager.logger.Panic().Err(err).Msg("unable to create video track")
+	manager.videos = manager.capture.VideoLadder()
+	manager.videoTracks = make([]*webrtc.TrackLocalStaticSample, len(manager.videos))
+
+	for i, video := range manager.videos {
+		videoTrack, err := webrtc.NewTrackLocalStaticSample(video.Codec().Capability, "video", "stream")
+		if err != nil {
+			manager.logger.Panic().Err(err).Str("video_id", video.ID()).Msg("unable to create video track")
+		}
+
+		video.OnSample(func(sample types.Sample) {
+			err := videoTrack.WriteSample(media.Sample(sample))
+			if err != nil && errors.Is(err, io.ErrClosedPipe) {
+				manager.logger.Warn().Err(err).Msg("video pipeline failed to write")
+			}
+		})
+
+		manager.videoTracks[i] = videoTrack
 	}
 
-	manager.capture.Video().OnSample(func(sample types.Sample) {
-		err := manager.videoTrack.WriteSample(media.Sample(sample))
-		if err != nil && errors.Is(err, io.ErrClosedPipe) {
-			manager.logger.Warn().Err(err).Msg("video pipeline failed to write")
+	manager.bitrates = make([]uint, len(manager.videos))
+
+	// recreated encoders start with nominal bitrate
+	manager.desktop.OnAfterScreenSizeChange(func() {
+		manager.bitratesMu.Lock()
+		for i := range manager.bitrates {
+			manager.bitrates[i] = 0
 		}
+		manager.bitratesMu.Unlock()
+
+		manager.adjustBitrates()
 	})
 
+	//
+	// data
+	//
+
+	// cursor is sent to clients, when it is not captured in video
+	if cursor := manager.capture.Cursor(); cursor.Enabled() {
+		cursor.OnPosition(func(x int, y int) {
+			manager.sendCursorPosition(x, y)
+		})
+
+		cursor.OnImage(func(image *types.CursorImage, png []byte) {
+			manager.sendCursorImage(image, png)
+		})
+	}
+
+	manager.leds = keyboardLEDs(manager.desktop.GetKeyboardModifiers()).LEDs
+
+	//
+	// stats
+	//
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-manager.done:
+				return
+			case <-ticker.C:
+				manager.collectStats()
+			}
+		}
+	}()
+
 	//
 	// api
 	//
@@ -96,6 +186,9 @@ func (manager *WebRTCManager) Start() {
 
 func (manager *WebRTCManager) Shutdown() error {
 	manager.logger.Info().Msgf("webrtc shutting down")
+
+	close(manager.done)
+	manager.wg.Wait()
 	return nil
 }
 
@@ -162,6 +255,28 @@ func (manager *WebRTCManager) initAPI() error {
 
 	// Register Interceptors
 	i := &interceptor.Registry{}
+
+	// Estimate bandwidth from TWCC
+	congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		return gcc.NewSendSideBWE(
+			gcc.SendSideBWEInitialBitrate(int(manager.videos[0].Bitrate()*1000)),
+			gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	congestionController.OnNewPeerConnection(func(id string, estimator cc.BandwidthEstimator) {
+		manager.estimator = estimator
+	})
+
+	i.Add(congestionController)
+
+	if err := webrtc.ConfigureTWCCHeaderExtensionSender(&engine, i); err != nil {
+		return err
+	}
+
 	if err := webrtc.RegisterDefaultInterceptors(&engine, i); err != nil {
 		return err
 	}
@@ -185,26 +300,31 @@ func (manager *WebRTCManager) CreatePeer(id string, session types.Session) (type
 		configuration.ICEServers = manager.config.ICEServers
 	}
 
-	// Create new peer connection
+	// Create new peer connection, estimator is created along with it
+	manager.estimatorMu.Lock()
 	connection, err := manager.api.NewPeerConnection(configuration)
+	estimator := manager.estimator
+	manager.estimator = nil
+	manager.estimatorMu.Unlock()
+
 	if err != nil {
 		return nil, err
 	}
 
-	negotiated := true
-	_, err = connection.CreateDataChannel("data", &webrtc.DataChannelInit{
+	// channel is negotiated with the same id by client
+	negotiated, channelID := true, uint16(0)
+	channel, err := connection.CreateDataChannel("data", &webrtc.DataChannelInit{
 		Negotiated: &negotiated,
+		ID:         &channelID,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	connection.OnDataChannel(func(d *webrtc.DataChannel) {
-		d.OnMessage(func(msg webrtc.DataChannelMessage) {
-			if err = manager.handle(id, msg); err != nil {
-				manager.logger.Warn().Err(err).Msg("data handle failed")
-			}
-		})
+	channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if err := manager.handle(id, msg); err != nil {
+			manager.logger.Warn().Err(err).Msg("data handle failed")
+		}
 	})
 
 	// Set the handler for ICE connection state
@@ -215,7 +335,8 @@ func (manager *WebRTCManager) CreatePeer(id string, session types.Session) (type
 			Msg("connection state has changed")
 	})
 
-	rtpVideo, err := connection.AddTrack(manager.videoTrack)
+	// start with the highest quality
+	rtpVideo, err := connection.AddTrack(manager.videoTracks[0])
 	if err != nil {
 		return nil, err
 	}
@@ -229,10 +350,10 @@ func (manager *WebRTCManager) CreatePeer(id string, session types.Session) (type
 		switch state {
 		case webrtc.PeerConnectionStateDisconnected:
 			manager.logger.Info().Str("id", id).Msg("peer disconnected")
-			manager.sessions.Destroy(id)
+			manager.restartPeer(id, session)
 		case webrtc.PeerConnectionStateFailed:
 			manager.logger.Warn().Str("id", id).Msg("peer failed")
-			manager.sessions.Destroy(id)
+			manager.restartPeer(id, session)
 		case webrtc.PeerConnectionStateClosed:
 			manager.logger.Info().Str("id", id).Msg("peer closed")
 			manager.sessions.Destroy(id)
@@ -246,15 +367,43 @@ func (manager *WebRTCManager) CreatePeer(id string, session types.Session) (type
 	})
 
 	peer := &Peer{
-		id:         id,
-		manager:    manager,
-		connection: connection,
+		id:           id,
+		manager:      manager,
+		connection:   connection,
+		videoSender:  rtpVideo,
+		audioSender:  rtpAudio,
+		channel:      channel,
+		mediaEnabled: session.Permissions().CanWatch,
+	}
+
+	if encodings := rtpVideo.GetParameters().Encodings; len(encodings) > 0 {
+		peer.videoSSRC = uint32(encodings[0].SSRC)
+	}
+
+	if encodings := rtpAudio.GetParameters().Encodings; len(encodings) > 0 {
+		peer.audioSSRC = uint32(encodings[0].SSRC)
 	}
 
+	if err := manager.videos[0].AddListener(); err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	if estimator != nil {
+		estimator.OnTargetBitrateChange(peer.estimate)
+	}
+
+	channel.OnOpen(func() {
+		manager.dataSync(peer)
+	})
+
+	channel.SetBufferedAmountLowThreshold(dataBufferedLow)
+	channel.OnBufferedAmountLow(peer.bufferedAmountLow)
+
 	connection.OnNegotiationNeeded(func() {
 		manager.logger.Warn().Msg("negotiation is needed")
 
-		sdp, err := peer.CreateOffer()
+		sdp, err := peer.CreateOffer(false)
 		if err != nil {
 			manager.logger.Err(err).Msg("creating offer failed")
 			return
@@ -286,38 +435,68 @@ func (manager *WebRTCManager) CreatePeer(id string, session types.Session) (type
 	})
 
 	if err := session.SetPeer(peer); err != nil {
+		peer.Destroy()
 		return nil, err
 	}
 
+	manager.peersMu.Lock()
+	manager.peers[id] = peer
+	manager.peersMu.Unlock()
+
 	go func() {
-		rtcpBuf := make([]byte, 1500)
 		for {
-			if _, _, rtcpErr := rtpVideo.Read(rtcpBuf); rtcpErr != nil {
+			packets, _, rtcpErr := rtpVideo.ReadRTCP()
+			if rtcpErr != nil {
 				return
 			}
+
+			peer.handleRTCP(packets)
 		}
 	}()
 
 	go func() {
-		rtcpBuf := make([]byte, 1500)
 		for {
-			if _, _, rtcpErr := rtpAudio.Read(rtcpBuf); rtcpErr != nil {
+			packets, _, rtcpErr := rtpAudio.ReadRTCP()
+			if rtcpErr != nil {
 				return
 			}
+
+			peer.handleRTCP(packets)
 		}
 	}()
 
 	return peer, nil
 }
 
+// restartPeer keeps session for reconnect grace period and restarts ICE, so
+// that peer can connect again after a short network drop. Offer is sent again
+// when session is resumed, if its socket was lost as well.
+func (manager *WebRTCManager) restartPeer(id string, session types.Session) {
+	manager.sessions.Interrupt(id)
+	if !manager.sessions.Has(id) {
+		return
+	}
+
+	if err := session.SignalRestart(); err != nil {
+		manager.logger.Warn().Err(err).Str("id", id).Msg("ICE restart failed")
+	}
+}
+
 func (manager *WebRTCManager) ICELite() bool {
 	return manager.config.ICELite
 }
 
-func (manager *WebRTCManager) ICEServers() []webrtc.ICEServer {
-	return manager.config.ICEServers
+// ICEServers returns ICE servers for the session, together with credentials
+// for embedded TURN server, if it is enabled.
+func (manager *WebRTCManager) ICEServers(id string) []webrtc.ICEServer {
+	servers := append([]webrtc.ICEServer{}, manager.config.ICEServers...)
+	return append(servers, manager.turn.ICEServers(id)...)
 }
 
 func (manager *WebRTCManager) ImplicitControl() bool {
 	return manager.config.ImplicitControl
 }
+
+func (manager *WebRTCManager) MultiControl() bool {
+	return manager.config.MultiControl
+}
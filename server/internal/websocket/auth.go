@@ -0,0 +1,203 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/utils"
+)
+
+// how long is session created by login valid
+const loginSessionTimeout = 24 * time.Hour
+
+// failed logins are limited per address, so that password hashing cannot
+// be abused to exhaust server resources or to guess passwords
+const (
+	loginFailuresMax    = 10
+	loginFailuresWindow = time.Minute
+)
+
+type loginFailures struct {
+	count int
+	since time.Time
+}
+
+type loginSession struct {
+	user      types.User
+	expiresAt time.Time
+}
+
+// Login verifies credentials and creates session, its token is sent back
+// in a cookie or used as bearer token.
+func (ws *WebSocketHandler) Login(r *http.Request, username string, password string) (string, *types.User, error) {
+	user, err := ws.authenticateCredentials(r, username, password)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := utils.NewUID(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ws.loginsMu.Lock()
+	defer ws.loginsMu.Unlock()
+
+	now := time.Now()
+	for key, val := range ws.logins {
+		if now.After(val.expiresAt) {
+			delete(ws.logins, key)
+		}
+	}
+
+	ws.logins[token] = loginSession{
+		user:      *user,
+		expiresAt: now.Add(loginSessionTimeout),
+	}
+
+	return token, user, nil
+}
+
+func (ws *WebSocketHandler) Logout(token string) {
+	ws.loginsMu.Lock()
+	delete(ws.logins, token)
+	ws.loginsMu.Unlock()
+}
+
+func (ws *WebSocketHandler) loginSession(token string) (*types.User, bool) {
+	ws.loginsMu.Lock()
+	defer ws.loginsMu.Unlock()
+
+	session, ok := ws.logins[token]
+	if !ok || time.Now().After(session.expiresAt) {
+		return nil, false
+	}
+
+	user := session.user
+	return &user, true
+}
+
+// Authenticate checks credentials of a request in this order: bearer token
+// (session token or password without username), basic auth, session cookie
+// and if allowed, username and password in query string.
+func (ws *WebSocketHandler) Authenticate(r *http.Request) (*types.User, error) {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token := strings.TrimPrefix(header, "Bearer ")
+		if user, ok := ws.loginSession(token); ok {
+			return user, nil
+		}
+
+		return ws.authenticateCredentials(r, "", token)
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		return ws.authenticateCredentials(r, username, password)
+	}
+
+	// cookie is sent by browser even when other site opens the request
+	if cookie, err := r.Cookie(types.SESSION_COOKIE); err == nil {
+		if !ws.checkOrigin(r) {
+			ws.logger.Warn().Str("origin", r.Header.Get("Origin")).Msg("session cookie sent from foreign origin")
+		} else if user, ok := ws.loginSession(cookie.Value); ok {
+			return user, nil
+		}
+	}
+
+	if !ws.conf.QueryAuth {
+		return nil, fmt.Errorf("no credentials provided")
+	}
+
+	query := r.URL.Query()
+	for _, key := range []string{"password", "pwd"} {
+		if password := query.Get(key); password != "" {
+			ws.queryAuthOnce.Do(func() {
+				ws.logger.Warn().Msg("password in query string is deprecated, use Authorization header or /login instead")
+			})
+
+			return ws.authenticateCredentials(r, query.Get("username"), password)
+		}
+	}
+
+	return nil, fmt.Errorf("no credentials provided")
+}
+
+// checkOrigin reports whether request was opened by a page of this server
+// or of an allowed origin. Browsers always send origin with websockets and
+// cross-site requests, so requests without it are accepted.
+func (ws *WebSocketHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	hosts := []string{r.Host}
+	if ws.conf.Proxy {
+		for _, host := range strings.Split(r.Header.Get("X-Forwarded-Host"), ",") {
+			hosts = append(hosts, strings.TrimSpace(host))
+		}
+	}
+
+	for _, host := range hosts {
+		if host != "" && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+
+	for _, allowed := range ws.conf.AllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authenticateCredentials verifies credentials, unless address of the
+// request failed too many times recently.
+func (ws *WebSocketHandler) authenticateCredentials(r *http.Request, username string, password string) (*types.User, error) {
+	address := utils.GetHttpRequestIP(r, ws.conf.Proxy)
+	now := time.Now()
+
+	ws.failuresMu.Lock()
+	failures, ok := ws.failures[address]
+	limited := ok && now.Sub(failures.since) < loginFailuresWindow && failures.count >= loginFailuresMax
+	ws.failuresMu.Unlock()
+
+	if limited {
+		ws.logger.Debug().Str("address", address).Msg("too many failed login attempts")
+		return nil, types.ErrTooManyAttempts
+	}
+
+	user, err := ws.auth.Authenticate(username, password)
+	if !errors.Is(err, types.ErrInvalidCredentials) {
+		return user, err
+	}
+
+	ws.failuresMu.Lock()
+	defer ws.failuresMu.Unlock()
+
+	for key, val := range ws.failures {
+		if now.Sub(val.since) >= loginFailuresWindow {
+			delete(ws.failures, key)
+		}
+	}
+
+	failures, ok = ws.failures[address]
+	if !ok {
+		failures = &loginFailures{since: now}
+		ws.failures[address] = failures
+	}
+
+	failures.count++
+	return nil, err
+}
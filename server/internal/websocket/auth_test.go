@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+func newTestHandler(conf *config.WebSocket) *WebSocketHandler {
+	return &WebSocketHandler{
+		logger: zerolog.Nop(),
+		conf:   conf,
+		logins: map[string]loginSession{
+			"session": {
+				user:      types.User{Username: "admin", Role: types.RoleAdmin},
+				expiresAt: time.Now().Add(time.Hour),
+			},
+		},
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.WebSocket
+		origin  string
+		forward string
+		allow   bool
+	}{
+		{"no origin", config.WebSocket{}, "", "", true},
+		{"same host", config.WebSocket{}, "https://neko.example.com", "", true},
+		{"same host case", config.WebSocket{}, "https://NEKO.example.com", "", true},
+		{"other host", config.WebSocket{}, "https://evil.example.com", "", false},
+		{"other port", config.WebSocket{}, "https://neko.example.com:8443", "", false},
+		{"invalid", config.WebSocket{}, "null", "", false},
+		{"allowed", config.WebSocket{AllowedOrigins: []string{"https://app.example.com/"}}, "https://app.example.com", "", true},
+		{"allowed other scheme", config.WebSocket{AllowedOrigins: []string{"https://app.example.com"}}, "http://app.example.com", "", false},
+		{"forwarded host", config.WebSocket{Proxy: true}, "https://public.example.com", "public.example.com", true},
+		{"forwarded host without proxy", config.WebSocket{}, "https://public.example.com", "public.example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := test.conf
+			ws := newTestHandler(&conf)
+
+			r := httptest.NewRequest(http.MethodGet, "http://neko.example.com/ws", nil)
+			if test.origin != "" {
+				r.Header.Set("Origin", test.origin)
+			}
+			if test.forward != "" {
+				r.Header.Set("X-Forwarded-Host", test.forward)
+			}
+
+			if allow := ws.checkOrigin(r); allow != test.allow {
+				t.Fatalf("checkOrigin = %v, want %v", allow, test.allow)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCookieOrigin(t *testing.T) {
+	ws := newTestHandler(&config.WebSocket{})
+
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://neko.example.com/ws", nil)
+		r.AddCookie(&http.Cookie{Name: types.SESSION_COOKIE, Value: "session"})
+		r.Header.Set("Origin", origin)
+		return r
+	}
+
+	user, err := ws.Authenticate(request("http://neko.example.com"))
+	if err != nil || !user.Admin() {
+		t.Fatalf("user = %v, error = %v, want admin", user, err)
+	}
+
+	// other site cannot use cookie of logged in admin
+	if user, err := ws.Authenticate(request("https://evil.example.com")); err == nil {
+		t.Fatalf("foreign origin authenticated as %v", user)
+	}
+}
@@ -1,41 +1,87 @@
 package handler
 
 import (
-	"strings"
+	"errors"
 
 	"m1k1o/neko/internal/types"
 	"m1k1o/neko/internal/types/event"
 	"m1k1o/neko/internal/types/message"
 )
 
+// rejected admin actions are logged, but they are not errors of the connection
+var adminRejections = []error{
+	types.ErrMemberNotFound,
+	types.ErrMemberIsAdmin,
+	types.ErrMemberNoAddress,
+	types.ErrMemberNoIdentity,
+	types.ErrMemberNoPermission,
+	types.ErrUnknownResource,
+	types.ErrResourceLocked,
+	types.ErrResourceNotLocked,
+	types.ErrInviteNotFound,
+	types.ErrInviteInvalid,
+	types.ErrBanInvalid,
+	types.ErrBanNotFound,
+	types.ErrMeetingBound,
+	types.ErrMeetingNotBound,
+}
+
+func (h *MessageHandler) adminResult(err error) error {
+	for _, rejection := range adminRejections {
+		if errors.Is(err, rejection) {
+			h.logger.Debug().Err(err).Msg("admin action rejected")
+			return nil
+		}
+	}
+
+	return err
+}
+
+// adminError reports failed admin action back to the session, that requested it.
+func (h *MessageHandler) adminError(session types.Session, action string, err error) error {
+	if err := session.Send(
+		message.AdminError{
+			Event:   event.ADMIN_ERROR,
+			Action:  action,
+			Message: err.Error(),
+		}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.ADMIN_ERROR)
+		return err
+	}
+
+	return h.adminResult(err)
+}
+
 func (h *MessageHandler) adminLock(id string, session types.Session, payload *message.AdminLock) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	if h.state.IsLocked(payload.Resource) {
-		h.logger.Debug().Str("resource", payload.Resource).Msg("resource already locked...")
-		return nil
+	return h.adminResult(h.Lock(id, payload.Resource))
+}
+
+func (h *MessageHandler) Lock(id string, resource string) error {
+	if h.state.IsLocked(resource) {
+		return types.ErrResourceLocked
 	}
 
-	if payload.Resource != "login" && payload.Resource != "control" {
-		h.logger.Debug().Msg("unknown lock resource")
-		return nil
+	if resource != "login" && resource != "control" {
+		return types.ErrUnknownResource
 	}
 
 	// TODO: Handle locks in sessions as flags.
-	if payload.Resource == "control" {
+	if resource == "control" {
 		h.sessions.SetControlLocked(true)
 	}
 
-	h.state.Lock(payload.Resource, id)
+	h.state.Lock(resource, id)
 
 	if err := h.sessions.Broadcast(
 		message.AdminLock{
 			Event:    event.ADMIN_LOCK,
 			ID:       id,
-			Resource: payload.Resource,
+			Resource: resource,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_LOCK)
 		return err
@@ -45,28 +91,31 @@ func (h *MessageHandler) adminLock(id string, session types.Session, payload *me
 }
 
 func (h *MessageHandler) adminUnlock(id string, session types.Session, payload *message.AdminLock) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	if !h.state.IsLocked(payload.Resource) {
-		h.logger.Debug().Str("resource", payload.Resource).Msg("resource not locked...")
-		return nil
+	return h.adminResult(h.Unlock(id, payload.Resource))
+}
+
+func (h *MessageHandler) Unlock(id string, resource string) error {
+	if !h.state.IsLocked(resource) {
+		return types.ErrResourceNotLocked
 	}
 
 	// TODO: Handle locks in sessions as flags.
-	if payload.Resource == "control" {
+	if resource == "control" {
 		h.sessions.SetControlLocked(false)
 	}
 
-	h.state.Unlock(payload.Resource)
+	h.state.Unlock(resource)
 
 	if err := h.sessions.Broadcast(
 		message.AdminLock{
 			Event:    event.ADMIN_UNLOCK,
 			ID:       id,
-			Resource: payload.Resource,
+			Resource: resource,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_UNLOCK)
 		return err
@@ -76,8 +125,13 @@ func (h *MessageHandler) adminUnlock(id string, session types.Session, payload *
 }
 
 func (h *MessageHandler) adminControl(id string, session types.Session) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	if !session.Permissions().CanControl {
+		h.logger.Debug().Msg("user cannot control")
 		return nil
 	}
 
@@ -113,11 +167,15 @@ func (h *MessageHandler) adminControl(id string, session types.Session) error {
 }
 
 func (h *MessageHandler) AdminRelease(id string, session types.Session) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
+	return h.Release(id)
+}
+
+func (h *MessageHandler) Release(id string) error {
 	host, ok := h.sessions.GetHost()
 
 	h.sessions.ClearHost()
@@ -143,22 +201,30 @@ func (h *MessageHandler) AdminRelease(id string, session types.Session) error {
 		}
 	}
 
-	return nil
+	return h.controlHandoff()
 }
 
 func (h *MessageHandler) adminGive(id string, session types.Session, payload *message.Admin) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	if !h.sessions.Has(payload.ID) {
-		h.logger.Debug().Str("id", payload.ID).Msg("user does not exist")
-		return nil
+	return h.adminResult(h.Give(id, payload.ID))
+}
+
+func (h *MessageHandler) Give(id string, target string) error {
+	targetSession, ok := h.sessions.Get(target)
+	if !ok {
+		return types.ErrMemberNotFound
+	}
+
+	if !targetSession.Permissions().CanControl {
+		return types.ErrMemberNoPermission
 	}
 
 	// set host
-	err := h.sessions.SetHost(payload.ID)
+	err := h.sessions.SetHost(target)
 	if err != nil {
 		return err
 	}
@@ -168,7 +234,7 @@ func (h *MessageHandler) adminGive(id string, session types.Session, payload *me
 		message.AdminTarget{
 			Event:  event.CONTROL_GIVE,
 			ID:     id,
-			Target: payload.ID,
+			Target: target,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_LOCKED)
 		return err
@@ -178,28 +244,35 @@ func (h *MessageHandler) adminGive(id string, session types.Session, payload *me
 }
 
 func (h *MessageHandler) adminMute(id string, session types.Session, payload *message.Admin) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	target, ok := h.sessions.Get(payload.ID)
+	return h.adminResult(h.Mute(id, payload.ID))
+}
+
+func (h *MessageHandler) Mute(id string, target string) error {
+	targetSession, ok := h.sessions.Get(target)
 	if !ok {
-		h.logger.Debug().Str("id", payload.ID).Msg("can't find session id")
-		return nil
+		return types.ErrMemberNotFound
 	}
 
-	if target.Admin() {
-		h.logger.Debug().Msg("target is an admin, baling")
-		return nil
+	if targetSession.Admin() {
+		return types.ErrMemberIsAdmin
 	}
 
-	target.SetMuted(true)
+	targetSession.SetMuted(true)
+
+	// mute of a user account survives reconnects and restarts
+	if username := targetSession.Username(); username != "" {
+		h.state.Mute(username, id)
+	}
 
 	if err := h.sessions.Broadcast(
 		message.AdminTarget{
 			Event:  event.ADMIN_MUTE,
-			Target: target.ID(),
+			Target: targetSession.ID(),
 			ID:     id,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_UNMUTE)
@@ -210,23 +283,30 @@ func (h *MessageHandler) adminMute(id string, session types.Session, payload *me
 }
 
 func (h *MessageHandler) adminUnmute(id string, session types.Session, payload *message.Admin) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	target, ok := h.sessions.Get(payload.ID)
+	return h.adminResult(h.Unmute(id, payload.ID))
+}
+
+func (h *MessageHandler) Unmute(id string, target string) error {
+	targetSession, ok := h.sessions.Get(target)
 	if !ok {
-		h.logger.Debug().Str("id", payload.ID).Msg("can't find target session")
-		return nil
+		return types.ErrMemberNotFound
 	}
 
-	target.SetMuted(false)
+	targetSession.SetMuted(false)
+
+	if username := targetSession.Username(); username != "" {
+		h.state.Unmute(username)
+	}
 
 	if err := h.sessions.Broadcast(
 		message.AdminTarget{
 			Event:  event.ADMIN_UNMUTE,
-			Target: target.ID(),
+			Target: targetSession.ID(),
 			ID:     id,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_UNMUTE)
@@ -237,32 +317,34 @@ func (h *MessageHandler) adminUnmute(id string, session types.Session, payload *
 }
 
 func (h *MessageHandler) adminKick(id string, session types.Session, payload *message.Admin) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	target, ok := h.sessions.Get(payload.ID)
+	return h.adminResult(h.Kick(id, payload.ID))
+}
+
+func (h *MessageHandler) Kick(id string, target string) error {
+	targetSession, ok := h.sessions.Get(target)
 	if !ok {
-		h.logger.Debug().Str("id", payload.ID).Msg("can't find session id")
-		return nil
+		return types.ErrMemberNotFound
 	}
 
-	if target.Admin() {
-		h.logger.Debug().Msg("target is an admin, baling")
-		return nil
+	if targetSession.Admin() {
+		return types.ErrMemberIsAdmin
 	}
 
-	if err := target.Kick("kicked"); err != nil {
+	if err := targetSession.Kick("kicked"); err != nil {
 		return err
 	}
 
 	if err := h.sessions.Broadcast(
 		message.AdminTarget{
 			Event:  event.ADMIN_KICK,
-			Target: target.ID(),
+			Target: targetSession.ID(),
 			ID:     id,
-		}, []string{payload.ID}); err != nil {
+		}, []string{target}); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_KICK)
 		return err
 	}
@@ -270,51 +352,61 @@ func (h *MessageHandler) adminKick(id string, session types.Session, payload *me
 	return nil
 }
 
-func (h *MessageHandler) adminBan(id string, session types.Session, payload *message.Admin) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+func (h *MessageHandler) adminPermissions(id string, session types.Session, payload *message.AdminPermissions) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
 		return nil
 	}
 
-	target, ok := h.sessions.Get(payload.ID)
-	if !ok {
-		h.logger.Debug().Str("id", payload.ID).Msg("can't find session id")
-		return nil
-	}
+	if targetSession, ok := h.sessions.Get(payload.ID); ok && !session.Admin() {
+		// moderators cannot demote each other, only admins can
+		if targetSession.Permissions().CanModerate {
+			return h.adminError(session, event.ADMIN_PERMISSIONS, types.ErrMemberNoPermission)
+		}
 
-	if target.Admin() {
-		h.logger.Debug().Msg("target is an admin, baling")
-		return nil
+		// moderators can only grant or revoke permissions they have
+		if !session.Permissions().CanChange(targetSession.Permissions(), payload.Permissions) {
+			h.logger.Debug().Msg("user cannot change these permissions")
+			return nil
+		}
 	}
 
-	remote := target.Address()
-	if remote == "" {
-		h.logger.Debug().Msg("no remote address, baling")
-		return nil
-	}
+	return h.adminResult(h.SetPermissions(id, payload.ID, payload.Permissions))
+}
 
-	address := strings.SplitN(remote, ":", -1)
-	if len(address[0]) < 1 {
-		h.logger.Debug().Str("address", remote).Msg("no remote address, baling")
-		return nil
+func (h *MessageHandler) SetPermissions(id string, target string, permissions types.Permissions) error {
+	targetSession, ok := h.sessions.Get(target)
+	if !ok {
+		return types.ErrMemberNotFound
 	}
 
-	h.logger.Debug().Str("address", remote).Msg("adding address to banned")
-	h.state.Ban(address[0], id)
+	if targetSession.Admin() {
+		return types.ErrMemberIsAdmin
+	}
 
-	if err := target.Kick("banned"); err != nil {
+	if err := targetSession.SetPermissions(permissions); err != nil {
 		return err
 	}
 
 	if err := h.sessions.Broadcast(
-		message.AdminTarget{
-			Event:  event.ADMIN_BAN,
-			Target: target.ID(),
-			ID:     id,
-		}, []string{payload.ID}); err != nil {
-		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_BAN)
+		message.AdminPermissions{
+			Event:       event.ADMIN_PERMISSIONS,
+			ID:          id,
+			Target:      target,
+			Permissions: permissions,
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_PERMISSIONS)
 		return err
 	}
 
+	// host loses control immediately
+	if !permissions.CanControl && h.sessions.IsHost(target) {
+		return h.Release(id)
+	}
+
 	return nil
 }
+
+func (h *MessageHandler) Members() []*types.Member {
+	return h.sessions.Members()
+}
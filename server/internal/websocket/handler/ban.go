@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
+	"m1k1o/neko/internal/websocket/state"
+)
+
+// banIdentities returns ban targets of an authenticated user.
+func banIdentities(username string, identity *types.Identity) []string {
+	identities := []string{}
+	if username != "" {
+		identities = append(identities, "user:"+username)
+	}
+	if identity != nil && identity.Provider != "" && identity.UserID != "" {
+		identities = append(identities, identity.Provider+":"+identity.UserID)
+	}
+	return identities
+}
+
+func (h *MessageHandler) adminBan(id string, session types.Session, payload *message.AdminBan) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	options := types.BanOptions{
+		Reason:   payload.Reason,
+		Duration: payload.Duration,
+		Identity: payload.Identity,
+	}
+
+	// member is banned, otherwise address, range or identity
+	if payload.ID != "" {
+		return h.adminResult(h.Ban(id, payload.ID, options))
+	}
+
+	// raw targets can hit members, that moderator does not see
+	if !session.Admin() {
+		h.logger.Debug().Msg("user not admin")
+		return h.adminResult(types.ErrMemberNoPermission)
+	}
+
+	return h.adminResult(h.BanTarget(id, payload.Target, options))
+}
+
+// Ban bans address or identity of a member and kicks it.
+func (h *MessageHandler) Ban(id string, target string, options types.BanOptions) error {
+	targetSession, ok := h.sessions.Get(target)
+	if !ok {
+		return types.ErrMemberNotFound
+	}
+
+	if targetSession.Admin() {
+		return types.ErrMemberIsAdmin
+	}
+
+	banTarget := targetSession.Address()
+	if options.Identity {
+		identities := banIdentities(targetSession.Username(), targetSession.Identity())
+		if len(identities) == 0 {
+			return types.ErrMemberNoIdentity
+		}
+
+		banTarget = identities[0]
+	} else if banTarget == "" {
+		return types.ErrMemberNoAddress
+	}
+
+	return h.BanTarget(id, banTarget, options)
+}
+
+// BanTarget bans address, address range or identity and kicks all
+// members, that it applies to.
+func (h *MessageHandler) BanTarget(id string, target string, options types.BanOptions) error {
+	target, err := state.NormalizeBanTarget(target)
+	if err != nil {
+		return err
+	}
+
+	if options.Duration < 0 {
+		return types.ErrBanInvalid
+	}
+
+	// admins are never banned, so their identities are refused
+	if strings.HasPrefix(target, "user:") {
+		if role, ok := h.auth.Role(strings.TrimPrefix(target, "user:")); ok && role == types.RoleAdmin {
+			return types.ErrMemberIsAdmin
+		}
+	}
+
+	for _, member := range h.sessions.Members() {
+		targetSession, ok := h.sessions.Get(member.ID)
+		if !ok || !targetSession.Admin() {
+			continue
+		}
+
+		for _, identity := range banIdentities(targetSession.Username(), targetSession.Identity()) {
+			if identity == target {
+				return types.ErrMemberIsAdmin
+			}
+		}
+	}
+
+	ban := types.Ban{
+		Reason:    options.Reason,
+		CreatedBy: id,
+		CreatedAt: time.Now(),
+	}
+
+	if options.Duration > 0 {
+		expiresAt := ban.CreatedAt.Add(time.Duration(options.Duration) * time.Second)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	h.logger.Info().Str("target", target).Str("by", id).Msg("adding ban")
+	h.state.Ban(target, ban)
+
+	for _, member := range h.sessions.Members() {
+		targetSession, ok := h.sessions.Get(member.ID)
+		if !ok || targetSession.Admin() || member.ID == id {
+			continue
+		}
+
+		if !h.state.IsBanned(targetSession.Address(), banIdentities(targetSession.Username(), targetSession.Identity())...) {
+			continue
+		}
+
+		if err := targetSession.Kick("banned"); err != nil {
+			return err
+		}
+
+		if err := h.sessions.Broadcast(
+			message.AdminTarget{
+				Event:  event.ADMIN_BAN,
+				Target: member.ID,
+				ID:     id,
+			}, []string{member.ID}); err != nil {
+			h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_BAN)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) adminUnban(id string, session types.Session, payload *message.AdminUnban) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	if err := h.adminResult(h.Unban(id, payload.Target)); err != nil {
+		return err
+	}
+
+	// send back remaining bans
+	return h.adminBans(id, session)
+}
+
+func (h *MessageHandler) Unban(id string, target string) error {
+	target, err := state.NormalizeBanTarget(target)
+	if err != nil {
+		return err
+	}
+
+	if !h.state.Unban(target) {
+		return types.ErrBanNotFound
+	}
+
+	h.logger.Info().Str("target", target).Str("by", id).Msg("ban removed")
+	return nil
+}
+
+func (h *MessageHandler) adminBans(id string, session types.Session) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	if err := session.Send(message.AdminBans{
+		Event: event.ADMIN_BANS,
+		Bans:  h.Bans(),
+	}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.ADMIN_BANS)
+		return err
+	}
+
+	return nil
+}
+
+// Bans returns active bans, oldest first.
+func (h *MessageHandler) Bans() []types.Ban {
+	bans := []types.Ban{}
+	for _, ban := range h.state.AllBanned() {
+		bans = append(bans, ban)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.Before(bans[j].CreatedAt)
+	})
+
+	return bans
+}
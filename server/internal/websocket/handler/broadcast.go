@@ -7,65 +7,57 @@ import (
 )
 
 func (h *MessageHandler) boradcastCreate(session types.Session, payload *message.BroadcastCreate) error {
-	broadcast := h.capture.Broadcast()
-
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanBroadcast {
+		h.logger.Debug().Msg("user cannot broadcast")
 		return nil
 	}
 
-	if payload.URL == "" {
-		return session.Send(
-			message.SystemMessage{
-				Event:   event.SYSTEM_ERROR,
-				Title:   "Error while starting broadcast",
-				Message: "missing broadcast URL",
-			})
+	if err := h.BroadcastStart(payload.URL); err != nil {
+		return h.boradcastError(session, "Error while starting broadcast", err)
 	}
 
-	if broadcast.Started() {
-		return session.Send(
-			message.SystemMessage{
-				Event:   event.SYSTEM_ERROR,
-				Title:   "Error while starting broadcast",
-				Message: "server is already broadcasting",
-			})
+	return nil
+}
+
+func (h *MessageHandler) BroadcastStart(url string) error {
+	broadcast := h.capture.Broadcast()
+
+	if url == "" {
+		return types.ErrBroadcastMissingURL
 	}
 
-	if err := broadcast.Start(payload.URL); err != nil {
-		if err := session.Send(
-			message.SystemMessage{
-				Event:   event.SYSTEM_ERROR,
-				Title:   "Error while starting broadcast",
-				Message: err.Error(),
-			}); err != nil {
-			h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.SYSTEM_ERROR)
-			return err
-		}
+	if broadcast.Started() {
+		return types.ErrBroadcastStarted
 	}
 
+	startErr := broadcast.Start(url)
+
+	// status is sent even if broadcast failed to start
 	if err := h.boradcastStatus(nil); err != nil {
 		return err
 	}
 
-	return nil
+	return startErr
 }
 
 func (h *MessageHandler) boradcastDestroy(session types.Session) error {
-	broadcast := h.capture.Broadcast()
-
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanBroadcast {
+		h.logger.Debug().Msg("user cannot broadcast")
 		return nil
 	}
 
+	if err := h.BroadcastStop(); err != nil {
+		return h.boradcastError(session, "Error while stopping broadcast", err)
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) BroadcastStop() error {
+	broadcast := h.capture.Broadcast()
+
 	if !broadcast.Started() {
-		return session.Send(
-			message.SystemMessage{
-				Event:   event.SYSTEM_ERROR,
-				Title:   "Error while stopping broadcast",
-				Message: "server is not broadcasting",
-			})
+		return types.ErrBroadcastNotStarted
 	}
 
 	broadcast.Stop()
@@ -77,6 +69,20 @@ func (h *MessageHandler) boradcastDestroy(session types.Session) error {
 	return nil
 }
 
+func (h *MessageHandler) boradcastError(session types.Session, title string, err error) error {
+	if err := session.Send(
+		message.SystemMessage{
+			Event:   event.SYSTEM_ERROR,
+			Title:   title,
+			Message: err.Error(),
+		}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.SYSTEM_ERROR)
+		return err
+	}
+
+	return nil
+}
+
 func (h *MessageHandler) boradcastStatus(session types.Session) error {
 	broadcast := h.capture.Broadcast()
 
@@ -88,7 +94,9 @@ func (h *MessageHandler) boradcastStatus(session types.Session) error {
 
 	// if no session, broadcast change
 	if session == nil {
-		if err := h.sessions.AdminBroadcast(msg, nil); err != nil {
+		if err := h.sessions.PermissionBroadcast(msg, func(permissions types.Permissions) bool {
+			return permissions.CanBroadcast
+		}, nil); err != nil {
 			h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.BORADCAST_STATUS)
 			return err
 		}
@@ -96,8 +104,8 @@ func (h *MessageHandler) boradcastStatus(session types.Session) error {
 		return nil
 	}
 
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanBroadcast {
+		h.logger.Debug().Msg("user cannot broadcast")
 		return nil
 	}
 
@@ -7,7 +7,7 @@ import (
 )
 
 func (h *MessageHandler) chat(id string, session types.Session, payload *message.ChatReceive) error {
-	if session.Muted() {
+	if session.Muted() || !session.Permissions().CanChat {
 		return nil
 	}
 
@@ -24,7 +24,7 @@ func (h *MessageHandler) chat(id string, session types.Session, payload *message
 }
 
 func (h *MessageHandler) chatEmote(id string, session types.Session, payload *message.EmoteReceive) error {
-	if session.Muted() {
+	if session.Muted() || !session.Permissions().CanChat {
 		return nil
 	}
 
@@ -1,21 +1,21 @@
 package handler
 
 import (
+	"time"
+
 	"m1k1o/neko/internal/types"
 	"m1k1o/neko/internal/types/event"
 	"m1k1o/neko/internal/types/message"
 )
 
 func (h *MessageHandler) controlRelease(id string, session types.Session) error {
-	// check if session is host
-	if !h.sessions.IsHost(id) {
+	// release host, if session is host
+	if !h.sessions.ClearHostIf(id) {
 		h.logger.Debug().Str("id", id).Msg("is not the host")
 		return nil
 	}
 
-	// release host
 	h.logger.Debug().Str("id", id).Msgf("host called %s", event.CONTROL_RELEASE)
-	h.sessions.ClearHost()
 
 	// tell everyone
 	if err := h.sessions.Broadcast(
@@ -27,24 +27,33 @@ func (h *MessageHandler) controlRelease(id string, session types.Session) error
 		return err
 	}
 
-	return nil
+	return h.controlHandoff()
+}
+
+// canControl checks if session can currently send input to the desktop.
+func (h *MessageHandler) canControl(id string, session types.Session) bool {
+	if !session.Permissions().CanControl {
+		return false
+	}
+
+	if h.webrtc.ImplicitControl() {
+		return h.sessions.CanControl(id)
+	}
+
+	return h.sessions.IsHost(id)
 }
 
 func (h *MessageHandler) controlRequest(id string, session types.Session) error {
-	// check for host
-	if !h.sessions.HasHost() {
-		// check if control is locked or user is admin
-		if h.state.IsLocked("control") && !session.Admin() {
-			h.logger.Debug().Msg("control is locked")
-			return nil
-		}
+	if !session.Permissions().CanControl {
+		h.logger.Debug().Str("id", id).Msg("user cannot control")
+		return nil
+	}
 
-		// set host
-		err := h.sessions.SetHost(id)
-		if err != nil {
-			return err
-		}
+	// check if control is locked or user is moderator
+	locked := h.state.IsLocked("control") && !session.Permissions().CanModerate
 
+	// set host, if there is none
+	if !locked && h.sessions.SetHostIfNone(id) {
 		// let everyone know
 		if err := h.sessions.Broadcast(
 			message.Control{
@@ -60,7 +69,22 @@ func (h *MessageHandler) controlRequest(id string, session types.Session) error
 
 	// get host
 	host, ok := h.sessions.GetHost()
+	if !ok && locked {
+		h.logger.Debug().Msg("control is locked")
+		return nil
+	}
+
 	if ok {
+		// wait in queue for control
+		if host.ID() != id && h.sessions.CanControl(id) {
+			position := h.sessions.QueueControl(id)
+			h.logger.Debug().Str("id", id).Int("position", position).Msg("queued for control")
+
+			// host held control for too long, so it was passed on right away
+			if h.sessions.IsHost(id) {
+				return nil
+			}
+		}
 
 		// tell session there is a host
 		if err := session.Send(message.Control{
@@ -84,6 +108,74 @@ func (h *MessageHandler) controlRequest(id string, session types.Session) error
 	return nil
 }
 
+func (h *MessageHandler) controlDequeue(id string, session types.Session) error {
+	if !h.sessions.DequeueControl(id) {
+		h.logger.Debug().Str("id", id).Msg("is not queued for control")
+	}
+
+	return nil
+}
+
+// ControlExpired releases host, that held control for too long or was
+// inactive, and passes control to the next in queue.
+func (h *MessageHandler) ControlExpired(id string, reason string) error {
+	if !h.sessions.ClearHostIf(id) {
+		return nil
+	}
+
+	h.logger.Debug().Str("id", id).Str("reason", reason).Msg("host control expired")
+
+	if err := h.sessions.Broadcast(
+		message.Control{
+			Event: event.CONTROL_RELEASE,
+			ID:    id,
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_RELEASE)
+		return err
+	}
+
+	return h.controlHandoff()
+}
+
+// controlHandoff gives control to the next in queue, if there is no host.
+func (h *MessageHandler) controlHandoff() error {
+	host, ok := h.sessions.NextHost()
+	if !ok {
+		return nil
+	}
+
+	if err := h.sessions.Broadcast(
+		message.Control{
+			Event: event.CONTROL_LOCKED,
+			ID:    host.ID(),
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_LOCKED)
+		return err
+	}
+
+	return nil
+}
+
+// ControlQueueChanged tells everyone who holds and who waits for control.
+func (h *MessageHandler) ControlQueueChanged(queue types.ControlQueue) error {
+	if err := h.sessions.Broadcast(controlQueue(queue), nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_QUEUE)
+		return err
+	}
+
+	return nil
+}
+
+func controlQueue(queue types.ControlQueue) message.ControlQueue {
+	return message.ControlQueue{
+		Event:       event.CONTROL_QUEUE,
+		Host:        queue.Host,
+		Queue:       queue.Queue,
+		Remaining:   int64(queue.Remaining.Round(time.Second) / time.Second),
+		IdleTimeout: int64(queue.IdleTimeout / time.Second),
+	}
+}
+
 func (h *MessageHandler) controlGive(id string, session types.Session, payload *message.Control) error {
 	// check if session is host
 	if !h.sessions.IsHost(id) {
@@ -91,21 +183,27 @@ func (h *MessageHandler) controlGive(id string, session types.Session, payload *
 		return nil
 	}
 
-	if !h.sessions.Has(payload.ID) {
+	target, ok := h.sessions.Get(payload.ID)
+	if !ok {
 		h.logger.Debug().Str("id", payload.ID).Msg("user does not exist")
 		return nil
 	}
 
-	// check if control is locked or giver is admin
-	if h.state.IsLocked("control") && !session.Admin() {
+	if !target.Permissions().CanControl {
+		h.logger.Debug().Str("id", payload.ID).Msg("user cannot control")
+		return nil
+	}
+
+	// check if control is locked or giver is moderator
+	if h.state.IsLocked("control") && !session.Permissions().CanModerate {
 		h.logger.Debug().Msg("control is locked")
 		return nil
 	}
 
-	// set host
-	err := h.sessions.SetHost(payload.ID)
-	if err != nil {
-		return err
+	// set host, unless control was taken from the giver meanwhile
+	if !h.sessions.SetHostIf(id, payload.ID) {
+		h.logger.Debug().Str("id", id).Msg("is not the host")
+		return nil
 	}
 
 	// let everyone know
@@ -124,7 +222,7 @@ func (h *MessageHandler) controlGive(id string, session types.Session, payload *
 
 func (h *MessageHandler) controlClipboard(id string, session types.Session, payload *message.Clipboard) error {
 	// check if session can access clipboard
-	if (!h.webrtc.ImplicitControl() && !h.sessions.IsHost(id)) || (h.webrtc.ImplicitControl() && !h.sessions.CanControl(id)) {
+	if !session.Permissions().CanUseClipboard || !h.canControl(id, session) {
 		h.logger.Debug().Str("id", id).Msg("cannot access clipboard")
 		return nil
 	}
@@ -135,7 +233,7 @@ func (h *MessageHandler) controlClipboard(id string, session types.Session, payl
 
 func (h *MessageHandler) controlKeyboard(id string, session types.Session, payload *message.Keyboard) error {
 	// check if session can control keyboard
-	if (!h.webrtc.ImplicitControl() && !h.sessions.IsHost(id)) || (h.webrtc.ImplicitControl() && !h.sessions.CanControl(id)) {
+	if !h.canControl(id, session) {
 		h.logger.Debug().Str("id", id).Msg("cannot control keyboard")
 		return nil
 	}
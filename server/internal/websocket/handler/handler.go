@@ -20,6 +20,8 @@ type MessageHandler struct {
 	desktop  types.DesktopManager
 	capture  types.CaptureManager
 	webrtc   types.WebRTCManager
+	auth     types.AuthProvider
+	invites  types.InviteManager
 	state    *state.State
 }
 
@@ -28,6 +30,8 @@ func New(
 	desktop types.DesktopManager,
 	capture types.CaptureManager,
 	webrtc types.WebRTCManager,
+	auth types.AuthProvider,
+	invites types.InviteManager,
 	state *state.State,
 ) *MessageHandler {
 	return &MessageHandler{
@@ -36,21 +40,34 @@ func New(
 		desktop:  desktop,
 		capture:  capture,
 		webrtc:   webrtc,
+		auth:     auth,
+		invites:  invites,
 		state:    state,
 	}
 }
 
-func (h *MessageHandler) Connected(admin bool, address string) (bool, string) {
+func (h *MessageHandler) Connected(user *types.User, identity *types.Identity, address string) (bool, string) {
 	if address == "" {
 		h.logger.Debug().Msg("no remote address")
-	} else {
-		if h.state.IsBanned(address) {
-			h.logger.Debug().Str("address", address).Msg("banned")
+	}
+
+	// authenticated admins are not affected by bans
+	if !user.Admin() {
+		if ban, ok := h.state.GetBanned(address, banIdentities(user.Username, identity)...); ok {
+			h.logger.Debug().Str("address", address).Str("target", ban.Target).Msg("banned")
 			return false, "banned"
 		}
 	}
 
-	if h.state.IsLocked("login") && !admin {
+	// room bound to a zoom meeting accepts only its participants
+	if identity != nil && identity.Provider == "zoom" {
+		if meeting, ok := h.state.GetMeeting(); ok && meeting != identity.MeetingID {
+			h.logger.Debug().Str("meeting", identity.MeetingID).Msg("different meeting")
+			return false, "meeting_mismatch"
+		}
+	}
+
+	if h.state.IsLocked("login") && !user.Admin() {
 		h.logger.Debug().Msg("server locked")
 		return false, "locked"
 	}
@@ -58,8 +75,13 @@ func (h *MessageHandler) Connected(admin bool, address string) (bool, string) {
 	return true, ""
 }
 
-func (h *MessageHandler) Disconnected(id string) {
-	h.sessions.Destroy(id)
+// Disconnected keeps session for reconnect grace period, so that it can
+// be resumed by a new socket. Session is destroyed right away, if client
+// closed the socket on purpose.
+func (h *MessageHandler) Disconnected(id string, socket types.WebSocket, closed bool) {
+	if h.sessions.Detach(id, socket) && closed {
+		h.sessions.Destroy(id)
+	}
 }
 
 func (h *MessageHandler) Message(id string, raw []byte) error {
@@ -93,6 +115,8 @@ func (h *MessageHandler) Message(id string, raw []byte) error {
 		return errors.Wrapf(h.controlRelease(id, session), "%s failed", header.Event)
 	case event.CONTROL_REQUEST:
 		return errors.Wrapf(h.controlRequest(id, session), "%s failed", header.Event)
+	case event.CONTROL_DEQUEUE:
+		return errors.Wrapf(h.controlDequeue(id, session), "%s failed", header.Event)
 	case event.CONTROL_GIVE:
 		payload := &message.Control{}
 		return errors.Wrapf(
@@ -172,11 +196,25 @@ func (h *MessageHandler) Message(id string, raw []byte) error {
 				return h.adminGive(id, session, payload)
 			}), "%s failed", header.Event)
 	case event.ADMIN_BAN:
-		payload := &message.Admin{}
+		payload := &message.AdminBan{}
 		return errors.Wrapf(
 			utils.Unmarshal(payload, raw, func() error {
 				return h.adminBan(id, session, payload)
 			}), "%s failed", header.Event)
+	case event.ADMIN_UNBAN:
+		payload := &message.AdminUnban{}
+		return errors.Wrapf(
+			utils.Unmarshal(payload, raw, func() error {
+				return h.adminUnban(id, session, payload)
+			}), "%s failed", header.Event)
+	case event.ADMIN_BANS:
+		return errors.Wrapf(h.adminBans(id, session), "%s failed", header.Event)
+	case event.ADMIN_BIND:
+		return errors.Wrapf(h.adminBind(id, session), "%s failed", header.Event)
+	case event.ADMIN_UNBIND:
+		return errors.Wrapf(h.adminUnbind(id, session), "%s failed", header.Event)
+	case event.ADMIN_PEER_STATS:
+		return errors.Wrapf(h.adminPeerStats(id, session), "%s failed", header.Event)
 	case event.ADMIN_KICK:
 		payload := &message.Admin{}
 		return errors.Wrapf(
@@ -195,6 +233,26 @@ func (h *MessageHandler) Message(id string, raw []byte) error {
 			utils.Unmarshal(payload, raw, func() error {
 				return h.adminUnmute(id, session, payload)
 			}), "%s failed", header.Event)
+	case event.ADMIN_PERMISSIONS:
+		payload := &message.AdminPermissions{}
+		return errors.Wrapf(
+			utils.Unmarshal(payload, raw, func() error {
+				return h.adminPermissions(id, session, payload)
+			}), "%s failed", header.Event)
+	case event.ADMIN_INVITE:
+		payload := &message.AdminInvite{}
+		return errors.Wrapf(
+			utils.Unmarshal(payload, raw, func() error {
+				return h.adminInvite(id, session, payload)
+			}), "%s failed", header.Event)
+	case event.ADMIN_INVITES:
+		return errors.Wrapf(h.adminInvites(id, session), "%s failed", header.Event)
+	case event.ADMIN_REVOKE:
+		payload := &message.Admin{}
+		return errors.Wrapf(
+			utils.Unmarshal(payload, raw, func() error {
+				return h.adminRevoke(id, session, payload)
+			}), "%s failed", header.Event)
 	default:
 		return errors.Errorf("unknown message event %s", header.Event)
 	}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
+)
+
+func (h *MessageHandler) adminInvite(id string, session types.Session, payload *message.AdminInvite) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	// only admins can invite other admins
+	if payload.Role == types.RoleAdmin && !session.Admin() {
+		h.logger.Debug().Msg("user not admin")
+		return nil
+	}
+
+	token, invite, err := h.createInvite(id, session.Admin(), payload.InviteOptions)
+	if err != nil {
+		return h.adminError(session, event.ADMIN_INVITE, err)
+	}
+
+	if err := session.Send(message.AdminInviteCreated{
+		Event:  event.ADMIN_INVITE,
+		Token:  token,
+		Invite: invite,
+	}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.ADMIN_INVITE)
+		return err
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) CreateInvite(id string, options types.InviteOptions) (string, *types.Invite, error) {
+	return h.createInvite(id, true, options)
+}
+
+func (h *MessageHandler) createInvite(id string, admin bool, options types.InviteOptions) (string, *types.Invite, error) {
+	token, invite, err := h.invites.CreateInvite(id, admin, options)
+	if err != nil {
+		return "", nil, err
+	}
+
+	h.logger.Info().
+		Str("invite", invite.ID).
+		Str("role", string(invite.Role)).
+		Time("expires_at", invite.ExpiresAt).
+		Msg("invite created")
+
+	return token, invite, nil
+}
+
+func (h *MessageHandler) adminInvites(id string, session types.Session) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	if err := session.Send(message.AdminInvites{
+		Event:   event.ADMIN_INVITES,
+		Invites: h.Invites(),
+	}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.ADMIN_INVITES)
+		return err
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) Invites() []types.Invite {
+	return h.invites.Invites()
+}
+
+func (h *MessageHandler) adminRevoke(id string, session types.Session, payload *message.Admin) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	// invites created by admins can be revoked only by admins
+	if err := h.revokeInvite(id, session.Admin(), payload.ID); err != nil {
+		return h.adminError(session, event.ADMIN_REVOKE, err)
+	}
+
+	// send back outstanding invites
+	return h.adminInvites(id, session)
+}
+
+func (h *MessageHandler) RevokeInvite(id string, invite string) error {
+	return h.revokeInvite(id, true, invite)
+}
+
+func (h *MessageHandler) revokeInvite(id string, admin bool, invite string) error {
+	if err := h.invites.RevokeInvite(invite, admin); err != nil {
+		return err
+	}
+
+	h.logger.Info().Str("invite", invite).Str("by", id).Msg("invite revoked")
+	return nil
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
+)
+
+const ZOOM_MEETING_SESSION = "by_zoom_meeting"
+
+// resources locked when bound meeting ends
+var zoomMeetingLocks = []string{"login", "control"}
+
+func (h *MessageHandler) adminBind(id string, session types.Session) error {
+	if !session.Admin() {
+		h.logger.Debug().Msg("user not admin")
+		return nil
+	}
+
+	// room is bound to the meeting, that admin joined from
+	identity := session.Identity()
+	if identity == nil || identity.Provider != "zoom" || identity.MeetingID == "" {
+		return h.adminResult(types.ErrMemberNoIdentity)
+	}
+
+	return h.adminResult(h.BindMeeting(id, identity.MeetingID))
+}
+
+// BindMeeting binds room to a zoom meeting, members from other meetings
+// are kicked and locks of the previous meeting are removed.
+func (h *MessageHandler) BindMeeting(id string, meeting string) error {
+	if !h.state.BindMeeting(meeting) {
+		return types.ErrMeetingBound
+	}
+
+	h.logger.Info().Str("id", id).Str("meeting", meeting).Msg("room bound to zoom meeting")
+
+	for _, member := range h.sessions.Members() {
+		session, ok := h.sessions.Get(member.ID)
+		if !ok {
+			continue
+		}
+
+		identity := session.Identity()
+		if identity == nil || identity.Provider != "zoom" || identity.MeetingID == meeting {
+			continue
+		}
+
+		if err := session.Kick("meeting_mismatch"); err != nil {
+			h.logger.Warn().Err(err).Str("id", member.ID).Msg("unable to kick member of different meeting")
+		}
+	}
+
+	h.meetingUnlock(id)
+
+	if err := h.sessions.Broadcast(
+		message.AdminMeeting{
+			Event:   event.ADMIN_BIND,
+			Meeting: meeting,
+			ID:      id,
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_BIND)
+		return err
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) adminUnbind(id string, session types.Session) error {
+	if !session.Admin() {
+		h.logger.Debug().Msg("user not admin")
+		return nil
+	}
+
+	return h.adminResult(h.UnbindMeeting(id))
+}
+
+func (h *MessageHandler) UnbindMeeting(id string) error {
+	meeting, ok := h.state.UnbindMeeting()
+	if !ok {
+		return types.ErrMeetingNotBound
+	}
+
+	h.logger.Info().Str("id", id).Str("meeting", meeting).Msg("room unbound from zoom meeting")
+
+	if err := h.sessions.Broadcast(
+		message.AdminMeeting{
+			Event: event.ADMIN_UNBIND,
+			ID:    id,
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_UNBIND)
+		return err
+	}
+
+	return nil
+}
+
+// MeetingEnded unbinds ended meeting, locks room on its behalf and releases
+// control. Room stays locked until admin binds it to a new meeting.
+func (h *MessageHandler) MeetingEnded(meeting string) {
+	if !h.state.EndMeeting(meeting) {
+		return
+	}
+
+	h.logger.Info().Str("meeting", meeting).Msg("bound zoom meeting ended")
+
+	for _, resource := range zoomMeetingLocks {
+		if h.state.IsLocked(resource) {
+			continue
+		}
+
+		// TODO: Handle locks in sessions as flags.
+		if resource == "control" {
+			h.sessions.SetControlLocked(true)
+		}
+
+		h.state.Lock(resource, ZOOM_MEETING_SESSION)
+
+		if err := h.sessions.Broadcast(
+			message.AdminLock{
+				Event:    event.ADMIN_LOCK,
+				ID:       ZOOM_MEETING_SESSION,
+				Resource: resource,
+			}, nil); err != nil {
+			h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_LOCK)
+		}
+	}
+
+	host, ok := h.sessions.GetHost()
+	if !ok || !h.sessions.ClearHostIf(host.ID()) {
+		return
+	}
+
+	if err := h.sessions.Broadcast(
+		message.AdminTarget{
+			Event:  event.ADMIN_RELEASE,
+			ID:     ZOOM_MEETING_SESSION,
+			Target: host.ID(),
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_RELEASE)
+	}
+}
+
+// meetingUnlock removes locks, that were set when previous meeting ended.
+func (h *MessageHandler) meetingUnlock(id string) {
+	for _, resource := range zoomMeetingLocks {
+		if sess, ok := h.state.GetLocked(resource); !ok || sess != ZOOM_MEETING_SESSION {
+			continue
+		}
+
+		// TODO: Handle locks in sessions as flags.
+		if resource == "control" {
+			h.sessions.SetControlLocked(false)
+		}
+
+		h.state.Unlock(resource)
+
+		if err := h.sessions.Broadcast(
+			message.AdminLock{
+				Event:    event.ADMIN_UNLOCK,
+				ID:       id,
+				Resource: resource,
+			}, nil); err != nil {
+			h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.ADMIN_UNLOCK)
+		}
+	}
+}
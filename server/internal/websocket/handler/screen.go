@@ -7,16 +7,20 @@ import (
 )
 
 func (h *MessageHandler) screenSet(id string, session types.Session, payload *message.ScreenResolution) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanChangeScreen {
+		h.logger.Debug().Msg("user cannot change screen")
 		return nil
 	}
 
-	if err := h.desktop.SetScreenSize(types.ScreenSize{
+	return h.SetScreenSize(id, types.ScreenSize{
 		Width:  payload.Width,
 		Height: payload.Height,
 		Rate:   payload.Rate,
-	}); err != nil {
+	})
+}
+
+func (h *MessageHandler) SetScreenSize(id string, size types.ScreenSize) error {
+	if err := h.desktop.SetScreenSize(size); err != nil {
 		h.logger.Warn().Err(err).Msgf("unable to change screen size")
 		return err
 	}
@@ -25,9 +29,9 @@ func (h *MessageHandler) screenSet(id string, session types.Session, payload *me
 		message.ScreenResolution{
 			Event:  event.SCREEN_RESOLUTION,
 			ID:     id,
-			Width:  payload.Width,
-			Height: payload.Height,
-			Rate:   payload.Rate,
+			Width:  size.Width,
+			Height: size.Height,
+			Rate:   size.Rate,
 		}, nil); err != nil {
 		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.SCREEN_RESOLUTION)
 		return err
@@ -53,8 +57,8 @@ func (h *MessageHandler) screenResolution(id string, session types.Session) erro
 }
 
 func (h *MessageHandler) screenConfigurations(id string, session types.Session) error {
-	if !session.Admin() {
-		h.logger.Debug().Msg("user not admin")
+	if !session.Permissions().CanChangeScreen {
+		h.logger.Debug().Msg("user cannot change screen")
 		return nil
 	}
 
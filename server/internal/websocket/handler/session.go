@@ -7,28 +7,54 @@ import (
 )
 
 func (h *MessageHandler) SessionCreated(id string, session types.Session) error {
+	// mute list is kept for user accounts
+	if username := session.Username(); username != "" && h.state.IsMuted(username) {
+		session.SetMuted(true)
+	}
+
 	// send sdp and id over to client
 	if err := h.signalProvide(id, session); err != nil {
 		return err
 	}
 
+	return h.sessionInit(id, session)
+}
+
+// SessionResumed synchronizes state, that could change while session was
+// disconnected, and restarts ICE of its peer.
+func (h *MessageHandler) SessionResumed(id string, session types.Session) error {
+	if err := h.sessionInit(id, session); err != nil {
+		return err
+	}
+
+	if err := h.sessionSync(id, session); err != nil {
+		return err
+	}
+
+	return session.SignalRestart()
+}
+
+func (h *MessageHandler) sessionInit(id string, session types.Session) error {
 	// send initialization information
 	if err := session.Send(message.SystemInit{
 		Event:           event.SYSTEM_INIT,
 		ImplicitHosting: h.webrtc.ImplicitControl(),
+		MultiControl:    h.webrtc.MultiControl(),
 		Locks:           h.state.AllLocked(),
 	}); err != nil {
 		h.logger.Warn().Str("id", id).Err(err).Msgf("sending event %s has failed", event.SYSTEM_INIT)
 		return err
 	}
 
-	if session.Admin() {
-		// send screen configurations if admin
+	// send screen configurations if allowed
+	if session.Permissions().CanChangeScreen {
 		if err := h.screenConfigurations(id, session); err != nil {
 			return err
 		}
+	}
 
-		// send broadcast status if admin
+	// send broadcast status if allowed
+	if session.Permissions().CanBroadcast {
 		if err := h.boradcastStatus(session); err != nil {
 			return err
 		}
@@ -38,6 +64,24 @@ func (h *MessageHandler) SessionCreated(id string, session types.Session) error
 }
 
 func (h *MessageHandler) SessionConnected(id string, session types.Session) error {
+	if err := h.sessionSync(id, session); err != nil {
+		return err
+	}
+
+	// let everyone know there is a new session
+	if err := h.sessions.Broadcast(
+		message.Member{
+			Event:  event.MEMBER_CONNECTED,
+			Member: session.Member(),
+		}, nil); err != nil {
+		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_RELEASE)
+		return err
+	}
+
+	return nil
+}
+
+func (h *MessageHandler) sessionSync(id string, session types.Session) error {
 	// send list of members to session
 	if err := session.Send(message.MembersList{
 		Event:    event.MEMBER_LIST,
@@ -64,14 +108,12 @@ func (h *MessageHandler) SessionConnected(id string, session types.Session) erro
 		}
 	}
 
-	// let everyone know there is a new session
-	if err := h.sessions.Broadcast(
-		message.Member{
-			Event:  event.MEMBER_CONNECTED,
-			Member: session.Member(),
-		}, nil); err != nil {
-		h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_RELEASE)
-		return err
+	// tell session who waits for control
+	if queue := h.sessions.ControlQueue(); len(queue.Queue) > 0 {
+		if err := session.Send(controlQueue(queue)); err != nil {
+			h.logger.Warn().Str("id", id).Err(err).Msgf("sending event %s has failed", event.CONTROL_QUEUE)
+			return err
+		}
 	}
 
 	return nil
@@ -79,14 +121,17 @@ func (h *MessageHandler) SessionConnected(id string, session types.Session) erro
 
 func (h *MessageHandler) SessionDestroyed(id string) error {
 	// clear host if exists
-	if h.sessions.IsHost(id) {
-		h.sessions.ClearHost()
+	if h.sessions.ClearHostIf(id) {
 		if err := h.sessions.Broadcast(message.Control{
 			Event: event.CONTROL_RELEASE,
 			ID:    id,
 		}, nil); err != nil {
 			h.logger.Warn().Err(err).Msgf("broadcasting event %s has failed", event.CONTROL_RELEASE)
 		}
+
+		if err := h.controlHandoff(); err != nil {
+			h.logger.Warn().Err(err).Msg("passing control to the next in queue has failed")
+		}
 	}
 
 	// let everyone know session disconnected
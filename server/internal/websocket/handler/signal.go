@@ -12,17 +12,18 @@ func (h *MessageHandler) signalProvide(id string, session types.Session) error {
 		return err
 	}
 
-	sdp, err := peer.CreateOffer()
+	sdp, err := peer.CreateOffer(false)
 	if err != nil {
 		return err
 	}
 
 	if err := session.Send(message.SignalProvide{
-		Event: event.SIGNAL_PROVIDE,
-		ID:    id,
-		SDP:   sdp,
-		Lite:  h.webrtc.ICELite(),
-		ICE:   h.webrtc.ICEServers(),
+		Event:  event.SIGNAL_PROVIDE,
+		ID:     id,
+		SDP:    sdp,
+		Lite:   h.webrtc.ICELite(),
+		ICE:    h.webrtc.ICEServers(id),
+		Resume: session.ResumeToken(),
 	}); err != nil {
 		return err
 	}
@@ -35,8 +36,11 @@ func (h *MessageHandler) signalRemoteOffer(id string, session types.Session, pay
 }
 
 func (h *MessageHandler) signalRemoteAnswer(id string, session types.Session, payload *message.SignalAnswer) error {
-	if err := session.SetName(payload.DisplayName); err != nil {
-		return err
+	// display name can be enforced by identity
+	if identity := session.Identity(); identity == nil || identity.Name == "" {
+		if err := session.SetName(payload.DisplayName); err != nil {
+			return err
+		}
 	}
 
 	if err := session.SignalRemoteAnswer(payload.SDP); err != nil {
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/types/event"
+	"m1k1o/neko/internal/types/message"
+)
+
+func (h *MessageHandler) adminPeerStats(id string, session types.Session) error {
+	if !session.Permissions().CanModerate {
+		h.logger.Debug().Msg("user cannot moderate")
+		return nil
+	}
+
+	peers := h.PeerStats()
+
+	// addresses of members are visible only to admins
+	if !session.Admin() {
+		for i := range peers {
+			peers[i].Local = nil
+			peers[i].Remote = nil
+		}
+	}
+
+	if err := session.Send(message.AdminPeerStats{
+		Event: event.ADMIN_PEER_STATS,
+		Peers: peers,
+	}); err != nil {
+		h.logger.Warn().Err(err).Msgf("sending event %s has failed", event.ADMIN_PEER_STATS)
+		return err
+	}
+
+	return nil
+}
+
+// PeerStats returns last connection summaries of all peers.
+func (h *MessageHandler) PeerStats() []types.PeerStats {
+	return h.webrtc.PeerStats()
+}
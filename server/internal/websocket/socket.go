@@ -2,7 +2,6 @@ package websocket
 
 import (
 	"encoding/json"
-	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
@@ -17,12 +16,7 @@ type WebSocket struct {
 }
 
 func (socket *WebSocket) Address() string {
-	//remote := socket.connection.RemoteAddr()
-	address := strings.SplitN(socket.address, ":", -1)
-	if len(address[0]) < 1 {
-		return socket.address
-	}
-	return address[0]
+	return socket.address
 }
 
 func (socket *WebSocket) Send(v interface{}) error {
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"net/netip"
+	"strings"
+
+	"m1k1o/neko/internal/types"
+)
+
+// ranges broader than these would ban large parts of the internet
+const (
+	banMinBits4 = 16
+	banMinBits6 = 48
+)
+
+// NormalizeBanTarget validates ban target and returns its canonical form.
+// Target is one of:
+//   - single address: 192.0.2.1, 2001:db8::1
+//   - address range: 10.0.0.0/16, 2001:db8::/48 (at most that broad)
+//   - identity: user:<username>, <provider>:<user id>
+func NormalizeBanTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+
+	if addr, err := netip.ParseAddr(target); err == nil {
+		return addr.WithZone("").Unmap().String(), nil
+	}
+
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		addr := prefix.Addr().Unmap()
+		bits := prefix.Bits()
+		if prefix.Addr().Is4In6() {
+			bits -= 96
+		}
+
+		if bits < 0 || (addr.Is4() && bits < banMinBits4) || (addr.Is6() && bits < banMinBits6) {
+			return "", types.ErrBanInvalid
+		}
+
+		prefix = netip.PrefixFrom(addr, bits).Masked()
+		if prefix.IsSingleIP() {
+			return prefix.Addr().String(), nil
+		}
+
+		return prefix.String(), nil
+	}
+
+	// provider is a lowercase word, so that address with port is not an identity
+	provider, id, ok := strings.Cut(target, ":")
+	if !ok || provider == "" || id == "" || strings.Trim(provider, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return "", types.ErrBanInvalid
+	}
+
+	return target, nil
+}
+
+// banIndex finds ban of an address or identity without going through
+// all bans. Ranges are grouped by prefix length, so that an address is
+// masked and looked up once for every length in use.
+type banIndex struct {
+	addresses  map[netip.Addr]string
+	prefixes   map[int]map[netip.Prefix]string
+	identities map[string]string
+}
+
+func newBanIndex() *banIndex {
+	return &banIndex{
+		addresses:  make(map[netip.Addr]string),
+		prefixes:   make(map[int]map[netip.Prefix]string),
+		identities: make(map[string]string),
+	}
+}
+
+// add expects normalized target.
+func (i *banIndex) add(target string) {
+	if addr, err := netip.ParseAddr(target); err == nil {
+		i.addresses[addr] = target
+		return
+	}
+
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		set, ok := i.prefixes[prefix.Bits()]
+		if !ok {
+			set = make(map[netip.Prefix]string)
+			i.prefixes[prefix.Bits()] = set
+		}
+
+		set[prefix] = target
+		return
+	}
+
+	i.identities[target] = target
+}
+
+func (i *banIndex) remove(target string) {
+	if addr, err := netip.ParseAddr(target); err == nil {
+		delete(i.addresses, addr)
+		return
+	}
+
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		if set, ok := i.prefixes[prefix.Bits()]; ok {
+			delete(set, prefix)
+			if len(set) == 0 {
+				delete(i.prefixes, prefix.Bits())
+			}
+		}
+		return
+	}
+
+	delete(i.identities, target)
+}
+
+// match returns targets of all bans, that apply to an address or identities.
+func (i *banIndex) match(address string, identities []string) []string {
+	targets := []string{}
+
+	for _, identity := range identities {
+		if target, ok := i.identities[identity]; ok {
+			targets = append(targets, target)
+		}
+	}
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return targets
+	}
+
+	addr = addr.WithZone("").Unmap()
+	if target, ok := i.addresses[addr]; ok {
+		targets = append(targets, target)
+	}
+
+	for bits, set := range i.prefixes {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+
+		if target, ok := set[prefix]; ok {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
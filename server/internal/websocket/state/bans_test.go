@@ -0,0 +1,174 @@
+package state
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"m1k1o/neko/internal/types"
+)
+
+func TestNormalizeBanTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		err    error
+	}{
+		// addresses
+		{"192.0.2.1", "192.0.2.1", nil},
+		{" 192.0.2.1 ", "192.0.2.1", nil},
+		{"2001:DB8::1", "2001:db8::1", nil},
+		{"fe80::1%eth0", "fe80::1", nil},
+		{"::ffff:192.0.2.1", "192.0.2.1", nil},
+		// ranges
+		{"192.0.2.77/24", "192.0.2.0/24", nil},
+		{"10.1.2.3/16", "10.1.0.0/16", nil},
+		{"10.1.2.3/15", "", types.ErrBanInvalid},
+		{"0.0.0.0/0", "", types.ErrBanInvalid},
+		{"192.0.2.1/32", "192.0.2.1", nil},
+		{"2001:db8:1:2::/48", "2001:db8:1::/48", nil},
+		{"2001:db8::/47", "", types.ErrBanInvalid},
+		{"::/0", "", types.ErrBanInvalid},
+		{"2001:db8::1/128", "2001:db8::1", nil},
+		{"::ffff:192.0.2.0/120", "192.0.2.0/24", nil},
+		{"::ffff:10.0.0.0/104", "", types.ErrBanInvalid},
+		{"::ffff:0.0.0.0/64", "", types.ErrBanInvalid},
+		// identities
+		{"user:alice", "user:alice", nil},
+		{"zoom:KdYKjnimT4KPd8FFgQt9FQ", "zoom:KdYKjnimT4KPd8FFgQt9FQ", nil},
+		{"user:", "", types.ErrBanInvalid},
+		{":alice", "", types.ErrBanInvalid},
+		{"Zoom:alice", "", types.ErrBanInvalid},
+		{"192.0.2.1:8080", "", types.ErrBanInvalid},
+		{"alice", "", types.ErrBanInvalid},
+		{"", "", types.ErrBanInvalid},
+	}
+
+	for _, test := range tests {
+		target, err := NormalizeBanTarget(test.target)
+		if !errors.Is(err, test.err) || target != test.want {
+			t.Errorf("%q: target = %q, error = %v, want %q, %v", test.target, target, err, test.want, test.err)
+		}
+	}
+}
+
+func TestBanIndexMatch(t *testing.T) {
+	index := newBanIndex()
+	for _, target := range []string{
+		"192.0.2.1",
+		"198.51.100.0/24",
+		"10.0.0.0/16",
+		"2001:db8::1",
+		"2001:db8:1::/48",
+		"user:alice",
+		"zoom:bob",
+	} {
+		index.add(target)
+	}
+
+	tests := []struct {
+		name       string
+		address    string
+		identities []string
+		want       []string
+	}{
+		{"address", "192.0.2.1", nil, []string{"192.0.2.1"}},
+		{"other address", "192.0.2.2", nil, []string{}},
+		{"range", "198.51.100.7", nil, []string{"198.51.100.0/24"}},
+		{"wide range", "10.0.255.1", nil, []string{"10.0.0.0/16"}},
+		{"mapped address", "::ffff:192.0.2.1", nil, []string{"192.0.2.1"}},
+		{"mapped range", "::ffff:198.51.100.7", nil, []string{"198.51.100.0/24"}},
+		{"ipv6 address", "2001:db8::1", nil, []string{"2001:db8::1"}},
+		{"ipv6 zone", "2001:db8::1%eth0", nil, []string{"2001:db8::1"}},
+		{"ipv6 range", "2001:db8:1:ffff::1", nil, []string{"2001:db8:1::/48"}},
+		{"ipv6 outside range", "2001:db8:2::1", nil, []string{}},
+		{"identity", "192.0.2.2", []string{"user:alice"}, []string{"user:alice"}},
+		{"provider identity", "192.0.2.2", []string{"user:bob", "zoom:bob"}, []string{"zoom:bob"}},
+		{"identity and address", "192.0.2.1", []string{"user:alice"}, []string{"192.0.2.1", "user:alice"}},
+		{"invalid address", "unknown", []string{"user:alice"}, []string{"user:alice"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := index.match(test.address, test.identities)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("match = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	for _, target := range []string{"192.0.2.1", "198.51.100.0/24", "2001:db8:1::/48", "user:alice"} {
+		index.remove(target)
+	}
+
+	if got := index.match("198.51.100.7", []string{"user:alice"}); len(got) != 0 {
+		t.Fatalf("removed bans match: %v", got)
+	}
+
+	if got := index.match("::ffff:192.0.2.1", nil); len(got) != 0 {
+		t.Fatalf("removed address matches: %v", got)
+	}
+
+	if _, ok := index.prefixes[24]; ok {
+		t.Fatal("empty prefix length is kept")
+	}
+
+	if got := index.match("10.0.0.1", []string{"zoom:bob"}); len(got) != 2 {
+		t.Fatalf("remaining bans = %v", got)
+	}
+}
+
+func TestStateBanExpiry(t *testing.T) {
+	state, err := New(NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+
+	state.Ban("192.0.2.1", types.Ban{ExpiresAt: &past})
+	state.Ban("198.51.100.0/24", types.Ban{ExpiresAt: &future})
+	state.Ban("user:alice", types.Ban{ExpiresAt: &past})
+	state.Ban("user:bob", types.Ban{})
+
+	if state.IsBanned("192.0.2.1") {
+		t.Fatal("expired address ban applies")
+	}
+
+	if _, ok := state.GetBanned("192.0.2.2", "user:alice"); ok {
+		t.Fatal("expired identity ban applies")
+	}
+
+	if ban, ok := state.GetBanned("198.51.100.1"); !ok || ban.Target != "198.51.100.0/24" {
+		t.Fatalf("ban = %+v, %v, want range ban", ban, ok)
+	}
+
+	// expired ban is removed, once it is found
+	if _, ok := state.banned["192.0.2.1"]; ok {
+		t.Fatal("expired ban is kept")
+	}
+
+	past = time.Now().Add(-time.Second)
+	state.Ban("203.0.113.1", types.Ban{ExpiresAt: &past})
+
+	banned := state.AllBanned()
+	if len(banned) != 2 || banned["user:bob"].Target != "user:bob" {
+		t.Fatalf("banned = %v", banned)
+	}
+
+	if _, ok := state.banned["203.0.113.1"]; ok {
+		t.Fatal("expired ban is kept")
+	}
+
+	if !state.Unban("user:bob") || state.IsBanned("192.0.2.2", "user:bob") {
+		t.Fatal("ban is not removed")
+	}
+
+	if state.Unban("user:bob") {
+		t.Fatal("ban is removed twice")
+	}
+}
@@ -1,61 +1,368 @@
 package state
 
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/types"
+)
+
+// State is safe for concurrent use. Maps returned by it are copies.
+// Changes are written to the store after the mutex is released, so that
+// slow disk does not block readers.
 type State struct {
-	banned map[string]string // IP -> session ID (that banned it)
-	locked map[string]string // resource name -> session ID (that locked it)
+	logger  zerolog.Logger
+	store   Store
+	storeMu sync.Mutex // held while writing, keeps order of changes
+	pending []Change
+	mu      sync.Mutex
+	banned  map[string]types.Ban // ban target -> ban record
+	bans    *banIndex
+	locked  map[string]string // resource name -> session ID (that locked it)
+	muted   map[string]string // username -> session ID (that muted it)
+	meeting string            // zoom meeting UUID the room is bound to
+
+	invites   map[string]types.Invite // invite ID -> outstanding invite
+	inviteKey []byte
 }
 
-func New() *State {
-	return &State{
-		banned: make(map[string]string),
-		locked: make(map[string]string),
+// New loads persisted bans, locks, mutes and invites from the store.
+func New(store Store) (*State, error) {
+	data, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{
+		logger: log.With().Str("module", "websocket").Str("submodule", "state").Logger(),
+		store:  store,
+		banned: make(map[string]types.Ban),
+		bans:   newBanIndex(),
+		locked: data.Locks,
+		muted:  data.Mutes,
+
+		invites:   data.Invites,
+		inviteKey: data.InviteKey,
+	}
+
+	for key, ban := range data.Bans {
+		target, err := NormalizeBanTarget(key)
+		if err != nil {
+			s.logger.Warn().Str("target", key).Msg("ignoring invalid persisted ban")
+			continue
+		}
+
+		ban.Target = target
+		s.banned[target] = ban
+		s.bans.add(target)
+	}
+
+	return s, nil
+}
+
+func (s *State) Close() error {
+	s.flush()
+	return s.store.Close()
+}
+
+// persist queues a copy of the change, must be called with lock held.
+func (s *State) persist(change Change) {
+	s.pending = append(s.pending, change)
+}
+
+// flush writes queued changes in order, must be called without lock held.
+// It does not fail, state is kept in memory even if store is not available.
+func (s *State) flush() {
+	// readers, that did not change anything, do not wait for writes
+	s.mu.Lock()
+	empty := len(s.pending) == 0
+	s.mu.Unlock()
+
+	if empty {
+		return
+	}
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		pending := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		if len(pending) == 0 {
+			return
+		}
+
+		for _, change := range pending {
+			if err := s.store.Apply(change); err != nil {
+				s.logger.Warn().Err(err).Str("op", change.Op).Str("key", change.Key).Msg("unable to persist state")
+			}
+		}
 	}
 }
 
 // Ban
 
-func (s *State) Ban(ip, id string) {
-	s.banned[ip] = id
+// Ban adds or replaces ban, target must be normalized using NormalizeBanTarget.
+func (s *State) Ban(target string, ban types.Ban) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ban.Target = target
+	s.banned[target] = ban
+	s.bans.add(target)
+	s.persist(Change{Op: OpBan, Key: target, Ban: &ban})
 }
 
-func (s *State) Unban(ip string) {
-	delete(s.banned, ip)
+func (s *State) Unban(target string) bool {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.unban(target)
+}
+
+func (s *State) unban(target string) bool {
+	if _, ok := s.banned[target]; !ok {
+		return false
+	}
+
+	delete(s.banned, target)
+	s.bans.remove(target)
+	s.persist(Change{Op: OpUnban, Key: target})
+	return true
 }
 
-func (s *State) IsBanned(ip string) bool {
-	_, ok := s.banned[ip]
+// IsBanned checks whether an address or any of identities is banned.
+func (s *State) IsBanned(address string, identities ...string) bool {
+	_, ok := s.GetBanned(address, identities...)
 	return ok
 }
 
-func (s *State) GetBanned(ip string) (string, bool) {
-	id, ok := s.banned[ip]
-	return id, ok
+// GetBanned returns a ban, that applies to an address or any of identities.
+func (s *State) GetBanned(address string, identities ...string) (types.Ban, bool) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, target := range s.bans.match(address, identities) {
+		ban := s.banned[target]
+		if ban.Expired(now) {
+			s.unban(target)
+			continue
+		}
+
+		return ban, true
+	}
+
+	return types.Ban{}, false
 }
 
-func (s *State) AllBanned() map[string]string {
-	return s.banned
+func (s *State) AllBanned() map[string]types.Ban {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	banned := make(map[string]types.Ban, len(s.banned))
+	for target, ban := range s.banned {
+		if ban.Expired(now) {
+			s.unban(target)
+			continue
+		}
+
+		banned[target] = ban
+	}
+
+	return banned
 }
 
 // Lock
 
 func (s *State) Lock(resource, id string) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.locked[resource] = id
+	s.persist(Change{Op: OpLock, Key: resource, ID: id})
 }
 
 func (s *State) Unlock(resource string) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.locked[resource]; !ok {
+		return
+	}
+
 	delete(s.locked, resource)
+	s.persist(Change{Op: OpUnlock, Key: resource})
 }
 
 func (s *State) IsLocked(resource string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	_, ok := s.locked[resource]
 	return ok
 }
 
 func (s *State) GetLocked(resource string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	id, ok := s.locked[resource]
 	return id, ok
 }
 
 func (s *State) AllLocked() map[string]string {
-	return s.locked
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locked := make(map[string]string, len(s.locked))
+	for resource, id := range s.locked {
+		locked[resource] = id
+	}
+
+	return locked
+}
+
+// Mute
+
+func (s *State) Mute(username, id string) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.muted[username] = id
+	s.persist(Change{Op: OpMute, Key: username, ID: id})
+}
+
+func (s *State) Unmute(username string) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.muted[username]; !ok {
+		return
+	}
+
+	delete(s.muted, username)
+	s.persist(Change{Op: OpUnmute, Key: username})
+}
+
+func (s *State) IsMuted(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.muted[username]
+	return ok
+}
+
+// Meeting
+
+// BindMeeting binds room to a meeting, only if it is not bound yet.
+func (s *State) BindMeeting(uuid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.meeting != "" {
+		return false
+	}
+
+	s.meeting = uuid
+	return true
+}
+
+// UnbindMeeting returns previously bound meeting.
+func (s *State) UnbindMeeting() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meeting := s.meeting
+	s.meeting = ""
+	return meeting, meeting != ""
+}
+
+// EndMeeting unbinds room, only if it is bound to the meeting.
+func (s *State) EndMeeting(uuid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.meeting == "" || s.meeting != uuid {
+		return false
+	}
+
+	s.meeting = ""
+	return true
+}
+
+func (s *State) GetMeeting() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.meeting, s.meeting != ""
+}
+
+// Invite
+
+// InviteKey returns persisted key, that signs invite tokens.
+func (s *State) InviteKey() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.inviteKey
+}
+
+func (s *State) SetInviteKey(key []byte) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inviteKey = key
+	s.persist(Change{Op: OpInviteKey, InviteKey: key})
+}
+
+func (s *State) StoredInvites() []types.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invites := make([]types.Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+
+	return invites
+}
+
+// StoreInvite adds or replaces invite.
+func (s *State) StoreInvite(invite types.Invite) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invites[invite.ID] = invite
+	s.persist(Change{Op: OpInvite, Key: invite.ID, Invite: &invite})
+}
+
+func (s *State) RemoveInvite(id string) {
+	defer s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.invites[id]; !ok {
+		return
+	}
+
+	delete(s.invites, id)
+	s.persist(Change{Op: OpRevoke, Key: id})
 }
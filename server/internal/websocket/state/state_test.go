@@ -0,0 +1,128 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"m1k1o/neko/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// blockingStore waits with every write until it is released.
+type blockingStore struct {
+	MemoryStore
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Apply(change Change) error {
+	s.writing <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func TestStateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	state, err := New(NewJournalStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			address := fmt.Sprintf("192.0.2.%d", i)
+			resource := fmt.Sprint("resource", i%4)
+			for j := 0; j < 50; j++ {
+				state.Ban(address, types.Ban{Reason: fmt.Sprint(j)})
+				state.Lock(resource, fmt.Sprint(i))
+				state.Mute(address, fmt.Sprint(i))
+
+				state.IsBanned(address)
+				state.IsLocked(resource)
+				state.IsMuted(address)
+				state.AllBanned()
+				state.AllLocked()
+
+				if j%2 == 0 {
+					state.Unban(address)
+					state.Unlock(resource)
+					state.Unmute(address)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	banned, locked := state.AllBanned(), state.AllLocked()
+	if err := state.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes are persisted in the same order as they were made
+	loaded, err := New(NewJournalStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	if got := loaded.AllBanned(); !reflect.DeepEqual(got, banned) {
+		t.Fatalf("loaded bans = %v, want %v", got, banned)
+	}
+
+	if got := loaded.AllLocked(); !reflect.DeepEqual(got, locked) {
+		t.Fatalf("loaded locks = %v, want %v", got, locked)
+	}
+}
+
+func TestStatePersistUnlocked(t *testing.T) {
+	store := &blockingStore{
+		writing: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	state, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		state.Lock("control", "id")
+		close(done)
+	}()
+
+	<-store.writing
+
+	// readers do not wait for the write
+	read := make(chan bool)
+	go func() {
+		read <- state.IsLocked("control") && !state.IsBanned("192.0.2.1")
+	}()
+
+	select {
+	case ok := <-read:
+		if !ok {
+			t.Fatal("change is not visible before it is persisted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader waits for the store")
+	}
+
+	close(store.release)
+	<-done
+}
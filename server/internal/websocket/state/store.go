@@ -0,0 +1,390 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"m1k1o/neko/internal/types"
+)
+
+const (
+	OpBan    = "ban"
+	OpUnban  = "unban"
+	OpLock   = "lock"
+	OpUnlock = "unlock"
+	OpMute   = "mute"
+	OpUnmute = "unmute"
+
+	OpInvite    = "invite"
+	OpRevoke    = "revoke"
+	OpInviteKey = "invite_key"
+)
+
+// Data is part of the state, that survives restarts.
+type Data struct {
+	Bans  map[string]types.Ban `json:"bans"`  // ban target -> ban record
+	Locks map[string]string    `json:"locks"` // resource name -> session ID (that locked it)
+	Mutes map[string]string    `json:"mutes"` // username -> session ID (that muted it)
+
+	Invites   map[string]types.Invite `json:"invites"`              // invite ID -> outstanding invite
+	InviteKey []byte                  `json:"invite_key,omitempty"` // key, that signs invite tokens
+}
+
+func NewData() *Data {
+	return &Data{
+		Bans:    make(map[string]types.Ban),
+		Locks:   make(map[string]string),
+		Mutes:   make(map[string]string),
+		Invites: make(map[string]types.Invite),
+	}
+}
+
+// Change is a single modification of the data.
+type Change struct {
+	Op  string     `json:"op"`
+	Key string     `json:"key"`
+	ID  string     `json:"id,omitempty"`
+	Ban *types.Ban `json:"ban,omitempty"`
+
+	Invite    *types.Invite `json:"invite,omitempty"`
+	InviteKey []byte        `json:"invite_key,omitempty"`
+}
+
+func (d *Data) Apply(change Change) {
+	switch change.Op {
+	case OpBan:
+		if change.Ban != nil {
+			d.Bans[change.Key] = *change.Ban
+		}
+	case OpUnban:
+		delete(d.Bans, change.Key)
+	case OpLock:
+		d.Locks[change.Key] = change.ID
+	case OpUnlock:
+		delete(d.Locks, change.Key)
+	case OpMute:
+		d.Mutes[change.Key] = change.ID
+	case OpUnmute:
+		delete(d.Mutes, change.Key)
+	case OpInvite:
+		if change.Invite != nil {
+			d.Invites[change.Key] = *change.Invite
+		}
+	case OpRevoke:
+		delete(d.Invites, change.Key)
+	case OpInviteKey:
+		d.InviteKey = change.InviteKey
+	}
+}
+
+// changes returns list of changes, that recreate the data.
+func (d *Data) changes() []Change {
+	changes := []Change{}
+	for key, ban := range d.Bans {
+		ban := ban
+		changes = append(changes, Change{Op: OpBan, Key: key, Ban: &ban})
+	}
+	for key, id := range d.Locks {
+		changes = append(changes, Change{Op: OpLock, Key: key, ID: id})
+	}
+	for key, id := range d.Mutes {
+		changes = append(changes, Change{Op: OpMute, Key: key, ID: id})
+	}
+	for key, invite := range d.Invites {
+		invite := invite
+		changes = append(changes, Change{Op: OpInvite, Key: key, Invite: &invite})
+	}
+	if len(d.InviteKey) > 0 {
+		changes = append(changes, Change{Op: OpInviteKey, InviteKey: d.InviteKey})
+	}
+	return changes
+}
+
+func (d *Data) len() int {
+	return len(d.Bans) + len(d.Locks) + len(d.Mutes) + len(d.Invites)
+}
+
+// Store persists the state.
+type Store interface {
+	Load() (*Data, error)
+	Apply(change Change) error
+	Close() error
+}
+
+//
+// memory store
+//
+
+// MemoryStore does not persist anything, state is lost on restart.
+type MemoryStore struct{}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Load() (*Data, error) {
+	return NewData(), nil
+}
+
+func (s *MemoryStore) Apply(change Change) error {
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+//
+// file store
+//
+
+// FileStore keeps whole state in a JSON file, that is rewritten on every change.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	data *Data
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+		data: NewData(),
+	}
+}
+
+// Load reads state from JSON file, file is created on first write.
+func (s *FileStore) Load() (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s.copy(), nil
+		}
+		return nil, err
+	}
+
+	if len(raw) > 0 {
+		data := NewData()
+		if err := json.Unmarshal(raw, data); err != nil {
+			return nil, err
+		}
+
+		// replayed, so that missing or null maps stay initialized
+		for _, change := range data.changes() {
+			s.data.Apply(change)
+		}
+	}
+
+	return s.copy(), nil
+}
+
+func (s *FileStore) Apply(change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Apply(change)
+
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.path, raw)
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+func (s *FileStore) copy() *Data {
+	data := NewData()
+	for _, change := range s.data.changes() {
+		data.Apply(change)
+	}
+	return data
+}
+
+//
+// journal store
+//
+
+// journal is compacted when it has more entries than this
+// and more than journalCompactRatio times of live records
+const (
+	journalCompactMin   = 1000
+	journalCompactRatio = 4
+)
+
+// JournalStore is an embedded append-only log of changes. Every change is
+// appended as a JSON line and synced to disk, so writes stay cheap with
+// many bans. Log is replayed on load and compacted to live records.
+type JournalStore struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	data    *Data
+	entries int
+}
+
+func NewJournalStore(path string) *JournalStore {
+	return &JournalStore{
+		path: path,
+		data: NewData(),
+	}
+}
+
+func (s *JournalStore) Load() (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	lines := bytes.Split(raw, []byte{'\n'})
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+
+		change := Change{}
+		if err := json.Unmarshal(line, &change); err != nil {
+			// line written partially by a crash is the last one and it is
+			// not terminated, it is dropped
+			if i == len(lines)-1 {
+				break
+			}
+
+			// journal is not compacted, so that no data is lost
+			return nil, fmt.Errorf("invalid journal entry at line %d: %w", i+1, err)
+		}
+
+		s.data.Apply(change)
+	}
+
+	// start with a clean journal
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	data := NewData()
+	for _, change := range s.data.changes() {
+		data.Apply(change)
+	}
+
+	return data, nil
+}
+
+func (s *JournalStore) Apply(change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+
+	s.data.Apply(change)
+
+	raw, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	s.entries++
+	if s.entries > journalCompactMin && s.entries > journalCompactRatio*s.data.len() {
+		return s.compact()
+	}
+
+	return nil
+}
+
+func (s *JournalStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// compact writes live records to a new journal and replaces the old one.
+// Must be called with lock held.
+func (s *JournalStore) compact() error {
+	changes := s.data.changes()
+
+	raw := []byte{}
+	for _, change := range changes {
+		line, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+
+		raw = append(raw, line...)
+		raw = append(raw, '\n')
+	}
+
+	if err := writeFile(s.path, raw); err != nil {
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		s.file = nil
+		return err
+	}
+
+	s.file = file
+	s.entries = len(changes)
+	return nil
+}
+
+// writeFile writes data to temporary file and renames it, so that
+// file is never left half written.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,247 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+var testStores = []struct {
+	name string
+	new  func(path string) Store
+}{
+	{"file", func(path string) Store { return NewFileStore(path) }},
+	{"journal", func(path string) Store { return NewJournalStore(path) }},
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expiresAt := createdAt.Add(100 * 365 * 24 * time.Hour)
+
+	for _, test := range testStores {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state")
+
+			state, err := New(test.new(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			state.Ban("192.0.2.1", types.Ban{Reason: "spam", CreatedBy: "admin", CreatedAt: createdAt})
+			state.Ban("198.51.100.0/24", types.Ban{CreatedAt: createdAt, ExpiresAt: &expiresAt})
+			state.Ban("192.0.2.2", types.Ban{CreatedAt: createdAt})
+			state.Unban("192.0.2.2")
+			state.Lock("control", "session")
+			state.Lock("file_transfer", "session")
+			state.Unlock("file_transfer")
+			state.Mute("viewer", "session")
+			state.SetInviteKey([]byte("key"))
+			state.StoreInvite(types.Invite{ID: "invite", Role: types.RoleViewer, CreatedAt: createdAt, ExpiresAt: expiresAt})
+			state.StoreInvite(types.Invite{ID: "revoked", CreatedAt: createdAt, ExpiresAt: expiresAt})
+			state.RemoveInvite("revoked")
+
+			banned, locked, invites := state.AllBanned(), state.AllLocked(), state.StoredInvites()
+			if err := state.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := New(test.new(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer loaded.Close()
+
+			if got := loaded.AllBanned(); !reflect.DeepEqual(got, banned) || len(got) != 2 {
+				t.Fatalf("loaded bans = %v, want %v", got, banned)
+			}
+
+			if got := loaded.AllLocked(); !reflect.DeepEqual(got, locked) || len(got) != 1 {
+				t.Fatalf("loaded locks = %v, want %v", got, locked)
+			}
+
+			if !loaded.IsMuted("viewer") {
+				t.Fatal("mute is not loaded")
+			}
+
+			if got := loaded.StoredInvites(); !reflect.DeepEqual(got, invites) || len(got) != 1 {
+				t.Fatalf("loaded invites = %v, want %v", got, invites)
+			}
+
+			if got := loaded.InviteKey(); string(got) != "key" {
+				t.Fatalf("loaded invite key = %q", got)
+			}
+		})
+	}
+}
+
+func TestStoreDefaultLocks(t *testing.T) {
+	conf := config.WebSocket{Locks: []string{"login", "control"}}
+
+	for _, test := range testStores {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state")
+
+			state, err := New(test.new(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// default locks are made on behalf of no session
+			for _, lock := range conf.Locks {
+				state.Lock(lock, "")
+			}
+
+			if err := state.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := New(test.new(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer loaded.Close()
+
+			for _, lock := range conf.Locks {
+				if id, ok := loaded.GetLocked(lock); !ok || id != "" {
+					t.Fatalf("lock %s = %q, %v, want default lock", lock, id, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreMissing(t *testing.T) {
+	data, err := NewFileStore(filepath.Join(t.TempDir(), "state.json")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.len() != 0 || data.Bans == nil || data.Locks == nil || data.Mutes == nil || data.Invites == nil {
+		t.Fatalf("data = %+v, want empty", data)
+	}
+}
+
+func TestJournalStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// journal with superseded changes is compacted on load
+	journal := []byte(`{"op":"lock","key":"control","id":"a"}
+{"op":"lock","key":"control","id":"b"}
+{"op":"mute","key":"viewer","id":"a"}
+{"op":"unmute","key":"viewer"}
+{"op":"lock","key":"login","id":"a"}
+`)
+	if err := os.WriteFile(path, journal, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewJournalStore(path)
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"control": "b", "login": "a"}
+	if !reflect.DeepEqual(data.Locks, want) || len(data.Mutes) != 0 {
+		t.Fatalf("loaded data = %+v, want locks %v", data, want)
+	}
+
+	if lines := journalLines(t, path); lines != 2 {
+		t.Fatalf("journal has %d lines after load, want 2", lines)
+	}
+
+	// journal is compacted once it grows with superseded changes
+	for i := 0; i <= journalCompactMin; i++ {
+		if err := store.Apply(Change{Op: OpMute, Key: "viewer", ID: "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if lines := journalLines(t, path); lines > journalCompactMin {
+		t.Fatalf("journal has %d lines, it is not compacted", lines)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = NewJournalStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(data.Locks, want) || data.Mutes["viewer"] != "a" {
+		t.Fatalf("replayed data = %+v", data)
+	}
+}
+
+func TestJournalStoreTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// last line was written partially by a crash
+	journal := []byte(`{"op":"lock","key":"control","id":"a"}
+{"op":"lock","key":"login","id":"a"}
+{"op":"mute","key":"vie`)
+	if err := os.WriteFile(path, journal, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewJournalStore(path)
+	defer store.Close()
+
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Locks) != 2 || len(data.Mutes) != 0 {
+		t.Fatalf("loaded data = %+v", data)
+	}
+
+	if lines := journalLines(t, path); lines != 2 {
+		t.Fatalf("journal has %d lines, want 2", lines)
+	}
+}
+
+func TestJournalStoreCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// entries after a corrupted line must not be lost
+	journal := []byte(`{"op":"lock","key":"control","id":"a"}
+{"op":"lock",
+{"op":"lock","key":"login","id":"a"}
+`)
+	if err := os.WriteFile(path, journal, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewJournalStore(path).Load(); err == nil {
+		t.Fatal("corrupted journal loaded")
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw, journal) {
+		t.Fatalf("corrupted journal was rewritten: %s", raw)
+	}
+}
+
+func journalLines(t *testing.T, path string) int {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Count(raw, []byte{'\n'})
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -22,15 +23,43 @@ import (
 
 const CONTROL_PROTECTION_SESSION = "by_control_protection"
 
-func New(sessions types.SessionManager, desktop types.DesktopManager, capture types.CaptureManager, webrtc types.WebRTCManager, conf *config.WebSocket) *WebSocketHandler {
+func New(sessions types.SessionManager, desktop types.DesktopManager, capture types.CaptureManager, webrtc types.WebRTCManager, zoom types.ZoomManager, auth types.AuthProvider, invites types.InviteManager, conf *config.WebSocket) *WebSocketHandler {
 	logger := log.With().Str("module", "websocket").Logger()
 
-	state := state.New()
+	var store state.Store
+	switch conf.StateStore {
+	case "file":
+		store = state.NewFileStore(conf.StateFile)
+	case "journal":
+		store = state.NewJournalStore(conf.StateFile)
+	default:
+		store = state.NewMemoryStore()
+	}
+
+	state, err := state.New(store)
+	if err != nil {
+		logger.Panic().Err(err).Str("path", conf.StateFile).Msg("unable to load state")
+	}
+
+	if conf.StateStore != "memory" {
+		logger.Info().
+			Str("store", conf.StateStore).
+			Str("path", conf.StateFile).
+			Int("bans", len(state.AllBanned())).
+			Interface("locks", state.AllLocked()).
+			Msg("loaded persisted state")
+	}
+
+	// outstanding invites survive restarts together with the state
+	invites.SetStore(state)
 
 	// if control protection is enabled
 	if conf.ControlProtection {
 		state.Lock("control", CONTROL_PROTECTION_SESSION)
 		logger.Info().Msgf("control locked on behalf of control protection")
+	} else if id, ok := state.GetLocked("control"); ok && id == CONTROL_PROTECTION_SESSION {
+		// persisted lock of control protection, that is not enabled anymore
+		state.Unlock("control")
 	}
 
 	// apply default locks
@@ -42,11 +71,18 @@ func New(sessions types.SessionManager, desktop types.DesktopManager, capture ty
 		logger.Info().Msgf("locked resources: %+v", conf.Locks)
 	}
 
+	// TODO: Handle locks in sessions as flags.
+	if state.IsLocked("control") {
+		sessions.SetControlLocked(true)
+	}
+
 	handler := handler.New(
 		sessions,
 		desktop,
 		capture,
 		webrtc,
+		auth,
+		invites,
 		state,
 	)
 
@@ -57,13 +93,19 @@ func New(sessions types.SessionManager, desktop types.DesktopManager, capture ty
 		sessions: sessions,
 		desktop:  desktop,
 		webrtc:   webrtc,
+		zoom:     zoom,
+		auth:     auth,
+		invites:  invites,
 		state:    state,
 		upgrader: websocket.Upgrader{
+			// origin matters only for session cookie, it is checked in Authenticate
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
 		handler:         handler,
+		logins:          map[string]loginSession{},
+		failures:        map[string]*loginFailures{},
 		serverStartedAt: time.Now(),
 	}
 }
@@ -79,13 +121,23 @@ type WebSocketHandler struct {
 	sessions types.SessionManager
 	desktop  types.DesktopManager
 	webrtc   types.WebRTCManager
+	zoom     types.ZoomManager
+	auth     types.AuthProvider
+	invites  types.InviteManager
 	state    *state.State
 	conf     *config.WebSocket
 	handler  *handler.MessageHandler
 
+	logins        map[string]loginSession
+	loginsMu      sync.Mutex
+	failures      map[string]*loginFailures
+	failuresMu    sync.Mutex
+	queryAuthOnce sync.Once
+
 	// stats
 	conns           uint32
 	serverStartedAt time.Time
+	statsMu         sync.Mutex
 	lastAdminLeftAt *time.Time
 	lastUserLeftAt  *time.Time
 }
@@ -125,11 +177,13 @@ func (ws *WebSocketHandler) Start() {
 		}
 
 		// remove outdated stats
+		ws.statsMu.Lock()
 		if session.Admin() {
 			ws.lastAdminLeftAt = nil
 		} else {
 			ws.lastUserLeftAt = nil
 		}
+		ws.statsMu.Unlock()
 	})
 
 	ws.sessions.OnDestroy(func(id string, session types.Session) {
@@ -161,6 +215,9 @@ func (ws *WebSocketHandler) Start() {
 			}
 		}
 
+		ws.statsMu.Lock()
+		defer ws.statsMu.Unlock()
+
 		// if this was the last admin
 		if session.Admin() && adminCount == 0 {
 			now := time.Now()
@@ -174,9 +231,19 @@ func (ws *WebSocketHandler) Start() {
 		}
 	})
 
+	ws.sessions.OnControlQueue(func(queue types.ControlQueue) {
+		ws.handler.ControlQueueChanged(queue)
+	})
+
+	ws.sessions.OnControlExpired(func(id string, reason string) {
+		if err := ws.handler.ControlExpired(id, reason); err != nil {
+			ws.logger.Warn().Str("id", id).Err(err).Msg("releasing expired control has failed")
+		}
+	})
+
 	ws.desktop.OnClipboardUpdated(func() {
 		session, ok := ws.sessions.GetHost()
-		if !ok {
+		if !ok || !session.Permissions().CanUseClipboard {
 			return
 		}
 
@@ -187,12 +254,16 @@ func (ws *WebSocketHandler) Start() {
 
 		ws.logger.Err(err).Msg("sync clipboard")
 	})
+
+	if ws.zoom.Enabled() {
+		ws.zoomStart()
+	}
 }
 
 func (ws *WebSocketHandler) Shutdown() error {
 	close(ws.shutdown)
 	ws.wg.Wait()
-	return nil
+	return ws.state.Close()
 }
 
 func (ws *WebSocketHandler) Upgrade(w http.ResponseWriter, r *http.Request) error {
@@ -210,7 +281,14 @@ func (ws *WebSocketHandler) Upgrade(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 
-	admin, err := ws.authenticate(r)
+	// session, that lost connection, is resumed without authentication
+	if resume := r.URL.Query().Get("resume"); resume != "" {
+		if ws.resume(connection, r, resume) {
+			return nil
+		}
+	}
+
+	user, identity, err := ws.authenticate(r)
 	if err != nil {
 		ws.logger.Warn().Err(err).Msg("authentication failed")
 
@@ -234,7 +312,16 @@ func (ws *WebSocketHandler) Upgrade(w http.ResponseWriter, r *http.Request) erro
 		connection: connection,
 	}
 
-	ok, reason := ws.handler.Connected(admin, socket.Address())
+	ok, reason := ws.handler.Connected(user, identity, socket.Address())
+
+	// invite is used up only by accepted connections
+	if ok && identity != nil && identity.Provider == "invite" {
+		if err := ws.invites.UseInvite(identity.UserID); err != nil {
+			ws.logger.Debug().Err(err).Str("invite", identity.UserID).Msg("unable to use invite")
+			ok, reason = false, "invalid_invite"
+		}
+	}
+
 	if !ok {
 		if err = connection.WriteJSON(message.SystemMessage{
 			Event:   event.SYSTEM_DISCONNECT,
@@ -250,7 +337,7 @@ func (ws *WebSocketHandler) Upgrade(w http.ResponseWriter, r *http.Request) erro
 		return nil
 	}
 
-	ws.sessions.New(id, admin, socket)
+	ws.sessions.New(id, user, identity, socket)
 
 	ws.logger.
 		Debug().
@@ -270,10 +357,83 @@ func (ws *WebSocketHandler) Upgrade(w http.ResponseWriter, r *http.Request) erro
 		atomic.AddUint32(&ws.conns, ^uint32(0))
 	}()
 
-	ws.handle(connection, id)
+	ws.handle(connection, socket)
 	return nil
 }
 
+// resume attaches connection to an existing session, it returns false if
+// the session cannot be resumed and a new one should be created instead.
+// Resumed session is checked the same way as a new connection.
+func (ws *WebSocketHandler) resume(connection *websocket.Conn, r *http.Request, token string) bool {
+	id := r.URL.Query().Get("id")
+
+	socket := &WebSocket{
+		id:         id,
+		ws:         ws,
+		address:    utils.GetHttpRequestIP(r, ws.conf.Proxy),
+		connection: connection,
+	}
+
+	reason := ""
+	session, err := ws.sessions.Resume(id, token, socket, func(session types.Session) bool {
+		user := &types.User{
+			Username: session.Username(),
+			Role:     session.Role(),
+		}
+
+		var ok bool
+		ok, reason = ws.handler.Connected(user, session.Identity(), socket.Address())
+		return ok
+	})
+
+	if errors.Is(err, types.ErrSessionRejected) {
+		ws.logger.Debug().Str("session", id).Str("reason", reason).Msg("session resume rejected")
+
+		if err = connection.WriteJSON(message.SystemMessage{
+			Event:   event.SYSTEM_DISCONNECT,
+			Message: reason,
+		}); err != nil {
+			ws.logger.Error().Err(err).Msg("failed to send disconnect")
+		}
+
+		if err = connection.Close(); err != nil {
+			ws.logger.Debug().Err(err).Msg("failed to close connection")
+		}
+
+		return true
+	}
+
+	if err != nil {
+		ws.logger.Debug().Err(err).Str("session", id).Msg("unable to resume session")
+		return false
+	}
+
+	ws.logger.
+		Debug().
+		Str("session", id).
+		Str("address", connection.RemoteAddr().String()).
+		Msg("connection resumed")
+
+	if err := ws.handler.SessionResumed(id, session); err != nil {
+		ws.logger.Warn().Str("id", id).Err(err).Msg("session resumed with and error")
+	}
+
+	atomic.AddUint32(&ws.conns, uint32(1))
+
+	defer func() {
+		ws.logger.
+			Debug().
+			Str("session", id).
+			Str("address", connection.RemoteAddr().String()).
+			Msg("session ended")
+
+		atomic.AddUint32(&ws.conns, ^uint32(0))
+	}()
+
+	ws.handle(connection, socket)
+	return true
+}
+
 func (ws *WebSocketHandler) Stats() types.Stats {
 	host := ""
 	session, ok := ws.sessions.GetHost()
@@ -281,20 +441,43 @@ func (ws *WebSocketHandler) Stats() types.Stats {
 		host = session.ID()
 	}
 
+	meeting, _ := ws.state.GetMeeting()
+
+	banned := map[string]string{}
+	for ip, ban := range ws.state.AllBanned() {
+		banned[ip] = ban.CreatedBy
+	}
+
+	// copied, so that returned stats are not changed afterwards
+	ws.statsMu.Lock()
+	var lastAdminLeftAt, lastUserLeftAt *time.Time
+	if ws.lastAdminLeftAt != nil {
+		t := *ws.lastAdminLeftAt
+		lastAdminLeftAt = &t
+	}
+	if ws.lastUserLeftAt != nil {
+		t := *ws.lastUserLeftAt
+		lastUserLeftAt = &t
+	}
+	ws.statsMu.Unlock()
+
 	return types.Stats{
 		Connections: atomic.LoadUint32(&ws.conns),
 		Host:        host,
 		Members:     ws.sessions.Members(),
 
-		Banned: ws.state.AllBanned(),
+		Banned: banned,
 		Locked: ws.state.AllLocked(),
 
+		Meeting: meeting,
+
 		ServerStartedAt: ws.serverStartedAt,
-		LastAdminLeftAt: ws.lastAdminLeftAt,
-		LastUserLeftAt:  ws.lastUserLeftAt,
+		LastAdminLeftAt: lastAdminLeftAt,
+		LastUserLeftAt:  lastUserLeftAt,
 
 		ControlProtection: ws.conf.ControlProtection,
 		ImplicitControl:   ws.webrtc.ImplicitControl(),
+		MultiControl:      ws.webrtc.MultiControl(),
 	}
 }
 
@@ -302,44 +485,82 @@ func (ws *WebSocketHandler) IsLocked(resource string) bool {
 	return ws.state.IsLocked(resource)
 }
 
-func (ws *WebSocketHandler) IsAdmin(password string) (bool, error) {
-	if password == ws.conf.AdminPassword {
-		return true, nil
+func (ws *WebSocketHandler) Admin() types.RoomAdmin {
+	return ws.handler
+}
+
+func (ws *WebSocketHandler) authenticate(r *http.Request) (*types.User, *types.Identity, error) {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return ws.authenticateTicket(ticket)
 	}
 
-	if password == ws.conf.Password {
-		return false, nil
+	if token := r.URL.Query().Get("token"); token != "" {
+		return ws.authenticateInvite(token)
 	}
 
-	return false, fmt.Errorf("invalid password")
+	user, err := ws.Authenticate(r)
+	return user, nil, err
 }
 
-func (ws *WebSocketHandler) authenticate(r *http.Request) (bool, error) {
-	passwords, ok := r.URL.Query()["password"]
-	if !ok || len(passwords[0]) < 1 {
-		return false, fmt.Errorf("no password provided")
+func (ws *WebSocketHandler) authenticateTicket(signed string) (*types.User, *types.Identity, error) {
+	if !ws.zoom.Enabled() {
+		return nil, nil, fmt.Errorf("zoom mode is not enabled")
 	}
 
-	return ws.IsAdmin(passwords[0])
+	ticket, err := ws.zoom.VerifyTicket(signed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := &types.User{Role: types.RoleParticipant}
+	if ticket.Admin {
+		user.Role = types.RoleAdmin
+	}
+
+	return user, &types.Identity{
+		Provider:  "zoom",
+		UserID:    ticket.UserID,
+		MeetingID: ticket.MeetingID,
+		Name:      ticket.Name,
+	}, nil
+}
+
+func (ws *WebSocketHandler) authenticateInvite(token string) (*types.User, *types.Identity, error) {
+	invite, err := ws.invites.VerifyInvite(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &types.User{Role: invite.Role}, &types.Identity{
+		Provider: "invite",
+		UserID:   invite.ID,
+		Name:     invite.Name,
+	}, nil
 }
 
-func (ws *WebSocketHandler) handle(connection *websocket.Conn, id string) {
+func (ws *WebSocketHandler) handle(connection *websocket.Conn, socket *WebSocket) {
+	id := socket.id
 	bytes := make(chan []byte)
 	cancel := make(chan struct{})
 	ticker := time.NewTicker(pingPeriod)
 
+	// client closed the socket on purpose, it is not going to reconnect
+	closed := false
+
 	ws.wg.Add(1)
 	go func() {
 		defer func() {
 			ticker.Stop()
 			ws.logger.Debug().Str("address", connection.RemoteAddr().String()).Msg("handle socket ending")
-			ws.handler.Disconnected(id)
+			ws.handler.Disconnected(id, socket, closed)
 			ws.wg.Done()
 		}()
 
 		for {
 			_, raw, err := connection.ReadMessage()
 			if err != nil {
+				closed = websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived)
+
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					ws.logger.Warn().Err(err).Msg("read message error")
 				} else {
@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"m1k1o/neko/internal/types"
+)
+
+func (ws *WebSocketHandler) zoomStart() {
+	ws.zoom.OnParticipantLeft(func(meeting types.ZoomMeeting, participant types.ZoomParticipant) {
+		if bound, ok := ws.state.GetMeeting(); !ok || bound != meeting.UUID {
+			return
+		}
+
+		for _, member := range ws.sessions.Members() {
+			session, ok := ws.sessions.Get(member.ID)
+			if !ok {
+				continue
+			}
+
+			identity := session.Identity()
+			if identity == nil || identity.Provider != "zoom" || identity.UserID != participant.ID {
+				continue
+			}
+
+			if err := session.Kick("left_meeting"); err != nil {
+				ws.logger.Warn().Err(err).Str("id", member.ID).Msg("unable to kick participant that left meeting")
+			} else {
+				ws.logger.Info().Str("id", member.ID).Msg("participant left meeting, kicked")
+			}
+		}
+	})
+
+	ws.zoom.OnMeetingEnded(func(meeting types.ZoomMeeting) {
+		ws.handler.MeetingEnded(meeting.UUID)
+	})
+}
@@ -0,0 +1,54 @@
+package zoom
+
+import (
+	"context"
+	"fmt"
+)
+
+const SendMeetingChatMessagePath = "/live_meetings/%s/chat/messages"
+
+// MeetingChatMessage is sent to in-meeting chat, either to everyone or
+// to a single participant.
+type MeetingChatMessage struct {
+	Message string `json:"message"`
+	ToUser  string `json:"to_user,omitempty"`
+}
+
+type sendMeetingChatMessageResult struct {
+	ID string `json:"id"`
+}
+
+// SendMeetingChatMessage sends message to chat of a live meeting on behalf
+// of the user that owns the token and returns its ID.
+func (c *Client) SendMeetingChatMessage(ctx context.Context, token string, meetingID string, message MeetingChatMessage) (string, error) {
+	var ret sendMeetingChatMessageResult
+	if err := c.request(ctx, requestV2Opts{
+		Method:         Post,
+		Path:           fmt.Sprintf(SendMeetingChatMessagePath, meetingPath(meetingID)),
+		DataParameters: message,
+		Ret:            &ret,
+	}, token); err != nil {
+		return "", err
+	}
+
+	return ret.ID, nil
+}
+
+const SendAppNotificationPath = "/app/notifications"
+
+// AppNotification is shown to the user in the Zoom client, in the app's
+// notification area or in the meeting the user currently attends.
+type AppNotification struct {
+	UserID string `json:"user_id,omitempty"`
+	Text   string `json:"text"`
+}
+
+// SendAppNotification sends notification of the app to a user.
+func (c *Client) SendAppNotification(ctx context.Context, token string, notification AppNotification) error {
+	return c.request(ctx, requestV2Opts{
+		Method:         Post,
+		Path:           SendAppNotificationPath,
+		DataParameters: notification,
+		HeadResponse:   true,
+	}, token)
+}
@@ -1,65 +1,160 @@
 package zoom
 
 import (
-	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 )
 
-// ZoomContextDecrypter ...
-// context - Encrypted Zoom App Contex (x-zoom-zapp-context)
+const contextTagLength = 16
+
+var (
+	ErrContextEmpty     = errors.New("app context is empty")
+	ErrContextSecret    = errors.New("client secret must be configured to decrypt app context")
+	ErrContextEncoding  = errors.New("app context is not valid base64")
+	ErrContextMalformed = errors.New("app context is malformed")
+	ErrContextDecrypt   = errors.New("app context could not be decrypted")
+	ErrContextPayload   = errors.New("app context payload is not valid")
+	ErrContextExpired   = errors.New("app context expired")
+)
+
+// AppContext is decrypted content of x-zoom-app-context header.
+type AppContext struct {
+	Type       string `json:"typ"`
+	UserID     string `json:"uid"`
+	MeetingID  string `json:"mid"`
+	AttendRole string `json:"attendrole"`
+	Timestamp  int64  `json:"ts"`
+	Expiration int64  `json:"exp"`
+}
+
+// ExpiresAt returns expiration of the context, zero if not set.
+func (c *AppContext) ExpiresAt() time.Time {
+	if c.Expiration == 0 {
+		return time.Time{}
+	}
+
+	// context expiration is in milliseconds
+	return time.UnixMilli(c.Expiration)
+}
+
+// contextReader reads length-prefixed fields and never reads past the buffer.
+type contextReader struct {
+	buf []byte
+}
+
+func (r *contextReader) next(field string, n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf) {
+		return nil, fmt.Errorf("%w: %s length %d exceeds remaining %d bytes", ErrContextMalformed, field, n, len(r.buf))
+	}
+
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out, nil
+}
+
+// ContextDecrypter decrypts Zoom app context.
+// context - Encrypted Zoom App Context (x-zoom-app-context)
 // secretKey - Client Secret Key.
-func ContextDecrypter(context string, secretKey []byte) []byte {
+//
+// Layout: [iv length: 1][iv][aad length: 2 LE][aad][cipher length: 4 LE][cipher][tag: 16]
+func ContextDecrypter(context string, secretKey []byte) ([]byte, error) {
 	ciphertext, err := base64.RawURLEncoding.DecodeString(context)
-	decoder := bytes.NewReader(ciphertext)
-	ivLength := make([]byte, 1)
-	_, _ = decoder.Read(ivLength)
-	iv := make([]byte, ivLength[0])
-	_, _ = decoder.Read(iv)
-	aadLengthBytes := make([]byte, 2)
-	_, _ = decoder.Read(aadLengthBytes)
-	addLength := binary.LittleEndian.Uint16(aadLengthBytes)
-	aad := make([]byte, addLength)
-	_, _ = decoder.Read(aad)
-	cipherLengthBytes := make([]byte, 4)
-	_, _ = decoder.Read(cipherLengthBytes)
-	cipherLength := binary.LittleEndian.Uint32(cipherLengthBytes)
-	encrypted := make([]byte, cipherLength+16)
-	_, _ = decoder.Read(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrContextEncoding, err)
+	}
+
+	r := &contextReader{buf: ciphertext}
+
+	ivLength, err := r.next("iv length", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if ivLength[0] == 0 {
+		return nil, fmt.Errorf("%w: iv is empty", ErrContextMalformed)
+	}
+
+	iv, err := r.next("iv", int(ivLength[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	aadLength, err := r.next("aad length", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := r.next("aad", int(binary.LittleEndian.Uint16(aadLength)))
+	if err != nil {
+		return nil, err
+	}
+
+	cipherLength, err := r.next("cipher length", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare as uint64, so that it does not overflow int on 32-bit platforms
+	length := uint64(binary.LittleEndian.Uint32(cipherLength))
+	if length+contextTagLength != uint64(len(r.buf)) {
+		return nil, fmt.Errorf("%w: cipher length %d does not match remaining %d bytes", ErrContextMalformed, length, len(r.buf))
+	}
+
+	encrypted := r.buf
+
 	hashed := sha256.Sum256(secretKey)
 	block, err := aes.NewCipher(hashed[:])
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
-	aesgcm, err := cipher.NewGCM(block)
+
+	aesgcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
+
 	plaintext, err := aesgcm.Open(nil, iv, encrypted, aad)
 	if err != nil {
-		panic(err.Error())
+		return nil, ErrContextDecrypt
 	}
-	return plaintext
+
+	return plaintext, nil
 }
 
-func GetAppContext(header string, secret string) (string, error) {
+// GetAppContext decrypts and parses context header, expired context is rejected.
+func GetAppContext(header string, secret string) (*AppContext, error) {
 	if len(header) == 0 {
-		return "", fmt.Errorf("context header must be a valid string")
+		return nil, ErrContextEmpty
+	}
+
+	if len(secret) == 0 {
+		return nil, ErrContextSecret
 	}
 
-	var key string
-	if len(secret) > 0 {
-		key = secret		
-	} else {
-		key = "Y6edLK2mnAbDC78bOgoxgQt7DdQay03i";
+	decrypted, err := ContextDecrypter(header, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	appContext := &AppContext{}
+	if err := json.Unmarshal(decrypted, appContext); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrContextPayload, err)
 	}
 
-	// Decode and parse context
-	decrypted := ContextDecrypter(header, []byte(key))
+	if appContext.UserID == "" {
+		return nil, fmt.Errorf("%w: missing uid", ErrContextPayload)
+	}
+
+	if expiresAt := appContext.ExpiresAt(); !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		return nil, ErrContextExpired
+	}
 
-	return string(decrypted), nil
+	return appContext, nil
 }
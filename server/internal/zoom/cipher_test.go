@@ -0,0 +1,179 @@
+package zoom
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testSecret = "client-secret"
+
+// sealContext encrypts payload the same way Zoom does for x-zoom-app-context
+// and returns raw layout, so that tests can corrupt it.
+func sealContext(t testing.TB, secret string, payload []byte) []byte {
+	t.Helper()
+
+	hashed := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv := []byte("0123456789ab")
+	aad := []byte("aad")
+	sealed := aesgcm.Seal(nil, iv, payload, aad)
+	encrypted, tag := sealed[:len(sealed)-contextTagLength], sealed[len(sealed)-contextTagLength:]
+
+	out := []byte{byte(len(iv))}
+	out = append(out, iv...)
+	aadLength := make([]byte, 2)
+	binary.LittleEndian.PutUint16(aadLength, uint16(len(aad)))
+	out = append(out, aadLength...)
+	out = append(out, aad...)
+	cipherLength := make([]byte, 4)
+	binary.LittleEndian.PutUint32(cipherLength, uint32(len(encrypted)))
+	out = append(out, cipherLength...)
+	out = append(out, encrypted...)
+	return append(out, tag...)
+}
+
+func encodeContext(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func contextPayload(t testing.TB, appContext AppContext) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(appContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return payload
+}
+
+func TestContextDecrypter(t *testing.T) {
+	payload := []byte(`{"uid":"user"}`)
+	raw := sealContext(t, testSecret, payload)
+
+	// offsets of fields in sealed layout
+	ivEnd := 1 + int(raw[0])
+	aadEnd := ivEnd + 2 + int(binary.LittleEndian.Uint16(raw[ivEnd:]))
+
+	badTag := append([]byte{}, raw...)
+	badTag[len(badTag)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		context string
+		secret  string
+		err     error
+	}{
+		{"valid", encodeContext(raw), testSecret, nil},
+		{"bad base64", "!" + encodeContext(raw), testSecret, ErrContextEncoding},
+		{"empty", "", testSecret, ErrContextMalformed},
+		{"empty iv", encodeContext([]byte{0}), testSecret, ErrContextMalformed},
+		{"truncated iv", encodeContext(raw[:ivEnd-1]), testSecret, ErrContextMalformed},
+		{"truncated aad length", encodeContext(raw[:ivEnd+1]), testSecret, ErrContextMalformed},
+		{"truncated aad", encodeContext(raw[:aadEnd-1]), testSecret, ErrContextMalformed},
+		{"truncated cipher length", encodeContext(raw[:aadEnd+3]), testSecret, ErrContextMalformed},
+		{"truncated cipher", encodeContext(raw[:len(raw)-contextTagLength-1]), testSecret, ErrContextMalformed},
+		{"trailing bytes", encodeContext(append(append([]byte{}, raw...), 0)), testSecret, ErrContextMalformed},
+		{"bad tag", encodeContext(badTag), testSecret, ErrContextDecrypt},
+		{"wrong secret", encodeContext(raw), "other-secret", ErrContextDecrypt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := ContextDecrypter(tt.context, []byte(tt.secret))
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if string(plaintext) != string(payload) {
+					t.Fatalf("plaintext = %q, want %q", plaintext, payload)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGetAppContext(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixMilli()
+	past := time.Now().Add(-time.Minute).UnixMilli()
+
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		err    error
+	}{
+		{"valid", encodeContext(sealContext(t, testSecret, contextPayload(t, AppContext{UserID: "user", Expiration: future}))), testSecret, nil},
+		{"without exp", encodeContext(sealContext(t, testSecret, contextPayload(t, AppContext{UserID: "user"}))), testSecret, nil},
+		{"expired exp", encodeContext(sealContext(t, testSecret, contextPayload(t, AppContext{UserID: "user", Expiration: past}))), testSecret, ErrContextExpired},
+		{"missing uid", encodeContext(sealContext(t, testSecret, contextPayload(t, AppContext{Expiration: future}))), testSecret, ErrContextPayload},
+		{"invalid json", encodeContext(sealContext(t, testSecret, []byte("{"))), testSecret, ErrContextPayload},
+		{"empty header", "", testSecret, ErrContextEmpty},
+		{"empty secret", "header", "", ErrContextSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appContext, err := GetAppContext(tt.header, tt.secret)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if appContext.UserID != "user" {
+					t.Fatalf("uid = %q, want %q", appContext.UserID, "user")
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func FuzzContextDecrypter(f *testing.F) {
+	raw := sealContext(f, testSecret, []byte(`{"uid":"user"}`))
+	f.Add(encodeContext(raw))
+	f.Add(encodeContext(raw[:len(raw)/2]))
+	f.Add(encodeContext([]byte{0xff, 0xff, 0xff}))
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, context string) {
+		// must never panic, every failure is one of typed errors
+		plaintext, err := ContextDecrypter(context, []byte(testSecret))
+		if err == nil {
+			if plaintext == nil {
+				t.Fatal("no error and no plaintext")
+			}
+			return
+		}
+
+		if !errors.Is(err, ErrContextEncoding) && !errors.Is(err, ErrContextMalformed) && !errors.Is(err, ErrContextDecrypt) {
+			t.Fatalf("untyped error: %v", err)
+		}
+	})
+}
@@ -2,15 +2,19 @@ package zoom
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
-	"net/url"
+	"sync"
 	"time"
+
+	"github.com/google/go-querystring/query"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
-	"github.com/google/go-querystring/query"
+	"m1k1o/neko/internal/config"
 )
 
 const apiVersion = "/v2"
@@ -36,26 +40,37 @@ const (
 
 type Client struct {
 	Transport http.RoundTripper
-	Timeout time.Duration
+	Timeout   time.Duration
+
+	// MaxRetries is how many times a request is repeated when it was rate
+	// limited or (for GET requests) when it failed on server side.
+	MaxRetries int
+	// RetryWait is initial backoff, it doubles with each retry up to MaxRetryWait.
+	// Requests that should be retried after MaxRetryWait are not retried at all.
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+
 	endpoint string
-	logger zerolog.Logger
-}
+	logger   zerolog.Logger
+	conf     *config.Zoom
 
+	// requests are held until rate limit reported by Zoom is reset
+	limitedUntil time.Time
+	limitedMu    sync.Mutex
+}
 
 // NewClient returns a new API client
-func NewClient() *Client {
-	
+func NewClient(conf *config.Zoom) *Client {
 	logger := log.With().Str("module", "zoom").Logger()
-	
-	var uri = url.URL{
-		Scheme: "https",
-		Host: "api.zoom.us",
-		Path: apiVersion,
-	}
 
 	return &Client{
-		endpoint: uri.String(),
-		logger: logger,
+		MaxRetries:   3,
+		RetryWait:    500 * time.Millisecond,
+		MaxRetryWait: 30 * time.Second,
+
+		endpoint: conf.APIHost + apiVersion,
+		logger:   logger,
+		conf:     conf,
 	}
 }
 
@@ -70,7 +85,6 @@ type requestV2Opts struct {
 	HeadResponse bool
 }
 
-
 func (c *Client) httpClient() *http.Client {
 	client := &http.Client{Transport: c.Transport}
 	if c.Timeout > 0 {
@@ -80,15 +94,14 @@ func (c *Client) httpClient() *http.Client {
 	return client
 }
 
-// func (c *Client) tokenRequest(opts requestV2Opts, id string, secret string) ()
-
-
-func (c *Client) httpRequest(opts requestV2Opts) (*http.Request, error) {
+func (c *Client) httpRequest(ctx context.Context, opts requestV2Opts) (*http.Request, error) {
 	var buf bytes.Buffer
 
 	// encode body parameters if any
-	if err := json.NewEncoder(&buf).Encode(&opts.DataParameters); err != nil {
-		return nil, err
+	if opts.DataParameters != nil {
+		if err := json.NewEncoder(&buf).Encode(&opts.DataParameters); err != nil {
+			return nil, err
+		}
 	}
 
 	// set URL parameters
@@ -104,17 +117,17 @@ func (c *Client) httpRequest(opts requestV2Opts) (*http.Request, error) {
 	}
 
 	// create HTTP request
-	return http.NewRequest(string(opts.Method), requestURL, &buf)
+	return http.NewRequestWithContext(ctx, string(opts.Method), requestURL, &buf)
 }
 
-func (c *Client) executeRequest(opts requestV2Opts, token string) (*http.Response, error) {
+func (c *Client) executeRequest(ctx context.Context, opts requestV2Opts, token string) (*http.Response, error) {
 	client := c.httpClient()
-	request, err := c.httpRequest(opts)
+	request, err := c.httpRequest(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 	req, err := c.addRequestAuth(request, token)
-	
+
 	if err != nil {
 		return nil, err
 	}
@@ -132,89 +145,274 @@ func (c *Client) addRequestAuth(req *http.Request, token string) (*http.Request,
 	return req, nil
 }
 
+// request executes API request and unmarshals response into opts.Ret. Rate
+// limited requests are retried after time requested by Zoom, other failures
+// of idempotent requests are retried with exponential backoff.
+func (c *Client) request(ctx context.Context, opts requestV2Opts, token string) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.waitRateLimit(ctx); err != nil {
+			return err
+		}
+
+		wait, err := c.attempt(ctx, opts, token)
+		if err == nil {
+			return nil
+		}
+
+		if wait < 0 || attempt >= c.MaxRetries {
+			return err
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+
+		c.logger.Debug().Err(err).
+			Str("path", opts.Path).
+			Int("attempt", attempt+1).
+			Dur("wait", wait).
+			Msg("request failed, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt executes request once, on failure it returns how long to wait
+// before retrying: zero for default backoff, negative if it must not be retried.
+func (c *Client) attempt(ctx context.Context, opts requestV2Opts, token string) (time.Duration, error) {
+	response, err := c.executeRequest(ctx, opts, token)
+	if err != nil {
+		if ctx.Err() != nil || opts.Method != Get {
+			return -1, err
+		}
+
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	c.rateLimit(rateLimitWait(response.Header, time.Now()))
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := checkResponse(response, body); err != nil {
+		switch {
+		case err.Status == http.StatusTooManyRequests:
+			c.rateLimit(err.RetryAfter)
+
+			// zoom did not process the request, it is safe to repeat it
+			if err.RetryAfter > c.MaxRetryWait {
+				return -1, err
+			}
+			return err.RetryAfter, err
+		case err.Status >= 500 && opts.Method == Get:
+			return 0, err
+		default:
+			return -1, err
+		}
+	}
+
+	if opts.Ret == nil || opts.HeadResponse || len(body) == 0 {
+		return 0, nil
+	}
+
+	// Unmarshall into the result
+	if err := json.Unmarshal(body, opts.Ret); err != nil {
+		return -1, err
+	}
+
+	return 0, nil
+}
+
+// rateLimit holds following requests for given duration.
+func (c *Client) rateLimit(wait time.Duration) {
+	if wait <= 0 {
+		return
+	}
+
+	c.limitedMu.Lock()
+	defer c.limitedMu.Unlock()
+
+	if until := time.Now().Add(wait); until.After(c.limitedUntil) {
+		c.limitedUntil = until
+	}
+}
+
+// waitRateLimit waits until rate limit is reset, requests that would wait
+// longer than MaxRetryWait fail right away.
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	c.limitedMu.Lock()
+	wait := time.Until(c.limitedUntil)
+	c.limitedMu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	if wait > c.MaxRetryWait {
+		return &APIError{
+			Status:     http.StatusTooManyRequests,
+			RetryAfter: wait,
+			Code:       http.StatusTooManyRequests,
+			Message:    "rate limit reached",
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.RetryWait << attempt
+	if wait <= 0 || wait > c.MaxRetryWait {
+		wait = c.MaxRetryWait
+	}
+
+	// add jitter, so that concurrent requests do not retry at once
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half))
+	}
+
+	return wait
+}
 
 const GetTokenPath = "/oauth/token"
 
 // GetTokenOptions are the options for creating and getting an access token
 type GetTokenOptions struct {
-	Code string `url:"code"`
-	GrantType string `url:"grant_type"`
-	RedirectUri string `url:"redirect_uri"`
+	Code         string `url:"code,omitempty"`
+	CodeVerifier string `url:"code_verifier,omitempty"`
+	GrantType    string `url:"grant_type"`
+	RedirectUri  string `url:"redirect_uri,omitempty"`
+	RefreshToken string `url:"refresh_token,omitempty"`
 }
 
-
 type AccessTokenResult struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn int `json:"expires_in"`
-	Scope string `json:"scope"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
 }
 
+// GetToken exchanges authorization code (and its PKCE verifier) for a token.
+func (c *Client) GetToken(ctx context.Context, code string, verifier string) (*Token, error) {
+	c.logger.Debug().Msg("retrieving access token")
 
-func (c *Client) GetToken(code string) (string, error) {
-
-	c.logger.Info().Msg("Retrieving access token")
+	return c.tokenRequest(ctx, GetTokenOptions{
+		Code:         code,
+		CodeVerifier: verifier,
+		GrantType:    "authorization_code",
+		RedirectUri:  c.conf.RedirectURL,
+	})
+}
 
-	var buf bytes.Buffer
+// RefreshToken exchanges refresh token for a new token.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
+	c.logger.Debug().Msg("refreshing access token")
 
-	tokenOptions := GetTokenOptions{
-		Code: code,
-		GrantType: "authorization_code",
-		RedirectUri: "https://3c97-137-220-76-2.eu.ngrok.io/auth",
-	}
+	return c.tokenRequest(ctx, GetTokenOptions{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+	})
+}
 
+func (c *Client) tokenRequest(ctx context.Context, tokenOptions GetTokenOptions) (*Token, error) {
 	values, err := query.Values(tokenOptions)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// set request URL
-	requestURL := "https://zoom.us/oauth/token"
+	requestURL := c.conf.Host + GetTokenPath
 	if len(values) > 0 {
 		requestURL += "?" + values.Encode()
 	}
 
-	c.logger.Info().Msgf("Request URL: %s", requestURL)
-
-	request, err := http.NewRequest(string(Post), requestURL, &buf)
-	
+	request, err := http.NewRequestWithContext(ctx, string(Post), requestURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Add form type
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	// Add authorization
-	request.SetBasicAuth("3HFvhLMeRZyz7K6Cjr62Q", "Y6edLK2mnAbDC78bOgoxgQt7DdQay03i")
+	request.SetBasicAuth(c.conf.ClientID, c.conf.ClientSecret)
 
-	response, err := c.httpClient().Do(request)
+	// time of request is used as base for token expiry
+	issuedAt := time.Now()
 
+	response, err := c.httpClient().Do(request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var ret AccessTokenResult
-	
 	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	c.logger.Info().Msgf("Response Body: %s", string(body))
-
-	if err := checkError(body); err != nil {
-		c.logger.Err(err).Msg("Error response returned from zoom api")
-		return "", err
+	if err := checkOAuthError(response.StatusCode, body); err != nil {
+		c.logger.Err(err).Msg("error response returned from zoom oauth")
+		return nil, err
 	}
 
 	// Unmarshall into the result
+	var ret AccessTokenResult
 	if err := json.Unmarshal(body, &ret); err != nil {
-		c.logger.Err(err).Msg("Unmarshall returned error")
-		return "", err
+		c.logger.Err(err).Msg("unmarshall returned error")
+		return nil, err
 	}
-	
-	return ret.AccessToken, nil
+
+	return &Token{
+		AccessToken:  ret.AccessToken,
+		RefreshToken: ret.RefreshToken,
+		TokenType:    ret.TokenType,
+		Scope:        ret.Scope,
+		Expiry:       issuedAt.Add(time.Duration(ret.ExpiresIn) * time.Second),
+	}, nil
+}
+
+const GetUserPath = "/users/me"
+
+type UserResult struct {
+	ID        string `json:"id"`
+	AccountID string `json:"account_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// GetUser returns user that owns the token.
+func (c *Client) GetUser(ctx context.Context, token string) (*UserResult, error) {
+	var ret UserResult
+	if err := c.request(ctx, requestV2Opts{
+		Method: Get,
+		Path:   GetUserPath,
+		Ret:    &ret,
+	}, token); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
 }
 
 const GetDeepLinkPath = "/zoomapp/deeplink"
@@ -224,22 +422,22 @@ type GetDeepLinkOptions struct {
 }
 
 type Action struct {
-	URL string `json:"url"`
+	URL      string `json:"url"`
 	RoleName string `json:"role_name"`
-	Verified int `json:"verified"`
-	RoleId int `json:"role_id"`
+	Verified int    `json:"verified"`
+	RoleId   int    `json:"role_id"`
 }
 
 type DeepLinkResult struct {
 	DeepLink string `json:"deeplink"`
 }
 
-func (c *Client) GetDeepLink(token string) (string, error) {
+func (c *Client) GetDeepLink(ctx context.Context, token string) (string, error) {
 	ac := Action{
-		URL: "/",
+		URL:      "/",
 		RoleName: "Owner",
 		Verified: 1,
-		RoleId: 0,
+		RoleId:   0,
 	}
 
 	acStr, err := json.Marshal(ac)
@@ -251,34 +449,17 @@ func (c *Client) GetDeepLink(token string) (string, error) {
 		Action: string(acStr),
 	}
 
-	response, err := c.executeRequest(requestV2Opts{
-										Method: Post,
-										Path: GetDeepLinkPath,
-										DataParameters: getDeepLinkOptions}, token)
-
-	if err != nil {
-		return "", err
-	}
-
 	var ret DeepLinkResult
-
-	defer response.Body.Close()
-
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
+	if err := c.request(ctx, requestV2Opts{
+		Method:         Post,
+		Path:           GetDeepLinkPath,
+		DataParameters: getDeepLinkOptions,
+		Ret:            &ret,
+	}, token); err != nil {
 		return "", err
 	}
 
-	log.Printf("Response Body: %s", string(body))
-
-	if err := checkError(body); err != nil {
-		return "", err
-	}
-
-	// Unmarshall into the result
-	if err := json.Unmarshal(body, &ret); err != nil {
-		return "", err
-	}
+	c.logger.Debug().Str("deeplink", ret.DeepLink).Msg("deeplink retrieved")
 
 	return ret.DeepLink, nil
 }
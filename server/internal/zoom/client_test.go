@@ -0,0 +1,251 @@
+package zoom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"m1k1o/neko/internal/config"
+)
+
+// newTestClient returns client of a stand-in Zoom API server.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.Zoom{APIHost: server.URL})
+	client.RetryWait = time.Millisecond
+	client.MaxRetryWait = 5 * time.Second
+	return client, &requests
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	var limited int32
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.CompareAndSwapInt32(&limited, 0, 1) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		fmt.Fprint(w, `{"id":"user","first_name":"Jane"}`)
+	})
+
+	start := time.Now()
+	user, err := client.GetUser(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.ID != "user" {
+		t.Fatalf("id = %q, want %q", user.ID, "user")
+	}
+
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %s, before Retry-After", elapsed)
+	}
+}
+
+func TestClientRetryAfterTooLong(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := client.GetUser(context.Background(), "token")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want rate limit error", err)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestClientBackoffExhausted(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.MaxRetries = 2
+
+	_, err := client.GetUser(context.Background(), "token")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("error = %v, want service unavailable", err)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("requests = %d, want 3", n)
+	}
+}
+
+func TestClientPostNotRetried(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if _, err := client.SendMeetingChatMessage(context.Background(), "token", "123", MeetingChatMessage{Message: "hi"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.RetryWait = time.Minute
+	client.MaxRetryWait = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetUser(ctx, "token")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancelled request returned after %s", elapsed)
+	}
+}
+
+func TestClientRateLimitHeaders(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Type", "Daily-limit")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		fmt.Fprint(w, `{"id":"user"}`)
+	})
+
+	if _, err := client.GetUser(context.Background(), "token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// daily limit is exhausted, request is not sent at all
+	_, err := client.GetUser(context.Background(), "token")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want rate limit error", err)
+	}
+
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestClientRateLimitPerSecond(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Type", "QPS")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		fmt.Fprint(w, `{"id":"user"}`)
+	})
+
+	if _, err := client.GetUser(context.Background(), "token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := client.GetUser(context.Background(), "token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Fatalf("request sent after %s, before rate limit was reset", elapsed)
+	}
+}
+
+func TestListMeetingParticipantsPages(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"next_page_token":"p2","participants":[{"id":"a"},{"id":"b"}]}`,
+		"p2": `{"next_page_token":"p3","participants":[{"id":"c"}]}`,
+		"p3": `{"next_page_token":"","participants":[{"id":"d"}]}`,
+	}
+
+	client, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/metrics/meetings/123/participants" {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("type") != "live" || query.Get("page_size") != "300" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+
+		page, ok := pages[query.Get("next_page_token")]
+		if !ok {
+			http.Error(w, "bad page", http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprint(w, page)
+	})
+
+	participants, err := client.ListMeetingParticipants(context.Background(), "token", "123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := ""
+	for _, participant := range participants {
+		ids += participant.ID
+	}
+
+	if ids != "abcd" {
+		t.Fatalf("participants = %q, want %q", ids, "abcd")
+	}
+
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("requests = %d, want 3", n)
+	}
+}
+
+func TestSendMeetingChatMessage(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/live_meetings/123/chat/messages" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"msg"}`)
+	})
+
+	id, err := client.SendMeetingChatMessage(context.Background(), "token", "123", MeetingChatMessage{Message: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id != "msg" {
+		t.Fatalf("id = %q, want %q", id, "msg")
+	}
+}
@@ -1,18 +1,20 @@
-
-
 package zoom
 
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type errorContainer struct {
-	*APIError
-}
-
 // APIError contains the code and message returned by any Zoom errors
 type APIError struct {
+	Status int `json:"-"`
+	// RetryAfter is how long to wait before repeating rate limited request
+	RetryAfter time.Duration `json:"-"`
+
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Errors  []struct {
@@ -29,16 +31,110 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("Zoom API error %d: \"%s\"", e.Code, e.Message)
 }
 
-func checkError(body []byte) error {
-	var c errorContainer
-	if err := json.Unmarshal(body, &c); err != nil {
-		return err
+func checkResponse(response *http.Response, body []byte) *APIError {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	e := &APIError{Status: response.StatusCode}
+	if err := json.Unmarshal(body, e); err != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	if e.Message == "" {
+		e.Message = http.StatusText(response.StatusCode)
+	}
+
+	if e.Code == 0 {
+		e.Code = response.StatusCode
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		e.RetryAfter = rateLimitReset(response.Header, time.Now())
+	}
+
+	return e
+}
+
+// rateLimitWait returns how long requests must wait, when Zoom reported
+// in X-RateLimit-Remaining, that no request remains in current window.
+func rateLimitWait(header http.Header, now time.Time) time.Duration {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return 0
+	}
+
+	if wait := rateLimitReset(header, now); wait > 0 {
+		return wait
+	}
+
+	// per-second limit
+	return time.Second
+}
+
+// rateLimitReset returns when rate limit is reset, either from Retry-After
+// or from X-RateLimit-Type. Daily limits are reset at midnight UTC.
+func rateLimitReset(header http.Header, now time.Time) time.Duration {
+	if wait := retryAfter(header, now); wait > 0 {
+		return wait
+	}
+
+	if strings.EqualFold(header.Get("X-RateLimit-Type"), "Daily-limit") {
+		utc := now.UTC()
+		return time.Date(utc.Year(), utc.Month(), utc.Day()+1, 0, 0, 0, 0, time.UTC).Sub(utc)
+	}
+
+	return 0
+}
+
+// retryAfter parses Retry-After header, that can contain either number
+// of seconds or a date (Zoom sends RFC3339 date when daily limit is
+// reached). Zero is returned if it is not present.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, value)
+	}
+
+	if err == nil && date.After(now) {
+		return date.Sub(now)
 	}
 
-	// need to explicitly return nil or it will register as an error
-	if c.APIError == nil {
+	return 0
+}
+
+// OAuthError contains the error returned by Zoom OAuth endpoints
+type OAuthError struct {
+	Status int    `json:"-"`
+	Err    string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("Zoom OAuth error %d %s: \"%s\"", e.Status, e.Err, e.Reason)
+}
+
+func checkOAuthError(status int, body []byte) error {
+	if status >= 200 && status < 300 {
 		return nil
 	}
 
-	return c.APIError
+	e := &OAuthError{Status: status}
+	if err := json.Unmarshal(body, e); err != nil {
+		e.Reason = string(body)
+	}
+
+	return e
 }
@@ -0,0 +1,94 @@
+package zoom
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/kataras/go-events"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"m1k1o/neko/internal/config"
+)
+
+type ZoomManager struct {
+	logger  zerolog.Logger
+	config  *config.Zoom
+	client  *Client
+	store   TokenStore
+	emmiter events.EventEmmiter
+
+	states   map[string]oauthState
+	statesMu sync.Mutex
+
+	refreshMu sync.Mutex
+
+	ticketKey []byte
+	tickets   map[string]time.Time
+	ticketsMu sync.Mutex
+
+	names   map[string]cachedName
+	namesMu sync.Mutex
+}
+
+func New(config *config.Zoom) *ZoomManager {
+	logger := log.With().Str("module", "zoom").Logger()
+
+	// tickets are short-lived, so key does not need to survive restarts
+	ticketKey := make([]byte, 32)
+	if _, err := rand.Read(ticketKey); err != nil {
+		logger.Panic().Err(err).Msg("unable to generate ticket key")
+	}
+
+	return &ZoomManager{
+		logger:    logger,
+		config:    config,
+		client:    NewClient(config),
+		emmiter:   events.New(),
+		states:    make(map[string]oauthState),
+		ticketKey: ticketKey,
+		tickets:   make(map[string]time.Time),
+		names:     make(map[string]cachedName),
+	}
+}
+
+func (manager *ZoomManager) Start() {
+	if !manager.config.Enabled {
+		return
+	}
+
+	if manager.config.TokenFile == "" {
+		manager.store = NewMemoryTokenStore()
+		manager.logger.Info().Msg("using in-memory token store")
+	} else {
+		store, err := NewFileTokenStore(manager.config.TokenFile)
+		if err != nil {
+			manager.logger.Panic().Err(err).Str("path", manager.config.TokenFile).Msg("unable to load token store")
+		}
+
+		manager.store = store
+		manager.logger.Info().Str("path", manager.config.TokenFile).Msg("using file token store")
+	}
+
+	// tokens of users, that removed the app, must be forgotten
+	manager.OnAppDeauthorized(func(userID string) {
+		if err := manager.Revoke(userID); err != nil {
+			manager.logger.Warn().Err(err).Str("user_id", userID).Msg("unable to revoke token")
+		} else {
+			manager.logger.Info().Str("user_id", userID).Msg("user deauthorized app")
+		}
+	})
+}
+
+func (manager *ZoomManager) Shutdown() error {
+	return nil
+}
+
+func (manager *ZoomManager) Enabled() bool {
+	return manager.config.Enabled
+}
+
+func (manager *ZoomManager) Client() *Client {
+	return manager.client
+}
@@ -0,0 +1,118 @@
+package zoom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maximum page size allowed by Zoom API
+const maxPageSize = 300
+
+type pageOptions struct {
+	PageSize      int    `url:"page_size,omitempty"`
+	NextPageToken string `url:"next_page_token,omitempty"`
+}
+
+type pageResult struct {
+	PageSize      int    `json:"page_size"`
+	TotalRecords  int    `json:"total_records"`
+	NextPageToken string `json:"next_page_token"`
+}
+
+// meetingPath escapes meeting ID or UUID for use in path. UUID that
+// begins with / or contains // must be double encoded.
+func meetingPath(id string) string {
+	escaped := url.PathEscape(id)
+	if strings.HasPrefix(id, "/") || strings.Contains(id, "//") {
+		escaped = url.PathEscape(escaped)
+	}
+
+	return escaped
+}
+
+const GetMeetingPath = "/meetings/"
+
+type MeetingResult struct {
+	// meeting id is sent as a number
+	ID        json.Number `json:"id"`
+	UUID      string      `json:"uuid"`
+	HostID    string      `json:"host_id"`
+	Topic     string      `json:"topic"`
+	Type      int         `json:"type"`
+	Status    string      `json:"status"`
+	StartTime time.Time   `json:"start_time"`
+	Duration  int         `json:"duration"`
+	Timezone  string      `json:"timezone"`
+	JoinURL   string      `json:"join_url"`
+}
+
+// GetMeeting returns details of a meeting, identified by its ID or UUID.
+func (c *Client) GetMeeting(ctx context.Context, token string, meetingID string) (*MeetingResult, error) {
+	var ret MeetingResult
+	if err := c.request(ctx, requestV2Opts{
+		Method: Get,
+		Path:   GetMeetingPath + meetingPath(meetingID),
+		Ret:    &ret,
+	}, token); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+const ListMeetingParticipantsPath = "/metrics/meetings/%s/participants"
+
+type listMeetingParticipantsOptions struct {
+	Type string `url:"type,omitempty"`
+	pageOptions
+}
+
+type ParticipantResult struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"user_name"`
+	Email     string    `json:"email"`
+	JoinTime  time.Time `json:"join_time"`
+	LeaveTime time.Time `json:"leave_time"`
+}
+
+type listMeetingParticipantsResult struct {
+	pageResult
+	Participants []ParticipantResult `json:"participants"`
+}
+
+// ListMeetingParticipants returns all participants of a live meeting,
+// it follows next_page_token until all pages are fetched.
+func (c *Client) ListMeetingParticipants(ctx context.Context, token string, meetingID string) ([]ParticipantResult, error) {
+	opts := listMeetingParticipantsOptions{
+		Type: "live",
+		pageOptions: pageOptions{
+			PageSize: maxPageSize,
+		},
+	}
+
+	participants := []ParticipantResult{}
+	for {
+		var ret listMeetingParticipantsResult
+		if err := c.request(ctx, requestV2Opts{
+			Method:        Get,
+			Path:          fmt.Sprintf(ListMeetingParticipantsPath, meetingPath(meetingID)),
+			URLParameters: opts,
+			Ret:           &ret,
+		}, token); err != nil {
+			return nil, err
+		}
+
+		participants = append(participants, ret.Participants...)
+
+		if ret.NextPageToken == "" {
+			return participants, nil
+		}
+
+		opts.NextPageToken = ret.NextPageToken
+	}
+}
@@ -0,0 +1,146 @@
+package zoom
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"time"
+
+	"m1k1o/neko/internal/utils"
+)
+
+const AuthorizePath = "/oauth/authorize"
+
+// how long is state of started authorization valid
+const oauthStateTimeout = 10 * time.Minute
+
+// token is refreshed when it expires sooner than this
+const tokenRefreshBefore = 5 * time.Minute
+
+var (
+	ErrInvalidState = errors.New("invalid or expired oauth state")
+)
+
+type oauthState struct {
+	verifier  string
+	createdAt time.Time
+}
+
+// AuthorizeURL starts new authorization, it generates state and PKCE
+// verifier and returns URL where user should be redirected.
+func (manager *ZoomManager) AuthorizeURL() (string, error) {
+	state, err := utils.NewUID(32)
+	if err != nil {
+		return "", err
+	}
+
+	// RFC 7636: verifier has between 43 and 128 characters
+	verifier, err := utils.NewUID(64)
+	if err != nil {
+		return "", err
+	}
+
+	manager.statesMu.Lock()
+	for key, val := range manager.states {
+		if time.Since(val.createdAt) > oauthStateTimeout {
+			delete(manager.states, key)
+		}
+	}
+	manager.states[state] = oauthState{
+		verifier:  verifier,
+		createdAt: time.Now(),
+	}
+	manager.statesMu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", manager.config.ClientID)
+	values.Set("redirect_uri", manager.config.RedirectURL)
+	values.Set("state", state)
+	values.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	values.Set("code_challenge_method", "S256")
+
+	return manager.config.Host + AuthorizePath + "?" + values.Encode(), nil
+}
+
+// Exchange validates state returned to callback, exchanges code for a token
+// and stores it for the user that authorized the app. Every state can be
+// used only once.
+func (manager *ZoomManager) Exchange(ctx context.Context, state, code string) (string, *Token, error) {
+	manager.statesMu.Lock()
+	pending, ok := manager.states[state]
+	delete(manager.states, state)
+	manager.statesMu.Unlock()
+
+	if !ok || state == "" || time.Since(pending.createdAt) > oauthStateTimeout {
+		return "", nil, ErrInvalidState
+	}
+
+	token, err := manager.client.GetToken(ctx, code, pending.verifier)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, err := manager.client.GetUser(ctx, token.AccessToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := manager.store.Set(user.ID, token); err != nil {
+		return "", nil, err
+	}
+
+	manager.setUserName(user.ID, user)
+
+	manager.logger.Info().Str("user_id", user.ID).Msg("user authorized")
+	return user.ID, token, nil
+}
+
+// Token returns valid token of a user, it is refreshed if it is about to expire.
+func (manager *ZoomManager) Token(ctx context.Context, userID string) (*Token, error) {
+	manager.refreshMu.Lock()
+	defer manager.refreshMu.Unlock()
+
+	token, err := manager.store.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.ExpiresWithin(tokenRefreshBefore) {
+		return token, nil
+	}
+
+	manager.logger.Debug().Str("user_id", userID).Msg("token is about to expire, refreshing")
+
+	token, err = manager.client.RefreshToken(ctx, token.RefreshToken)
+	if err != nil {
+		// refresh token was revoked or is expired, user must authorize again
+		var oauthErr *OAuthError
+		if errors.As(err, &oauthErr) && oauthErr.Status < 500 {
+			if err := manager.store.Delete(userID); err != nil {
+				manager.logger.Warn().Err(err).Str("user_id", userID).Msg("unable to delete token")
+			}
+		}
+
+		return nil, err
+	}
+
+	if err := manager.store.Set(userID, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Revoke forgets token of a user.
+func (manager *ZoomManager) Revoke(userID string) error {
+	manager.namesMu.Lock()
+	delete(manager.names, userID)
+	manager.namesMu.Unlock()
+
+	return manager.store.Delete(userID)
+}
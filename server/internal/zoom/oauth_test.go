@@ -0,0 +1,259 @@
+package zoom
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"m1k1o/neko/internal/config"
+)
+
+// newTestManager returns manager with memory token store, that talks to
+// a stand-in Zoom OAuth and API server.
+func newTestManager(t *testing.T, handler http.HandlerFunc) *ZoomManager {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	manager := New(&config.Zoom{
+		Host:         server.URL,
+		APIHost:      server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://neko.example.com/zoom/callback",
+	})
+	manager.store = NewMemoryTokenStore()
+	return manager
+}
+
+// oauthHandler serves token endpoint and current user, it records code
+// verifiers and counts refresh requests.
+type oauthHandler struct {
+	verifier     atomic.Value
+	refreshes    int32
+	refreshError int
+}
+
+func (h *oauthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case GetTokenPath:
+		query := r.URL.Query()
+		if query.Get("grant_type") == "refresh_token" {
+			atomic.AddInt32(&h.refreshes, 1)
+			if h.refreshError != 0 {
+				w.WriteHeader(h.refreshError)
+				fmt.Fprint(w, `{"error":"invalid_grant","reason":"Invalid Token!"}`)
+				return
+			}
+		} else {
+			h.verifier.Store(query.Get("code_verifier"))
+		}
+
+		fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"bearer","expires_in":3600}`)
+	case apiVersion + GetUserPath:
+		fmt.Fprint(w, `{"id":"user","first_name":"Jane"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	manager := newTestManager(t, http.NotFound)
+
+	raw, err := manager.AuthorizeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := u.Query()
+	if u.Path != AuthorizePath || query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "client" {
+		t.Fatalf("unexpected authorize url %s", raw)
+	}
+
+	pending, ok := manager.states[query.Get("state")]
+	if !ok {
+		t.Fatal("state is not stored")
+	}
+
+	// RFC 7636: verifier has between 43 and 128 characters
+	if n := len(pending.verifier); n < 43 || n > 128 {
+		t.Fatalf("verifier length = %d", n)
+	}
+
+	sum := sha256.Sum256([]byte(pending.verifier))
+	if challenge := base64.RawURLEncoding.EncodeToString(sum[:]); query.Get("code_challenge") != challenge {
+		t.Fatalf("challenge = %q, want %q", query.Get("code_challenge"), challenge)
+	}
+
+	// every authorization has its own state and verifier
+	other, err := manager.AuthorizeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other == raw || len(manager.states) != 2 {
+		t.Fatal("authorization state reused")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name  string
+		state func(manager *ZoomManager) string
+		err   error
+	}{
+		{
+			name:  "valid",
+			state: pendingState(0),
+		},
+		{
+			name:  "unknown",
+			state: func(*ZoomManager) string { return "unknown" },
+			err:   ErrInvalidState,
+		},
+		{
+			name:  "empty",
+			state: func(*ZoomManager) string { return "" },
+			err:   ErrInvalidState,
+		},
+		{
+			name:  "expired",
+			state: pendingState(oauthStateTimeout + time.Second),
+			err:   ErrInvalidState,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &oauthHandler{}
+			manager := newTestManager(t, handler.ServeHTTP)
+
+			state := test.state(manager)
+			verifier := manager.states[state].verifier
+
+			userID, _, err := manager.Exchange(context.Background(), state, "code")
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error = %v, want %v", err, test.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := handler.verifier.Load(); got != verifier {
+				t.Fatalf("verifier = %v, want %q", got, verifier)
+			}
+
+			if _, err := manager.store.Get(userID); err != nil {
+				t.Fatalf("token is not stored: %v", err)
+			}
+
+			// state is used only once
+			if _, _, err := manager.Exchange(context.Background(), state, "code"); !errors.Is(err, ErrInvalidState) {
+				t.Fatalf("reused state error = %v, want %v", err, ErrInvalidState)
+			}
+		})
+	}
+}
+
+// pendingState starts authorization, that was created given time ago.
+func pendingState(age time.Duration) func(manager *ZoomManager) string {
+	return func(manager *ZoomManager) string {
+		raw, err := manager.AuthorizeURL()
+		if err != nil {
+			panic(err)
+		}
+
+		u, _ := url.Parse(raw)
+		state := u.Query().Get("state")
+
+		pending := manager.states[state]
+		pending.createdAt = pending.createdAt.Add(-age)
+		manager.states[state] = pending
+		return state
+	}
+}
+
+func TestToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		expiresIn    time.Duration
+		refreshError int
+		refreshes    int32
+		access       string
+		kept         bool
+	}{
+		{
+			name:      "valid",
+			expiresIn: time.Hour,
+			access:    "stored",
+			kept:      true,
+		},
+		{
+			name:      "expiring",
+			expiresIn: tokenRefreshBefore - time.Second,
+			refreshes: 1,
+			access:    "access",
+			kept:      true,
+		},
+		{
+			name:         "revoked",
+			expiresIn:    -time.Hour,
+			refreshError: http.StatusBadRequest,
+			refreshes:    1,
+		},
+		{
+			name:         "unavailable",
+			expiresIn:    -time.Hour,
+			refreshError: http.StatusServiceUnavailable,
+			refreshes:    1,
+			kept:         true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &oauthHandler{refreshError: test.refreshError}
+			manager := newTestManager(t, handler.ServeHTTP)
+
+			if err := manager.store.Set("user", &Token{
+				AccessToken:  "stored",
+				RefreshToken: "refresh",
+				Expiry:       time.Now().Add(test.expiresIn),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := manager.Token(context.Background(), "user")
+			if test.access == "" {
+				if err == nil {
+					t.Fatal("expected refresh error")
+				}
+			} else if err != nil || token.AccessToken != test.access {
+				t.Fatalf("token = %+v, error = %v, want %q", token, err, test.access)
+			}
+
+			if n := atomic.LoadInt32(&handler.refreshes); n != test.refreshes {
+				t.Fatalf("refreshes = %d, want %d", n, test.refreshes)
+			}
+
+			if _, err := manager.store.Get("user"); (err == nil) != test.kept {
+				t.Fatalf("token kept = %v, want %v", err == nil, test.kept)
+			}
+		})
+	}
+}
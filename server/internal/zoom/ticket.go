@@ -0,0 +1,195 @@
+package zoom
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"m1k1o/neko/internal/types"
+	"m1k1o/neko/internal/utils"
+)
+
+// how long is issued session ticket valid
+const ticketTimeout = time.Minute
+
+// names of users are cached, so that issuing a ticket does not wait for
+// Zoom API, they are refreshed in background after this
+const (
+	userNameTimeout = time.Hour
+	userNameRequest = 10 * time.Second
+)
+
+var (
+	ErrInvalidTicket = errors.New("invalid session ticket")
+	ErrExpiredTicket = errors.New("session ticket expired")
+	ErrUsedTicket    = errors.New("session ticket already used")
+)
+
+type cachedName struct {
+	name      string
+	fetchedAt time.Time
+	fetching  bool
+}
+
+// NewTicket decrypts Zoom app context and issues short-lived signed
+// ticket, that can be used to authenticate websocket connection.
+func (manager *ZoomManager) NewTicket(header string) (string, *types.ZoomTicket, error) {
+	appContext, err := GetAppContext(header, manager.config.ClientSecret)
+	if err != nil {
+		return "", nil, err
+	}
+
+	expiresAt := time.Now().Add(ticketTimeout)
+	if contextExpiresAt := appContext.ExpiresAt(); !contextExpiresAt.IsZero() && contextExpiresAt.Before(expiresAt) {
+		expiresAt = contextExpiresAt
+	}
+
+	nonce, err := utils.NewUID(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ticket := &types.ZoomTicket{
+		Nonce:     nonce,
+		UserID:    appContext.UserID,
+		MeetingID: appContext.MeetingID,
+		Type:      appContext.Type,
+		Role:      appContext.AttendRole,
+		Name:      manager.userName(appContext.UserID),
+		ExpiresAt: expiresAt,
+	}
+
+	in, _ := utils.ArrayIn(appContext.AttendRole, manager.config.AdminRoles)
+	ticket.Admin = in
+
+	signed, err := manager.signTicket(ticket)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, ticket, nil
+}
+
+// VerifyTicket checks signature and expiration of a ticket. Every ticket
+// can be used only once.
+func (manager *ZoomManager) VerifyTicket(signed string) (*types.ZoomTicket, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidTicket
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	if !hmac.Equal(signature, manager.ticketSignature(payload)) {
+		return nil, ErrInvalidTicket
+	}
+
+	ticket := &types.ZoomTicket{}
+	if err := json.Unmarshal(payload, ticket); err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	now := time.Now()
+	if now.After(ticket.ExpiresAt) {
+		return nil, ErrExpiredTicket
+	}
+
+	if ticket.Nonce == "" {
+		return nil, ErrInvalidTicket
+	}
+
+	manager.ticketsMu.Lock()
+	defer manager.ticketsMu.Unlock()
+
+	// consumed tickets are kept only until they expire
+	for nonce, expiresAt := range manager.tickets {
+		if now.After(expiresAt) {
+			delete(manager.tickets, nonce)
+		}
+	}
+
+	if _, ok := manager.tickets[ticket.Nonce]; ok {
+		return nil, ErrUsedTicket
+	}
+
+	manager.tickets[ticket.Nonce] = ticket.ExpiresAt
+	return ticket, nil
+}
+
+func (manager *ZoomManager) signTicket(ticket *types.ZoomTicket) (string, error) {
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(manager.ticketSignature(payload)), nil
+}
+
+func (manager *ZoomManager) ticketSignature(payload []byte) []byte {
+	mac := hmac.New(sha256.New, manager.ticketKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// userName returns cached name of a user, if the user authorized the app.
+// Missing or stale name is fetched in background.
+func (manager *ZoomManager) userName(userID string) string {
+	manager.namesMu.Lock()
+	defer manager.namesMu.Unlock()
+
+	cached := manager.names[userID]
+	if !cached.fetching && time.Since(cached.fetchedAt) > userNameTimeout {
+		cached.fetching = true
+		manager.names[userID] = cached
+		go manager.fetchUserName(userID)
+	}
+
+	return cached.name
+}
+
+func (manager *ZoomManager) fetchUserName(userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), userNameRequest)
+	defer cancel()
+
+	token, err := manager.Token(ctx, userID)
+	if err != nil {
+		manager.setUserName(userID, nil)
+		return
+	}
+
+	user, err := manager.client.GetUser(ctx, token.AccessToken)
+	if err != nil {
+		manager.logger.Warn().Err(err).Str("user_id", userID).Msg("unable to get user")
+	}
+
+	manager.setUserName(userID, user)
+}
+
+// setUserName caches name of a user, previous name is kept if user is nil.
+func (manager *ZoomManager) setUserName(userID string, user *UserResult) {
+	manager.namesMu.Lock()
+	defer manager.namesMu.Unlock()
+
+	cached := manager.names[userID]
+	if user != nil {
+		cached.name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	cached.fetchedAt = time.Now()
+	cached.fetching = false
+	manager.names[userID] = cached
+}
@@ -0,0 +1,164 @@
+package zoom
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var ErrTokenNotFound = errors.New("token not found")
+
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Scope        string    `json:"scope"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// ExpiresWithin reports whether token expires in less than given duration.
+func (t *Token) ExpiresWithin(d time.Duration) bool {
+	return t.Expiry.IsZero() || time.Until(t.Expiry) < d
+}
+
+// TokenStore persists tokens keyed by Zoom user ID.
+type TokenStore interface {
+	Get(userID string) (*Token, error)
+	Set(userID string, token *Token) error
+	Delete(userID string) error
+}
+
+//
+// memory store
+//
+
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]Token
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]Token),
+	}
+}
+
+func (s *MemoryTokenStore) Get(userID string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &token, nil
+}
+
+func (s *MemoryTokenStore) Set(userID string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[userID] = *token
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, userID)
+	return nil
+}
+
+//
+// file store
+//
+
+type FileTokenStore struct {
+	mu     sync.RWMutex
+	path   string
+	tokens map[string]Token
+}
+
+// NewFileTokenStore loads tokens from JSON file, file is created on first write.
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	s := &FileTokenStore{
+		path:   path,
+		tokens: make(map[string]Token),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *FileTokenStore) Get(userID string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &token, nil
+}
+
+func (s *FileTokenStore) Set(userID string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[userID] = *token
+	return s.save()
+}
+
+func (s *FileTokenStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, userID)
+	return s.save()
+}
+
+// save writes tokens to temporary file and renames it, so that
+// file is never left half written. Must be called with lock held.
+func (s *FileTokenStore) save() error {
+	data, err := json.Marshal(s.tokens)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,93 @@
+package zoom
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenExpiresWithin(t *testing.T) {
+	tests := []struct {
+		name    string
+		expiry  time.Time
+		expires bool
+	}{
+		{"unknown", time.Time{}, true},
+		{"expired", time.Now().Add(-time.Minute), true},
+		{"soon", time.Now().Add(time.Minute), true},
+		{"later", time.Now().Add(time.Hour), false},
+	}
+
+	for _, test := range tests {
+		token := Token{Expiry: test.expiry}
+		if expires := token.ExpiresWithin(5 * time.Minute); expires != test.expires {
+			t.Errorf("%s: expires = %v, want %v", test.name, expires, test.expires)
+		}
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens.json")
+
+	store, err := NewFileTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("user"); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("error = %v, want %v", err, ErrTokenNotFound)
+	}
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	for _, id := range []string{"user", "other"} {
+		if err := store.Set(id, &Token{AccessToken: id, RefreshToken: "refresh", Expiry: expiry}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Delete("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// file is replaced atomically, no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "tokens.json" {
+		t.Fatalf("unexpected files in store directory: %v", entries)
+	}
+
+	loaded, err := NewFileTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := loaded.Get("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "user" || !token.Expiry.Equal(expiry) {
+		t.Fatalf("loaded token = %+v", token)
+	}
+
+	if _, err := loaded.Get("other"); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("deleted token error = %v, want %v", err, ErrTokenNotFound)
+	}
+}
+
+func TestFileTokenStoreInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileTokenStore(path); err == nil {
+		t.Fatal("loaded invalid token file")
+	}
+}
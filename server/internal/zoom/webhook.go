@@ -0,0 +1,180 @@
+package zoom
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kataras/go-events"
+
+	"m1k1o/neko/internal/types"
+)
+
+// webhook requests older than this are rejected to prevent replays
+const webhookMaxAge = 5 * time.Minute
+
+const (
+	WEBHOOK_URL_VALIDATION     = "endpoint.url_validation"
+	WEBHOOK_MEETING_STARTED    = "meeting.started"
+	WEBHOOK_MEETING_ENDED      = "meeting.ended"
+	WEBHOOK_PARTICIPANT_JOINED = "meeting.participant_joined"
+	WEBHOOK_PARTICIPANT_LEFT   = "meeting.participant_left"
+	WEBHOOK_APP_DEAUTHORIZED   = "app_deauthorized"
+)
+
+var (
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+	ErrWebhookTimestamp = errors.New("invalid webhook timestamp")
+)
+
+type webhookEvent struct {
+	Event   string          `json:"event"`
+	EventTS int64           `json:"event_ts"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type webhookMeetingPayload struct {
+	AccountID string `json:"account_id"`
+	Object    struct {
+		// meeting id is sent as a number
+		ID          json.Number           `json:"id"`
+		UUID        string                `json:"uuid"`
+		HostID      string                `json:"host_id"`
+		Topic       string                `json:"topic"`
+		Participant types.ZoomParticipant `json:"participant"`
+	} `json:"object"`
+}
+
+type webhookDeauthorizedPayload struct {
+	AccountID string `json:"account_id"`
+	UserID    string `json:"user_id"`
+	ClientID  string `json:"client_id"`
+}
+
+type webhookValidationPayload struct {
+	PlainToken string `json:"plainToken"`
+}
+
+type WebhookValidationResponse struct {
+	PlainToken     string `json:"plainToken"`
+	EncryptedToken string `json:"encryptedToken"`
+}
+
+func (manager *ZoomManager) WebhookEnabled() bool {
+	return manager.config.WebhookSecret != ""
+}
+
+// VerifyWebhook checks x-zm-signature of a request, signature is computed
+// as v0=HMAC_SHA256(secret, "v0:{x-zm-request-timestamp}:{body}").
+func (manager *ZoomManager) VerifyWebhook(signature string, timestamp string, body []byte) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookTimestamp
+	}
+
+	if age := time.Since(time.Unix(ts, 0)); age > webhookMaxAge || age < -webhookMaxAge {
+		return ErrWebhookTimestamp
+	}
+
+	mac := hmac.New(sha256.New, []byte(manager.config.WebhookSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrWebhookSignature
+	}
+
+	return nil
+}
+
+// HandleWebhook dispatches verified webhook body to listeners. For url
+// validation challenge it returns response that must be sent back to Zoom.
+func (manager *ZoomManager) HandleWebhook(body []byte) (interface{}, error) {
+	event := webhookEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+
+	manager.logger.Debug().Str("event", event.Event).Msg("received webhook event")
+
+	switch event.Event {
+	case WEBHOOK_URL_VALIDATION:
+		payload := webhookValidationPayload{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, err
+		}
+
+		mac := hmac.New(sha256.New, []byte(manager.config.WebhookSecret))
+		mac.Write([]byte(payload.PlainToken))
+
+		return WebhookValidationResponse{
+			PlainToken:     payload.PlainToken,
+			EncryptedToken: hex.EncodeToString(mac.Sum(nil)),
+		}, nil
+	case WEBHOOK_MEETING_STARTED, WEBHOOK_MEETING_ENDED, WEBHOOK_PARTICIPANT_JOINED, WEBHOOK_PARTICIPANT_LEFT:
+		payload := webhookMeetingPayload{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, err
+		}
+
+		meeting := types.ZoomMeeting{
+			ID:     payload.Object.ID.String(),
+			UUID:   payload.Object.UUID,
+			HostID: payload.Object.HostID,
+			Topic:  payload.Object.Topic,
+		}
+
+		switch event.Event {
+		case WEBHOOK_MEETING_STARTED, WEBHOOK_MEETING_ENDED:
+			manager.emmiter.Emit(events.EventName(event.Event), meeting)
+		default:
+			manager.emmiter.Emit(events.EventName(event.Event), meeting, payload.Object.Participant)
+		}
+	case WEBHOOK_APP_DEAUTHORIZED:
+		payload := webhookDeauthorizedPayload{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, err
+		}
+
+		manager.emmiter.Emit(events.EventName(event.Event), payload.UserID)
+	default:
+		manager.logger.Debug().Str("event", event.Event).Msg("unhandled webhook event")
+	}
+
+	return nil, nil
+}
+
+func (manager *ZoomManager) OnMeetingStarted(listener func(meeting types.ZoomMeeting)) {
+	manager.emmiter.On(WEBHOOK_MEETING_STARTED, func(payload ...interface{}) {
+		listener(payload[0].(types.ZoomMeeting))
+	})
+}
+
+func (manager *ZoomManager) OnMeetingEnded(listener func(meeting types.ZoomMeeting)) {
+	manager.emmiter.On(WEBHOOK_MEETING_ENDED, func(payload ...interface{}) {
+		listener(payload[0].(types.ZoomMeeting))
+	})
+}
+
+func (manager *ZoomManager) OnParticipantJoined(listener func(meeting types.ZoomMeeting, participant types.ZoomParticipant)) {
+	manager.emmiter.On(WEBHOOK_PARTICIPANT_JOINED, func(payload ...interface{}) {
+		listener(payload[0].(types.ZoomMeeting), payload[1].(types.ZoomParticipant))
+	})
+}
+
+func (manager *ZoomManager) OnParticipantLeft(listener func(meeting types.ZoomMeeting, participant types.ZoomParticipant)) {
+	manager.emmiter.On(WEBHOOK_PARTICIPANT_LEFT, func(payload ...interface{}) {
+		listener(payload[0].(types.ZoomMeeting), payload[1].(types.ZoomParticipant))
+	})
+}
+
+func (manager *ZoomManager) OnAppDeauthorized(listener func(userID string)) {
+	manager.emmiter.On(WEBHOOK_APP_DEAUTHORIZED, func(payload ...interface{}) {
+		listener(payload[0].(string))
+	})
+}
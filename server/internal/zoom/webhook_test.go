@@ -0,0 +1,139 @@
+package zoom
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"m1k1o/neko/internal/config"
+	"m1k1o/neko/internal/types"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+func newWebhookManager() *ZoomManager {
+	return New(&config.Zoom{WebhookSecret: testWebhookSecret})
+}
+
+// signWebhook computes x-zm-signature the same way as Zoom does.
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	manager := newWebhookManager()
+	body := []byte(`{"event":"meeting.started"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-webhookMaxAge-time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(webhookMaxAge+time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      []byte
+		err       error
+	}{
+		{"valid", signWebhook(testWebhookSecret, now, body), now, body, nil},
+		{"wrong secret", signWebhook("other", now, body), now, body, ErrWebhookSignature},
+		{"changed body", signWebhook(testWebhookSecret, now, body), now, []byte(`{"event":"meeting.ended"}`), ErrWebhookSignature},
+		{"missing prefix", signWebhook(testWebhookSecret, now, body)[3:], now, body, ErrWebhookSignature},
+		{"empty signature", "", now, body, ErrWebhookSignature},
+		{"stale timestamp", signWebhook(testWebhookSecret, stale, body), stale, body, ErrWebhookTimestamp},
+		{"future timestamp", signWebhook(testWebhookSecret, future, body), future, body, ErrWebhookTimestamp},
+		{"invalid timestamp", signWebhook(testWebhookSecret, "now", body), "now", body, ErrWebhookTimestamp},
+		// signature is bound to the timestamp
+		{"replaced timestamp", signWebhook(testWebhookSecret, stale, body), now, body, ErrWebhookSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := manager.VerifyWebhook(test.signature, test.timestamp, test.body); !errors.Is(err, test.err) {
+				t.Fatalf("error = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookGolden(t *testing.T) {
+	body := []byte(`{"event":"meeting.started"}`)
+	want := "v0=45bd899721daa0603eaa721b963db2356e3e66300b4daaf0241efa988206ed6e"
+
+	if got := signWebhook(testWebhookSecret, "1700000000", body); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookURLValidation(t *testing.T) {
+	manager := newWebhookManager()
+
+	res, err := manager.HandleWebhook([]byte(`{"event":"endpoint.url_validation","payload":{"plainToken":"qgg8vlvZRS6UYooatFL8Aw"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// HMAC_SHA256(secret, plainToken) in hex
+	want := WebhookValidationResponse{
+		PlainToken:     "qgg8vlvZRS6UYooatFL8Aw",
+		EncryptedToken: "6888178e55e7626f94e88a56224baefbb707ceaa8ec37ce09efc6bbf66b89ea8",
+	}
+
+	if res != want {
+		t.Fatalf("response = %+v, want %+v", res, want)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	manager := newWebhookManager()
+
+	var started types.ZoomMeeting
+	manager.OnMeetingStarted(func(meeting types.ZoomMeeting) {
+		started = meeting
+	})
+
+	var joined types.ZoomParticipant
+	manager.OnParticipantJoined(func(meeting types.ZoomMeeting, participant types.ZoomParticipant) {
+		joined = participant
+	})
+
+	var deauthorized string
+	manager.OnAppDeauthorized(func(userID string) {
+		deauthorized = userID
+	})
+
+	bodies := []string{
+		`{"event":"meeting.started","payload":{"object":{"id":85746065432,"uuid":"uuid","host_id":"host"}}}`,
+		`{"event":"meeting.participant_joined","payload":{"object":{"id":85746065432,"participant":{"user_id":"participant"}}}}`,
+		`{"event":"app_deauthorized","payload":{"user_id":"user"}}`,
+		`{"event":"unknown"}`,
+	}
+
+	for _, body := range bodies {
+		if res, err := manager.HandleWebhook([]byte(body)); res != nil || err != nil {
+			t.Fatalf("%s: response = %v, error = %v", body, res, err)
+		}
+	}
+
+	if started.ID != "85746065432" || started.HostID != "host" {
+		t.Fatalf("started meeting = %+v", started)
+	}
+
+	if joined.UserID != "participant" {
+		t.Fatalf("joined participant = %+v", joined)
+	}
+
+	if deauthorized != "user" {
+		t.Fatalf("deauthorized user = %q", deauthorized)
+	}
+
+	if _, err := manager.HandleWebhook([]byte(`{`)); err == nil {
+		t.Fatal("invalid body accepted")
+	}
+}
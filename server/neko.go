@@ -6,13 +6,16 @@ import (
 	"os/signal"
 	"runtime"
 
+	"m1k1o/neko/internal/auth"
 	"m1k1o/neko/internal/capture"
 	"m1k1o/neko/internal/config"
 	"m1k1o/neko/internal/desktop"
 	"m1k1o/neko/internal/http"
 	"m1k1o/neko/internal/session"
+	"m1k1o/neko/internal/turn"
 	"m1k1o/neko/internal/webrtc"
 	"m1k1o/neko/internal/websocket"
+	"m1k1o/neko/internal/zoom"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -64,7 +67,11 @@ func init() {
 		Capture:   &config.Capture{},
 		Desktop:   &config.Desktop{},
 		WebRTC:    &config.WebRTC{},
+		TURN:      &config.TURN{},
 		WebSocket: &config.WebSocket{},
+		Session:   &config.Session{},
+		Zoom:      &config.Zoom{},
+		Auth:      &config.Auth{},
 	}
 }
 
@@ -104,7 +111,11 @@ type Neko struct {
 	Desktop   *config.Desktop
 	Server    *config.Server
 	WebRTC    *config.WebRTC
+	TURN      *config.TURN
 	WebSocket *config.WebSocket
+	Session   *config.Session
+	Zoom      *config.Zoom
+	Auth      *config.Auth
 
 	logger           zerolog.Logger
 	server           *http.Server
@@ -112,7 +123,10 @@ type Neko struct {
 	captureManager   *capture.CaptureManagerCtx
 	desktopManager   *desktop.DesktopManagerCtx
 	webRTCManager    *webrtc.WebRTCManager
+	turnManager      *turn.TURNManager
 	webSocketHandler *websocket.WebSocketHandler
+	zoomManager      *zoom.ZoomManager
+	authManager      *auth.AuthManager
 }
 
 func (neko *Neko) Preflight() {
@@ -126,22 +140,34 @@ func (neko *Neko) Start() {
 	captureManager := capture.New(desktopManager, neko.Capture)
 	captureManager.Start()
 
-	sessionManager := session.New(captureManager)
+	sessionManager := session.New(captureManager, neko.Session)
 
-	webRTCManager := webrtc.New(sessionManager, captureManager, desktopManager, neko.WebRTC)
+	zoomManager := zoom.New(neko.Zoom)
+	zoomManager.Start()
+
+	authManager := auth.New(neko.Auth, neko.WebSocket)
+	authManager.Start()
+
+	turnManager := turn.New(neko.TURN, neko.WebRTC)
+	turnManager.Start()
+
+	webRTCManager := webrtc.New(sessionManager, captureManager, desktopManager, turnManager, neko.WebRTC)
 	webRTCManager.Start()
 
-	webSocketHandler := websocket.New(sessionManager, desktopManager, captureManager, webRTCManager, neko.WebSocket)
+	webSocketHandler := websocket.New(sessionManager, desktopManager, captureManager, webRTCManager, zoomManager, authManager, authManager, neko.WebSocket)
 	webSocketHandler.Start()
 
-	server := http.New(neko.Server, webSocketHandler, desktopManager)
+	server := http.New(neko.Server, zoomManager, webSocketHandler, desktopManager)
 	server.Start()
 
 	neko.sessionManager = sessionManager
 	neko.captureManager = captureManager
 	neko.desktopManager = desktopManager
 	neko.webRTCManager = webRTCManager
+	neko.turnManager = turnManager
 	neko.webSocketHandler = webSocketHandler
+	neko.zoomManager = zoomManager
+	neko.authManager = authManager
 	neko.server = server
 }
 
@@ -154,9 +180,18 @@ func (neko *Neko) Shutdown() {
 	err = neko.webSocketHandler.Shutdown()
 	neko.logger.Err(err).Msg("websocket handler shutdown")
 
+	err = neko.authManager.Shutdown()
+	neko.logger.Err(err).Msg("auth manager shutdown")
+
+	err = neko.zoomManager.Shutdown()
+	neko.logger.Err(err).Msg("zoom manager shutdown")
+
 	err = neko.webRTCManager.Shutdown()
 	neko.logger.Err(err).Msg("webrtc manager shutdown")
 
+	err = neko.turnManager.Shutdown()
+	neko.logger.Err(err).Msg("turn manager shutdown")
+
 	err = neko.captureManager.Shutdown()
 	neko.logger.Err(err).Msg("capture manager shutdown")
 